@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/steinarvk/qmfs/lib/selfsigned"
+)
+
+// The credentials for talking to a qmfs server are stored under the same
+// names as in the service directory of a mount.
+const (
+	serverCertFilename = "server_cert.pem"
+	clientCertFilename = "client_cert.pem"
+	clientKeyFilename  = "client_key.pem"
+)
+
+// tlsIdentityDir returns where the TLS certificates are kept, or "" if they
+// are to be generated afresh on every start.
+func tlsIdentityDir(pathLocalDB, tlsDir string, persistent bool) string {
+	if tlsDir != "" {
+		return tlsDir
+	}
+	if persistent {
+		return pathLocalDB + ".tls"
+	}
+	return ""
+}
+
+func writeCredentials(dir string, serverCertPEM []byte, clientCert *tls.Certificate) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	var certBuf bytes.Buffer
+	if err := pem.Encode(&certBuf, &pem.Block{Type: "CERTIFICATE", Bytes: clientCert.Certificate[0]}); err != nil {
+		return err
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(clientCert.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	var keyBuf bytes.Buffer
+	if err := pem.Encode(&keyBuf, &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}); err != nil {
+		return err
+	}
+
+	// Each file is replaced atomically, since clients may be reading them
+	// while the credentials are rewritten after a rotation.
+	for filename, data := range map[string][]byte{
+		serverCertFilename: serverCertPEM,
+		clientCertFilename: certBuf.Bytes(),
+		clientKeyFilename:  keyBuf.Bytes(),
+	} {
+		path := filepath.Join(dir, filename)
+		if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+			return fmt.Errorf("Failed to write credentials to %q: %v", dir, err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return fmt.Errorf("Failed to write credentials to %q: %v", dir, err)
+		}
+	}
+
+	return nil
+}
+
+// writeProviderCredentials writes the credentials currently handed out by
+// the provider, returning the server certificates written.
+func writeProviderCredentials(dir string, provider *selfsigned.Provider, hostname string) ([]byte, error) {
+	serverCertPEM, err := provider.GetPEM(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCert, err := provider.GetClientCertificate(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeCredentials(dir, serverCertPEM, clientCert); err != nil {
+		return nil, err
+	}
+
+	return serverCertPEM, nil
+}
+
+// refreshCredentials periodically rotates the provider's certificates as
+// needed, and rewrites the credentials whenever they change.
+func refreshCredentials(ctx context.Context, dir string, provider *selfsigned.Provider, hostname string, written []byte, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		serverCertPEM, err := provider.GetPEM(hostname)
+		if err != nil {
+			logrus.Errorf("Failed to check for rotated certificates: %v", err)
+			continue
+		}
+		if bytes.Equal(serverCertPEM, written) {
+			continue
+		}
+
+		written, err = writeProviderCredentials(dir, provider, hostname)
+		if err != nil {
+			logrus.Errorf("Failed to rewrite client credentials to %q: %v", dir, err)
+			continue
+		}
+		logrus.Infof("Rewrote client credentials to %q after certificate rotation.", dir)
+	}
+}
+
+func readCredentials(dir string) ([]byte, *tls.Certificate, error) {
+	serverCertPEM, err := ioutil.ReadFile(filepath.Join(dir, serverCertFilename))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read server certificate: %v", err)
+	}
+
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, clientCertFilename), filepath.Join(dir, clientKeyFilename))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read client certificate: %v", err)
+	}
+
+	return serverCertPEM, &clientCert, nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/steinarvk/orc"
+	"github.com/steinarvk/qmfs/lib/qmfsarchive"
+	"github.com/steinarvk/qmfs/lib/qmfsdb"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+func init() {
+	var localdb string
+	var namespace string
+	var allNamespaces bool
+	var includeHistory bool
+	var includeAuthorship bool
+	var output string
+
+	exportCmd := orc.Command(Root, orc.Modules(), cobra.Command{
+		Use:   "export",
+		Short: "Write the contents of a qmfs database to a portable archive",
+	}, func() error {
+		if localdb == "" {
+			return fmt.Errorf("Missing required flag --localdb")
+		}
+
+		if allNamespaces && namespace != "" {
+			return fmt.Errorf("Cannot combine --namespace and --all_namespaces")
+		}
+
+		ctx := context.Background()
+
+		pathLocalDB, err := filepath.Abs(localdb)
+		if err != nil {
+			return err
+		}
+
+		var out io.Writer = os.Stdout
+		if output != "-" {
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+
+		logrus.Infof("Opening database %q", pathLocalDB)
+		db, err := qmfsdb.Open(ctx, pathLocalDB, nil)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := db.Close(); err != nil {
+				logrus.Fatalf("Error closing database %q: %v", localdb, err)
+			}
+		}()
+
+		w := qmfsarchive.NewWriter(out)
+
+		var count int64
+
+		if err := db.Export(ctx, qmfsdb.ExportOptions{
+			Namespace:         namespace,
+			AllNamespaces:     allNamespaces,
+			IncludeHistory:    includeHistory,
+			IncludeAuthorship: includeAuthorship,
+		}, func(rec *pb.ArchivedRevision) error {
+			count++
+			return w.Write(rec)
+		}); err != nil {
+			return err
+		}
+
+		if err := w.Close(); err != nil {
+			return err
+		}
+
+		logrus.Infof("Exported %d revisions.", count)
+
+		return nil
+	})
+
+	exportCmd.Flags().StringVar(&localdb, "localdb", "", "filename of local database")
+	exportCmd.Flags().StringVar(&namespace, "namespace", "", "namespace to export (default: the default namespace)")
+	exportCmd.Flags().BoolVar(&allNamespaces, "all_namespaces", false, "export every namespace")
+	exportCmd.Flags().BoolVar(&includeHistory, "history", false, "include old revisions and deletions")
+	exportCmd.Flags().BoolVar(&includeAuthorship, "authorship", false, "include authorship metadata")
+	exportCmd.Flags().StringVar(&output, "output", "-", "filename of archive to write, or - for standard output")
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/steinarvk/orc"
+	"github.com/steinarvk/qmfs/lib/qmfsdb"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+func init() {
+	var localdb string
+	var retention time.Duration
+	var skipVacuum bool
+
+	gcCmd := orc.Command(Root, orc.Modules(), cobra.Command{
+		Use:   "gc",
+		Short: "Delete old revisions and deletion records from a qmfs database",
+	}, func() error {
+		if localdb == "" {
+			return fmt.Errorf("Missing required flag --localdb")
+		}
+
+		if retention < 0 {
+			return fmt.Errorf("Invalid --retention: %v", retention)
+		}
+
+		ctx := context.Background()
+
+		pathLocalDB, err := filepath.Abs(localdb)
+		if err != nil {
+			return err
+		}
+
+		logrus.Infof("Opening database %q", pathLocalDB)
+		db, err := qmfsdb.Open(ctx, pathLocalDB, nil)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := db.Close(); err != nil {
+				logrus.Fatalf("Error closing database %q: %v", localdb, err)
+			}
+		}()
+
+		resp, err := db.Compact(ctx, &pb.CompactRequest{
+			RetentionSeconds: int64(retention / time.Second),
+			SkipVacuum:       skipVacuum,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("deleted_revisions: %d\n", resp.GetDeletedRevisions())
+		fmt.Printf("deleted_tombstones: %d\n", resp.GetDeletedTombstones())
+		fmt.Printf("bytes_reclaimed: %d\n", resp.GetBytesReclaimed())
+		fmt.Printf("total_rows: %d\n", resp.GetSize().GetTotalRows())
+		fmt.Printf("active_rows: %d\n", resp.GetSize().GetActiveRows())
+
+		return nil
+	})
+
+	gcCmd.Flags().StringVar(&localdb, "localdb", "", "filename of local database")
+	gcCmd.Flags().DurationVar(&retention, "retention", 30*24*time.Hour, "keep history newer than this")
+	gcCmd.Flags().BoolVar(&skipVacuum, "skip_vacuum", false, "do not VACUUM the database after deleting rows")
+}
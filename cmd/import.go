@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/steinarvk/orc"
+	"github.com/steinarvk/qmfs/lib/qmfsarchive"
+	"github.com/steinarvk/qmfs/lib/qmfsdb"
+)
+
+func init() {
+	var localdb string
+	var input string
+	var preserveRevisions bool
+
+	importCmd := orc.Command(Root, orc.Modules(), cobra.Command{
+		Use:   "import",
+		Short: "Replay an archive written by export into a qmfs database",
+	}, func() error {
+		if localdb == "" {
+			return fmt.Errorf("Missing required flag --localdb")
+		}
+
+		ctx := context.Background()
+
+		pathLocalDB, err := filepath.Abs(localdb)
+		if err != nil {
+			return err
+		}
+
+		var in io.Reader = os.Stdin
+		if input != "-" {
+			f, err := os.Open(input)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+
+		logrus.Infof("Opening database %q", pathLocalDB)
+		db, err := qmfsdb.Open(ctx, pathLocalDB, nil)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := db.Close(); err != nil {
+				logrus.Fatalf("Error closing database %q: %v", localdb, err)
+			}
+		}()
+
+		r := qmfsarchive.NewReader(in)
+
+		result, err := db.Import(ctx, qmfsdb.ImportOptions{
+			PreserveRevisions: preserveRevisions,
+		}, r.Read)
+
+		if result != nil {
+			fmt.Printf("imported: %d\n", result.Imported)
+			fmt.Printf("skipped: %d\n", result.Skipped)
+		}
+
+		return err
+	})
+
+	importCmd.Flags().StringVar(&localdb, "localdb", "", "filename of local database")
+	importCmd.Flags().StringVar(&input, "input", "-", "filename of archive to read, or - for standard input")
+	importCmd.Flags().BoolVar(&preserveRevisions, "preserve_revisions", false, "keep the row GUIDs and timestamps of imported revisions")
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"bazil.org/fuse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/steinarvk/orc"
+	"github.com/steinarvk/qmfs/lib/loopbackgrpc"
+	"github.com/steinarvk/qmfs/lib/qmfs"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+var forbiddenFilenameREs = []string{
+	".*[.]sw[a-z]$",
+	"^[.]Trash$",
+}
+
+func mountAndServe(ctx context.Context, q *qmfs.Filesystem, mountpoint, fsname string, tryUnmount bool, shutdownCh chan error, onMounted func()) error {
+	infos, err := ioutil.ReadDir(mountpoint)
+	switch {
+	case len(infos) == 0 && err == nil:
+		logrus.Infof("Mountpoint %q is empty and valid; no issues.", mountpoint)
+
+	case len(infos) > 0:
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		return fmt.Errorf("mountpoint %q not empty; contains files (%v) -- mount cannot succeed", mountpoint, names)
+
+	default:
+		logrus.Infof("Error accessing mountpoint %q (%v); may still be mounted", mountpoint, err)
+		if !tryUnmount {
+			return err
+		}
+
+		if err := fuse.Unmount(mountpoint); err != nil {
+			return fmt.Errorf("failed to unmount existing mount on %q: %v", mountpoint, err)
+		}
+
+		logrus.Infof("Unmounted existing filesystem on %q.", mountpoint)
+	}
+
+	logrus.Infof("Performing mount.")
+
+	fuseConn, err := fuse.Mount(
+		mountpoint,
+		fuse.FSName(fsname),
+		fuse.Subtype("qmfs"),
+	)
+	if err != nil {
+		logrus.Fatalf("Failed to set up fuse mount on %q: %v", mountpoint, err)
+	}
+	defer func() {
+		if fuseConn != nil {
+			fuseConn.Close()
+		}
+	}()
+
+	if onMounted != nil {
+		onMounted()
+	}
+
+	logrus.Infof("Ready to serve qmfs on %q.", mountpoint)
+
+	go func() {
+		for err := range shutdownCh {
+			logrus.Errorf("Received shutdown request: %v", err)
+			time.AfterFunc(5*time.Second, func() {
+				logrus.Fatalf("Connection stalled, force-quitting to honour shutdown request: %v", err)
+			})
+			fuseConn.Close()
+			fuseConn = nil
+		}
+	}()
+
+	if err := q.Serve(ctx, fuseConn); err != nil {
+		logrus.Fatalf("Failed to serve fuse mount: %v", err)
+	}
+
+	return nil
+}
+
+func init() {
+	var mountpoint string
+	var serverAddr string
+	var credentialsDir string
+	var tryUnmount bool
+	var entityJSONFilename string
+	var onConflict string
+
+	mountCmd := orc.Command(Root, orc.Modules(), cobra.Command{
+		Use:   "mount",
+		Short: "Mount a remote qmfs server as a fuse filesystem",
+	}, func() error {
+		if mountpoint == "" {
+			return fmt.Errorf("Missing required flag --mountpoint")
+		}
+
+		if serverAddr == "" {
+			return fmt.Errorf("Missing required flag --server")
+		}
+
+		if credentialsDir == "" {
+			return fmt.Errorf("Missing required flag --credentials_dir")
+		}
+
+		conflictPolicy, err := qmfs.ParseConflictPolicy(onConflict)
+		if err != nil {
+			return fmt.Errorf("Invalid --on_conflict: %v", err)
+		}
+
+		hostname, _, err := net.SplitHostPort(serverAddr)
+		if err != nil {
+			return fmt.Errorf("Invalid --server %q: %v", serverAddr, err)
+		}
+
+		serverCertPEM, clientCert, err := readCredentials(credentialsDir)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		logrus.Infof("Connecting to qmfs server at %q", serverAddr)
+
+		conn, err := loopbackgrpc.Dial(ctx, loopbackgrpc.Params{
+			Deadline:           10 * time.Second,
+			Hostname:           hostname,
+			AddressGRPC:        serverAddr,
+			ServerCertPEM:      serverCertPEM,
+			ClientCertificates: []tls.Certificate{*clientCert},
+		})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		client := pb.NewQMetadataServiceClient(conn)
+
+		shutdownCh := make(chan error, 10)
+
+		q, err := qmfs.New(ctx, client, qmfs.Params{
+			ServiceData: qmfs.ServiceData{
+				Hostname:    hostname,
+				AddressGRPC: serverAddr,
+				ServerCertPEM: func() ([]byte, error) {
+					return serverCertPEM, nil
+				},
+				ClientCertificate: func() (*tls.Certificate, error) {
+					return clientCert, nil
+				},
+				ForbiddenFilenameREs: forbiddenFilenameREs,
+			},
+			Mountpoint:         mountpoint,
+			ShutdownChan:       shutdownCh,
+			EntityJSONFilename: entityJSONFilename,
+			ConflictPolicy:     conflictPolicy,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to create qmfs: %v", err)
+		}
+
+		return mountAndServe(ctx, q, mountpoint, serverAddr, tryUnmount, shutdownCh, nil)
+	})
+
+	mountCmd.Flags().StringVar(&mountpoint, "mountpoint", "", "path at which to mount file system")
+	mountCmd.Flags().StringVar(&serverAddr, "server", "", "address (host:port) of the qmfs gRPC server")
+	mountCmd.Flags().StringVar(&credentialsDir, "credentials_dir", "", "directory holding server_cert.pem, client_cert.pem and client_key.pem for the server")
+	mountCmd.Flags().BoolVar(&tryUnmount, "unmount", false, "attempt unmount of old qmfs")
+	mountCmd.Flags().StringVar(&entityJSONFilename, "entity_json_filename", ".entity.json", "name of the virtual file exposing each entity directory as JSON")
+	mountCmd.Flags().StringVar(&onConflict, "on_conflict", "fail", "what to do with writes to files changed since they were read: fail (with ESTALE) or save (as <file>.conflict-<row_guid>)")
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -10,7 +11,6 @@ import (
 	"time"
 
 	"bazil.org/fuse"
-	"bazil.org/fuse/fs"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/steinarvk/orc"
@@ -20,6 +20,7 @@ import (
 	"github.com/steinarvk/qmfs/lib/qmfs"
 	"github.com/steinarvk/qmfs/lib/qmfsdb"
 	"github.com/steinarvk/qmfs/lib/selfsigned"
+	"google.golang.org/grpc"
 
 	orcdebug "github.com/steinarvk/orclib/module/orc-debug"
 	orcgrpcserver "github.com/steinarvk/orclib/module/orc-grpcserver"
@@ -76,6 +77,12 @@ func init() {
 	var localdb string
 	var tryUnmount bool
 	var touchOnChange string
+	var keepRevisionData bool
+	var entityJSONFilename string
+	var onConflict string
+	var grpcSocket string
+	var tlsDir string
+	var persistentTLS bool
 
 	mountCmd := orc.Command(Root, orc.ModulesWithSetup(
 		func() {
@@ -92,6 +99,11 @@ func init() {
 		Use:   "serve",
 		Short: "Serve qmfs as a fuse mount and service",
 	}, func() error {
+		conflictPolicy, err := qmfs.ParseConflictPolicy(onConflict)
+		if err != nil {
+			return fmt.Errorf("Invalid --on_conflict: %v", err)
+		}
+
 		hostname := lisProvider.hostname
 
 		fuse.Debug = func(msg interface{}) {
@@ -117,6 +129,8 @@ func init() {
 			return err
 		}
 
+		provider.Dir = tlsIdentityDir(pathLocalDB, tlsDir, persistentTLS)
+
 		changewatchOpts := changewatch.Options{
 			Delay: time.Second,
 		}
@@ -151,7 +165,9 @@ func init() {
         t0 := time.Now()
 		logrus.Infof("Opening database %q", pathLocalDB)
 		db, err := qmfsdb.Open(ctx, pathLocalDB, &qmfsdb.Options{
-			ChangeHook: func() { watcher.OnChange() },
+			ChangeHook:         func() { watcher.OnChange() },
+			KeepRevisionData:   keepRevisionData,
+			DeleteExpiredFiles: true,
 		})
 		if err != nil {
 			return err
@@ -186,24 +202,44 @@ func init() {
 
 		logrus.Infof("Established listening: http=%q grpc=%q", httpAddress, grpcAddress)
 
-		certBytes, err := provider.GetPEM(hostname)
+		if grpcSocket == "" {
+			socketDir, err := ioutil.TempDir("", "qmfs-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(socketDir)
+
+			grpcSocket = filepath.Join(socketDir, "grpc.sock")
+		}
+
+		grpcSocket, err = filepath.Abs(grpcSocket)
 		if err != nil {
 			return err
 		}
 
-		serverTLSConfig, err := provider.GetTLSConfig(hostname)
+		socketListener, err := loopbackgrpc.ListenSocket(grpcSocket)
 		if err != nil {
-			return err
+			return fmt.Errorf("Failed to listen on socket %q: %v", grpcSocket, err)
 		}
+		defer os.Remove(grpcSocket)
+
+		socketServer := grpc.NewServer()
+		pb.RegisterQMetadataServiceServer(socketServer, db)
+
+		go func() {
+			if err := socketServer.Serve(socketListener); err != nil {
+				logrus.Fatalf("Fatal: Socket server exited: %v", err)
+			}
+		}()
+		defer socketServer.Stop()
+
+		logrus.Infof("Established listening: socket=%q", grpcSocket)
 
 		grpcAddress = fmt.Sprintf("%s:%s", hostname, strings.Split(grpcAddress, ":")[1])
 
 		conn, err := loopbackgrpc.Dial(ctx, loopbackgrpc.Params{
-			Deadline:           2 * time.Second,
-			Hostname:           hostname,
-			AddressGRPC:        grpcAddress,
-			ServerCertPEM:      certBytes,
-			ClientCertificates: serverTLSConfig.Certificates,
+			Deadline:   2 * time.Second,
+			SocketPath: grpcSocket,
 		})
 		if err != nil {
 			return err
@@ -216,89 +252,39 @@ func init() {
 
 		q, err := qmfs.New(ctx, client, qmfs.Params{
 			ServiceData: qmfs.ServiceData{
-				Hostname:          hostname,
-				DatabasePath:      pathLocalDB,
-				AddressGRPC:       grpcAddress,
-				AddressHTTP:       httpAddress,
-				ServerCertPEM:     certBytes,
-				ClientCertificate: &serverTLSConfig.Certificates[0],
-				ForbiddenFilenameREs: []string{
-					".*[.]sw[a-z]$",
-					"^[.]Trash$",
+				Hostname:     hostname,
+				DatabasePath: pathLocalDB,
+				AddressGRPC:  grpcAddress,
+				AddressHTTP:  httpAddress,
+				SocketPath:   grpcSocket,
+				ServerCertPEM: func() ([]byte, error) {
+					return provider.GetPEM(hostname)
 				},
+				ClientCertificate: func() (*tls.Certificate, error) {
+					return provider.GetClientCertificate(hostname)
+				},
+				ForbiddenFilenameREs: forbiddenFilenameREs,
 			},
-			Mountpoint:   mountpoint,
-			ShutdownChan: shutdownCh,
+			Mountpoint:         mountpoint,
+			ShutdownChan:       shutdownCh,
+			EntityJSONFilename: entityJSONFilename,
+			ConflictPolicy:     conflictPolicy,
 		})
 		if err != nil {
 			return fmt.Errorf("Failed to create qmfs: %v", err)
 		}
 
-		infos, err := ioutil.ReadDir(mountpoint)
-		switch {
-		case len(infos) == 0 && err == nil:
-			logrus.Infof("Mountpoint %q is empty and valid; no issues.", mountpoint)
-
-		case len(infos) > 0:
-			var names []string
-			for _, info := range infos {
-				names = append(names, info.Name())
-			}
-			return fmt.Errorf("mountpoint %q not empty; contains files (%v) -- mount cannot succeed", mountpoint, names)
-
-		default:
-			logrus.Infof("Error accessing mountpoint %q (%v); may still be mounted", mountpoint, err)
-			if !tryUnmount {
-				return err
-			}
-
-			if err := fuse.Unmount(mountpoint); err != nil {
-				return fmt.Errorf("failed to unmount existing mount on %q: %v", mountpoint, err)
-			}
-
-			logrus.Infof("Unmounted existing filesystem on %q.", mountpoint)
-		}
-
-		logrus.Infof("Performing mount.")
-
-		fuseConn, err := fuse.Mount(
-			mountpoint,
-			fuse.FSName(localdb),
-			fuse.Subtype("qmfs"),
-		)
-		if err != nil {
-			logrus.Fatalf("Failed to set up fuse mount on %q: %v", mountpoint, err)
-		}
-		defer func() {
-			if fuseConn != nil {
-				fuseConn.Close()
-			}
-		}()
-
-		watcher.OnChange()
-
-		logrus.Infof("Ready to serve qmfs on %q.", mountpoint)
-
-		go func() {
-			for err := range shutdownCh {
-				logrus.Errorf("Received shutdown request: %v", err)
-				time.AfterFunc(5*time.Second, func() {
-					logrus.Fatalf("Connection stalled, force-quitting to honour shutdown request: %v", err)
-				})
-				fuseConn.Close()
-				fuseConn = nil
-			}
-		}()
-
-		if err := fs.Serve(fuseConn, q); err != nil {
-			logrus.Fatalf("Failed to serve fuse mount: %v", err)
-		}
-
-		return nil
+		return mountAndServe(ctx, q, mountpoint, localdb, tryUnmount, shutdownCh, watcher.OnChange)
 	})
 
 	mountCmd.Flags().StringVar(&mountpoint, "mountpoint", "", "path at which to mount file system")
 	mountCmd.Flags().StringVar(&localdb, "localdb", "", "filename of local database")
 	mountCmd.Flags().BoolVar(&tryUnmount, "unmount", false, "attempt unmount of old qmfs")
 	mountCmd.Flags().StringVar(&touchOnChange, "touch_on_change", "", "filename of file to touch when database changes")
+	mountCmd.Flags().BoolVar(&keepRevisionData, "keep_revision_data", false, "retain the contents of old revisions of files")
+	mountCmd.Flags().StringVar(&tlsDir, "tls_dir", "", "directory in which to keep the TLS certificates across restarts")
+	mountCmd.Flags().BoolVar(&persistentTLS, "persistent_tls", false, "keep the TLS certificates across restarts, next to the database unless --tls_dir is set")
+	mountCmd.Flags().StringVar(&grpcSocket, "grpc_socket", "", "path of the Unix socket to serve gRPC on (default: in a private temporary directory)")
+	mountCmd.Flags().StringVar(&entityJSONFilename, "entity_json_filename", ".entity.json", "name of the virtual file exposing each entity directory as JSON")
+	mountCmd.Flags().StringVar(&onConflict, "on_conflict", "fail", "what to do with writes to files changed since they were read: fail (with ESTALE) or save (as <file>.conflict-<row_guid>)")
 }
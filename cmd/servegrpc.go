@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/steinarvk/orc"
+	"github.com/steinarvk/qmfs/lib/qmfsdb"
+	"github.com/steinarvk/qmfs/lib/selfsigned"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+func init() {
+	var localdb string
+	var listenAddr string
+	var hostname string
+	var credentialsDir string
+	var keepRevisionData bool
+	var tlsDir string
+	var persistentTLS bool
+	var tlsValidity time.Duration
+	var tlsRotationOverlap time.Duration
+
+	serveGRPCCmd := orc.Command(Root, orc.Modules(), cobra.Command{
+		Use:   "serve-grpc",
+		Short: "Serve a qmfs database over gRPC without mounting it",
+	}, func() error {
+		if localdb == "" {
+			return fmt.Errorf("Missing required flag --localdb")
+		}
+
+		if listenAddr == "" {
+			return fmt.Errorf("Missing required flag --listen")
+		}
+
+		if credentialsDir == "" {
+			return fmt.Errorf("Missing required flag --credentials_dir")
+		}
+
+		if tlsValidity <= 0 {
+			return fmt.Errorf("Invalid flag --tls_validity %v: must be positive", tlsValidity)
+		}
+		if tlsRotationOverlap <= 0 || tlsRotationOverlap >= tlsValidity {
+			return fmt.Errorf("Invalid flag --tls_rotation_overlap %v: must be positive and shorter than --tls_validity", tlsRotationOverlap)
+		}
+
+		if hostname == "" {
+			name, err := os.Hostname()
+			if err != nil {
+				return err
+			}
+			hostname = name
+		}
+
+		ctx := context.Background()
+
+		pathLocalDB, err := filepath.Abs(localdb)
+		if err != nil {
+			return err
+		}
+
+		logrus.Infof("Opening database %q", pathLocalDB)
+		db, err := qmfsdb.Open(ctx, pathLocalDB, &qmfsdb.Options{
+			KeepRevisionData:   keepRevisionData,
+			DeleteExpiredFiles: true,
+		})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := db.Close(); err != nil {
+				logrus.Fatalf("Error closing database %q: %v", localdb, err)
+			}
+		}()
+
+		provider := &selfsigned.Provider{
+			Dir:             tlsIdentityDir(pathLocalDB, tlsDir, persistentTLS),
+			ValidFor:        tlsValidity,
+			RotationOverlap: tlsRotationOverlap,
+		}
+
+		serverTLSConfig, err := provider.GetTLSConfig(hostname)
+		if err != nil {
+			return err
+		}
+
+		written, err := writeProviderCredentials(credentialsDir, provider, hostname)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Wrote client credentials to %q.", credentialsDir)
+
+		// Rotation is checked for well within the overlap, so that clients
+		// reading the credentials pick up a successor before it is needed.
+		refreshInterval := time.Hour
+		if quarter := tlsRotationOverlap / 4; quarter < refreshInterval {
+			refreshInterval = quarter
+		}
+		if refreshInterval < time.Second {
+			refreshInterval = time.Second
+		}
+		go refreshCredentials(ctx, credentialsDir, provider, hostname, written, refreshInterval)
+
+		lis, err := net.Listen("tcp", listenAddr)
+		if err != nil {
+			return err
+		}
+
+		srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverTLSConfig)))
+		pb.RegisterQMetadataServiceServer(srv, db)
+
+		logrus.Infof("Serving database %q over gRPC on %q as %q.", pathLocalDB, lis.Addr(), hostname)
+
+		return srv.Serve(lis)
+	})
+
+	serveGRPCCmd.Flags().StringVar(&localdb, "localdb", "", "filename of local database")
+	serveGRPCCmd.Flags().StringVar(&listenAddr, "listen", "", "address (host:port) to serve gRPC on")
+	serveGRPCCmd.Flags().StringVar(&hostname, "hostname", "", "hostname clients use to reach the server (default: this machine's hostname)")
+	serveGRPCCmd.Flags().StringVar(&credentialsDir, "credentials_dir", "", "directory in which to write the credentials clients need to connect")
+	serveGRPCCmd.Flags().StringVar(&tlsDir, "tls_dir", "", "directory in which to keep the TLS certificates across restarts")
+	serveGRPCCmd.Flags().BoolVar(&persistentTLS, "persistent_tls", false, "keep the TLS certificates across restarts, next to the database unless --tls_dir is set")
+	serveGRPCCmd.Flags().DurationVar(&tlsValidity, "tls_validity", selfsigned.ValidFor, "how long generated TLS certificates are valid")
+	serveGRPCCmd.Flags().DurationVar(&tlsRotationOverlap, "tls_rotation_overlap", selfsigned.DefaultRotationOverlap, "how long before expiry TLS certificates are rotated")
+	serveGRPCCmd.Flags().BoolVar(&keepRevisionData, "keep_revision_data", false, "retain the contents of old revisions of files")
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/steinarvk/orc"
+	"github.com/steinarvk/qmfs/lib/loopbackgrpc"
+	"github.com/steinarvk/qmfs/lib/qmfsdb"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+func init() {
+	var localdb string
+	var credentialsDir string
+	var keepRevisionData bool
+
+	syncCmd := orc.Command(Root, orc.Modules(), cobra.Command{
+		Use:   "sync <remote>",
+		Short: "Exchange changes with a remote qmfs server",
+		Long: `Exchange changes with a remote qmfs server (host:port), in both directions.
+
+Only revisions written since the last sync with the same remote are sent.
+Concurrent edits of the same file are resolved in favour of the latest write;
+the other revision is kept in the file's history.`,
+	}, func(args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("Expected exactly one remote (host:port), got %d arguments", len(args))
+		}
+		serverAddr := args[0]
+
+		if localdb == "" {
+			return fmt.Errorf("Missing required flag --localdb")
+		}
+
+		if credentialsDir == "" {
+			return fmt.Errorf("Missing required flag --credentials_dir")
+		}
+
+		hostname, _, err := net.SplitHostPort(serverAddr)
+		if err != nil {
+			return fmt.Errorf("Invalid remote %q: %v", serverAddr, err)
+		}
+
+		serverCertPEM, clientCert, err := readCredentials(credentialsDir)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		pathLocalDB, err := filepath.Abs(localdb)
+		if err != nil {
+			return err
+		}
+
+		logrus.Infof("Opening database %q", pathLocalDB)
+		db, err := qmfsdb.Open(ctx, pathLocalDB, &qmfsdb.Options{
+			KeepRevisionData: keepRevisionData,
+		})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := db.Close(); err != nil {
+				logrus.Fatalf("Error closing database %q: %v", localdb, err)
+			}
+		}()
+
+		logrus.Infof("Connecting to qmfs server at %q", serverAddr)
+
+		conn, err := loopbackgrpc.Dial(ctx, loopbackgrpc.Params{
+			Deadline:           10 * time.Second,
+			Hostname:           hostname,
+			AddressGRPC:        serverAddr,
+			ServerCertPEM:      serverCertPEM,
+			ClientCertificates: []tls.Certificate{*clientCert},
+		})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		result, err := db.Sync(ctx, serverAddr, pb.NewQMetadataServiceClient(conn))
+
+		if result != nil {
+			fmt.Printf("pulled: %d\n", result.Pulled)
+			fmt.Printf("pushed: %d\n", result.Pushed)
+			fmt.Printf("skipped: %d\n", result.PulledSkipped+result.PushedSkipped)
+		}
+
+		return err
+	})
+
+	syncCmd.Flags().StringVar(&localdb, "localdb", "", "filename of local database")
+	syncCmd.Flags().StringVar(&credentialsDir, "credentials_dir", "", "directory holding server_cert.pem, client_cert.pem and client_key.pem for the server")
+	syncCmd.Flags().BoolVar(&keepRevisionData, "keep_revision_data", false, "retain the contents of old revisions of files")
+}
@@ -56,6 +56,148 @@ func (DeletionType) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_213b282dda0e8199, []int{0}
 }
 
+type EntitiesQuery_Clause_FileComparison_Operator int32
+
+const (
+	EntitiesQuery_Clause_FileComparison_INVALID_OPERATOR EntitiesQuery_Clause_FileComparison_Operator = 0
+	EntitiesQuery_Clause_FileComparison_LESS             EntitiesQuery_Clause_FileComparison_Operator = 1
+	EntitiesQuery_Clause_FileComparison_LESS_OR_EQUAL    EntitiesQuery_Clause_FileComparison_Operator = 2
+	EntitiesQuery_Clause_FileComparison_GREATER          EntitiesQuery_Clause_FileComparison_Operator = 3
+	EntitiesQuery_Clause_FileComparison_GREATER_OR_EQUAL EntitiesQuery_Clause_FileComparison_Operator = 4
+)
+
+var EntitiesQuery_Clause_FileComparison_Operator_name = map[int32]string{
+	0: "INVALID_OPERATOR",
+	1: "LESS",
+	2: "LESS_OR_EQUAL",
+	3: "GREATER",
+	4: "GREATER_OR_EQUAL",
+}
+
+var EntitiesQuery_Clause_FileComparison_Operator_value = map[string]int32{
+	"INVALID_OPERATOR": 0,
+	"LESS":             1,
+	"LESS_OR_EQUAL":    2,
+	"GREATER":          3,
+	"GREATER_OR_EQUAL": 4,
+}
+
+func (x EntitiesQuery_Clause_FileComparison_Operator) String() string {
+	return proto.EnumName(EntitiesQuery_Clause_FileComparison_Operator_name, int32(x))
+}
+
+func (EntitiesQuery_Clause_FileComparison_Operator) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{14, 0, 4, 0}
+}
+
+type EntitiesQuery_Clause_FileComparison_Semantics int32
+
+const (
+	EntitiesQuery_Clause_FileComparison_INVALID_SEMANTICS EntitiesQuery_Clause_FileComparison_Semantics = 0
+	// Compare as numbers; files whose trimmed contents are not a number never match.
+	EntitiesQuery_Clause_FileComparison_NUMERIC EntitiesQuery_Clause_FileComparison_Semantics = 1
+	// Compare trimmed contents bytewise.
+	EntitiesQuery_Clause_FileComparison_STRING EntitiesQuery_Clause_FileComparison_Semantics = 2
+)
+
+var EntitiesQuery_Clause_FileComparison_Semantics_name = map[int32]string{
+	0: "INVALID_SEMANTICS",
+	1: "NUMERIC",
+	2: "STRING",
+}
+
+var EntitiesQuery_Clause_FileComparison_Semantics_value = map[string]int32{
+	"INVALID_SEMANTICS": 0,
+	"NUMERIC":           1,
+	"STRING":            2,
+}
+
+func (x EntitiesQuery_Clause_FileComparison_Semantics) String() string {
+	return proto.EnumName(EntitiesQuery_Clause_FileComparison_Semantics_name, int32(x))
+}
+
+func (EntitiesQuery_Clause_FileComparison_Semantics) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{14, 0, 4, 1}
+}
+
+type EntitiesQuery_Clause_FileMatchesPattern_Mode int32
+
+const (
+	EntitiesQuery_Clause_FileMatchesPattern_INVALID_MODE EntitiesQuery_Clause_FileMatchesPattern_Mode = 0
+	// Trimmed contents start with the pattern.
+	EntitiesQuery_Clause_FileMatchesPattern_PREFIX EntitiesQuery_Clause_FileMatchesPattern_Mode = 1
+	// Trimmed contents contain the pattern as a substring.
+	EntitiesQuery_Clause_FileMatchesPattern_CONTAINS EntitiesQuery_Clause_FileMatchesPattern_Mode = 2
+	// Trimmed contents match the pattern as a shell-style glob (case-sensitive).
+	EntitiesQuery_Clause_FileMatchesPattern_GLOB EntitiesQuery_Clause_FileMatchesPattern_Mode = 3
+	// Trimmed contents match the pattern as a Go regular expression (RE2 syntax).
+	EntitiesQuery_Clause_FileMatchesPattern_REGEX EntitiesQuery_Clause_FileMatchesPattern_Mode = 4
+)
+
+var EntitiesQuery_Clause_FileMatchesPattern_Mode_name = map[int32]string{
+	0: "INVALID_MODE",
+	1: "PREFIX",
+	2: "CONTAINS",
+	3: "GLOB",
+	4: "REGEX",
+}
+
+var EntitiesQuery_Clause_FileMatchesPattern_Mode_value = map[string]int32{
+	"INVALID_MODE": 0,
+	"PREFIX":       1,
+	"CONTAINS":     2,
+	"GLOB":         3,
+	"REGEX":        4,
+}
+
+func (x EntitiesQuery_Clause_FileMatchesPattern_Mode) String() string {
+	return proto.EnumName(EntitiesQuery_Clause_FileMatchesPattern_Mode_name, int32(x))
+}
+
+func (EntitiesQuery_Clause_FileMatchesPattern_Mode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{14, 0, 5, 0}
+}
+
+type AggregateEntitiesRequest_Function int32
+
+const (
+	AggregateEntitiesRequest_INVALID_FUNCTION AggregateEntitiesRequest_Function = 0
+	// Number of matching entities.
+	AggregateEntitiesRequest_COUNT AggregateEntitiesRequest_Function = 1
+	// The others consider the numeric trimmed contents of the given file
+	// across matching entities; files not containing a number are ignored.
+	AggregateEntitiesRequest_SUM AggregateEntitiesRequest_Function = 2
+	AggregateEntitiesRequest_MIN AggregateEntitiesRequest_Function = 3
+	AggregateEntitiesRequest_MAX AggregateEntitiesRequest_Function = 4
+	AggregateEntitiesRequest_AVG AggregateEntitiesRequest_Function = 5
+)
+
+var AggregateEntitiesRequest_Function_name = map[int32]string{
+	0: "INVALID_FUNCTION",
+	1: "COUNT",
+	2: "SUM",
+	3: "MIN",
+	4: "MAX",
+	5: "AVG",
+}
+
+var AggregateEntitiesRequest_Function_value = map[string]int32{
+	"INVALID_FUNCTION": 0,
+	"COUNT":            1,
+	"SUM":              2,
+	"MIN":              3,
+	"MAX":              4,
+	"AVG":              5,
+}
+
+func (x AggregateEntitiesRequest_Function) String() string {
+	return proto.EnumName(AggregateEntitiesRequest_Function_name, int32(x))
+}
+
+func (AggregateEntitiesRequest_Function) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{17, 0}
+}
+
 type Timestamp struct {
 	UnixNano             int64    `protobuf:"varint,1,opt,name=unix_nano,json=unixNano,proto3" json:"unix_nano,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -159,14 +301,16 @@ func (m *Checksums) GetTrimmedSha256() []byte {
 }
 
 type EntityFileHeader struct {
-	EntityId             string     `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
-	Filename             string     `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
-	Checksums            *Checksums `protobuf:"bytes,3,opt,name=checksums,proto3" json:"checksums,omitempty"`
-	LastChanged          *Timestamp `protobuf:"bytes,4,opt,name=last_changed,json=lastChanged,proto3" json:"last_changed,omitempty"`
-	RowGuid              string     `protobuf:"bytes,5,opt,name=row_guid,json=rowGuid,proto3" json:"row_guid,omitempty"`
-	Tombstone            bool       `protobuf:"varint,6,opt,name=tombstone,proto3" json:"tombstone,omitempty"`
-	Namespace            string     `protobuf:"bytes,7,opt,name=namespace,proto3" json:"namespace,omitempty"`
-	Directory            bool       `protobuf:"varint,8,opt,name=directory,proto3" json:"directory,omitempty"`
+	EntityId    string     `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
+	Filename    string     `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Checksums   *Checksums `protobuf:"bytes,3,opt,name=checksums,proto3" json:"checksums,omitempty"`
+	LastChanged *Timestamp `protobuf:"bytes,4,opt,name=last_changed,json=lastChanged,proto3" json:"last_changed,omitempty"`
+	RowGuid     string     `protobuf:"bytes,5,opt,name=row_guid,json=rowGuid,proto3" json:"row_guid,omitempty"`
+	Tombstone   bool       `protobuf:"varint,6,opt,name=tombstone,proto3" json:"tombstone,omitempty"`
+	Namespace   string     `protobuf:"bytes,7,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Directory   bool       `protobuf:"varint,8,opt,name=directory,proto3" json:"directory,omitempty"`
+	// When the file is due to be deleted, if it was written with an expiry.
+	ExpiresAt            *Timestamp `protobuf:"bytes,9,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
 	XXX_unrecognized     []byte     `json:"-"`
 	XXX_sizecache        int32      `json:"-"`
@@ -253,6 +397,13 @@ func (m *EntityFileHeader) GetDirectory() bool {
 	return false
 }
 
+func (m *EntityFileHeader) GetExpiresAt() *Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
 type Entity struct {
 	EntityId             string                       `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
 	Files                map[string]*EntityFileHeader `protobuf:"bytes,2,rep,name=files,proto3" json:"files,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
@@ -348,11 +499,13 @@ func (m *EntityFile) GetData() []byte {
 }
 
 type GetEntityRequest struct {
-	EntityId             string   `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
-	Namespace            string   `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	EntityId  string `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
+	Namespace string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// If set, return the entity as it was at this point in time.
+	AsOf                 *Timestamp `protobuf:"bytes,3,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
 }
 
 func (m *GetEntityRequest) Reset()         { *m = GetEntityRequest{} }
@@ -394,6 +547,13 @@ func (m *GetEntityRequest) GetNamespace() string {
 	return ""
 }
 
+func (m *GetEntityRequest) GetAsOf() *Timestamp {
+	if m != nil {
+		return m.AsOf
+	}
+	return nil
+}
+
 type GetEntityResponse struct {
 	Entity               *Entity  `protobuf:"bytes,1,opt,name=entity,proto3" json:"entity,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -434,12 +594,14 @@ func (m *GetEntityResponse) GetEntity() *Entity {
 }
 
 type ReadFileRequest struct {
-	EntityId             string   `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
-	Filename             string   `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
-	Namespace            string   `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	EntityId  string `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
+	Filename  string `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Namespace string `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// If set, return the file as it was at this point in time.
+	AsOf                 *Timestamp `protobuf:"bytes,4,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
 }
 
 func (m *ReadFileRequest) Reset()         { *m = ReadFileRequest{} }
@@ -488,6 +650,13 @@ func (m *ReadFileRequest) GetNamespace() string {
 	return ""
 }
 
+func (m *ReadFileRequest) GetAsOf() *Timestamp {
+	if m != nil {
+		return m.AsOf
+	}
+	return nil
+}
+
 type ReadFileResponse struct {
 	File                 *EntityFile `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
@@ -528,16 +697,19 @@ func (m *ReadFileResponse) GetFile() *EntityFile {
 }
 
 type WriteFileRequest struct {
-	Namespace            string              `protobuf:"bytes,6,opt,name=namespace,proto3" json:"namespace,omitempty"`
-	EntityId             string              `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
-	Filename             string              `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
-	Data                 []byte              `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
-	OldRevisionGuid      string              `protobuf:"bytes,4,opt,name=old_revision_guid,json=oldRevisionGuid,proto3" json:"old_revision_guid,omitempty"`
-	AuthorshipMetadata   *AuthorshipMetadata `protobuf:"bytes,5,opt,name=authorship_metadata,json=authorshipMetadata,proto3" json:"authorship_metadata,omitempty"`
-	Directory            bool                `protobuf:"varint,7,opt,name=directory,proto3" json:"directory,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
-	XXX_unrecognized     []byte              `json:"-"`
-	XXX_sizecache        int32               `json:"-"`
+	Namespace          string              `protobuf:"bytes,6,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	EntityId           string              `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
+	Filename           string              `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Data               []byte              `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	OldRevisionGuid    string              `protobuf:"bytes,4,opt,name=old_revision_guid,json=oldRevisionGuid,proto3" json:"old_revision_guid,omitempty"`
+	AuthorshipMetadata *AuthorshipMetadata `protobuf:"bytes,5,opt,name=authorship_metadata,json=authorshipMetadata,proto3" json:"authorship_metadata,omitempty"`
+	Directory          bool                `protobuf:"varint,7,opt,name=directory,proto3" json:"directory,omitempty"`
+	// If set, the file is deleted once this time has passed, unless it is
+	// overwritten first. Not allowed for directories.
+	ExpiresAt            *Timestamp `protobuf:"bytes,8,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
 }
 
 func (m *WriteFileRequest) Reset()         { *m = WriteFileRequest{} }
@@ -614,6 +786,13 @@ func (m *WriteFileRequest) GetDirectory() bool {
 	return false
 }
 
+func (m *WriteFileRequest) GetExpiresAt() *Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
 type WriteFileResponse struct {
 	Header               *EntityFileHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
@@ -653,6 +832,55 @@ func (m *WriteFileResponse) GetHeader() *EntityFileHeader {
 	return nil
 }
 
+// Detail of the FailedPrecondition error returned for a write or deletion
+// whose old_revision_guid is no longer the current revision of the file.
+type RevisionConflict struct {
+	ExpectedRowGuid      string   `protobuf:"bytes,1,opt,name=expected_row_guid,json=expectedRowGuid,proto3" json:"expected_row_guid,omitempty"`
+	CurrentRowGuid       string   `protobuf:"bytes,2,opt,name=current_row_guid,json=currentRowGuid,proto3" json:"current_row_guid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevisionConflict) Reset()         { *m = RevisionConflict{} }
+func (m *RevisionConflict) String() string { return proto.CompactTextString(m) }
+func (*RevisionConflict) ProtoMessage()    {}
+func (*RevisionConflict) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{11}
+}
+
+func (m *RevisionConflict) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevisionConflict.Unmarshal(m, b)
+}
+func (m *RevisionConflict) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevisionConflict.Marshal(b, m, deterministic)
+}
+func (m *RevisionConflict) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevisionConflict.Merge(m, src)
+}
+func (m *RevisionConflict) XXX_Size() int {
+	return xxx_messageInfo_RevisionConflict.Size(m)
+}
+func (m *RevisionConflict) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevisionConflict.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevisionConflict proto.InternalMessageInfo
+
+func (m *RevisionConflict) GetExpectedRowGuid() string {
+	if m != nil {
+		return m.ExpectedRowGuid
+	}
+	return ""
+}
+
+func (m *RevisionConflict) GetCurrentRowGuid() string {
+	if m != nil {
+		return m.CurrentRowGuid
+	}
+	return ""
+}
+
 type DeleteFileRequest struct {
 	EntityId             string              `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
 	Filename             string              `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
@@ -669,7 +897,7 @@ func (m *DeleteFileRequest) Reset()         { *m = DeleteFileRequest{} }
 func (m *DeleteFileRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteFileRequest) ProtoMessage()    {}
 func (*DeleteFileRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{11}
+	return fileDescriptor_213b282dda0e8199, []int{12}
 }
 
 func (m *DeleteFileRequest) XXX_Unmarshal(b []byte) error {
@@ -743,7 +971,7 @@ func (m *DeleteFileResponse) Reset()         { *m = DeleteFileResponse{} }
 func (m *DeleteFileResponse) String() string { return proto.CompactTextString(m) }
 func (*DeleteFileResponse) ProtoMessage()    {}
 func (*DeleteFileResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{12}
+	return fileDescriptor_213b282dda0e8199, []int{13}
 }
 
 func (m *DeleteFileResponse) XXX_Unmarshal(b []byte) error {
@@ -783,7 +1011,7 @@ func (m *EntitiesQuery) Reset()         { *m = EntitiesQuery{} }
 func (m *EntitiesQuery) String() string { return proto.CompactTextString(m) }
 func (*EntitiesQuery) ProtoMessage()    {}
 func (*EntitiesQuery) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{13}
+	return fileDescriptor_213b282dda0e8199, []int{14}
 }
 
 func (m *EntitiesQuery) XXX_Unmarshal(b []byte) error {
@@ -818,6 +1046,12 @@ type EntitiesQuery_Clause struct {
 	//	*EntitiesQuery_Clause_EntityId
 	//	*EntitiesQuery_Clause_Shard
 	//	*EntitiesQuery_Clause_Random
+	//	*EntitiesQuery_Clause_AnyOf_
+	//	*EntitiesQuery_Clause_Compare
+	//	*EntitiesQuery_Clause_Matches
+	//	*EntitiesQuery_Clause_Sort
+	//	*EntitiesQuery_Clause_Limit
+	//	*EntitiesQuery_Clause_Offset
 	Kind                 isEntitiesQuery_Clause_Kind `protobuf_oneof:"kind"`
 	Invert               bool                        `protobuf:"varint,3,opt,name=invert,proto3" json:"invert,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
@@ -829,7 +1063,7 @@ func (m *EntitiesQuery_Clause) Reset()         { *m = EntitiesQuery_Clause{} }
 func (m *EntitiesQuery_Clause) String() string { return proto.CompactTextString(m) }
 func (*EntitiesQuery_Clause) ProtoMessage()    {}
 func (*EntitiesQuery_Clause) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{13, 0}
+	return fileDescriptor_213b282dda0e8199, []int{14, 0}
 }
 
 func (m *EntitiesQuery_Clause) XXX_Unmarshal(b []byte) error {
@@ -874,6 +1108,30 @@ type EntitiesQuery_Clause_Random struct {
 	Random *EntitiesQuery_Clause_RandomSelection `protobuf:"bytes,6,opt,name=random,proto3,oneof"`
 }
 
+type EntitiesQuery_Clause_AnyOf_ struct {
+	AnyOf *EntitiesQuery_Clause_AnyOf `protobuf:"bytes,7,opt,name=any_of,json=anyOf,proto3,oneof"`
+}
+
+type EntitiesQuery_Clause_Compare struct {
+	Compare *EntitiesQuery_Clause_FileComparison `protobuf:"bytes,8,opt,name=compare,proto3,oneof"`
+}
+
+type EntitiesQuery_Clause_Matches struct {
+	Matches *EntitiesQuery_Clause_FileMatchesPattern `protobuf:"bytes,9,opt,name=matches,proto3,oneof"`
+}
+
+type EntitiesQuery_Clause_Sort struct {
+	Sort *EntitiesQuery_Clause_Ordering `protobuf:"bytes,10,opt,name=sort,proto3,oneof"`
+}
+
+type EntitiesQuery_Clause_Limit struct {
+	Limit int32 `protobuf:"varint,11,opt,name=limit,proto3,oneof"`
+}
+
+type EntitiesQuery_Clause_Offset struct {
+	Offset int32 `protobuf:"varint,12,opt,name=offset,proto3,oneof"`
+}
+
 func (*EntitiesQuery_Clause_FileExists) isEntitiesQuery_Clause_Kind() {}
 
 func (*EntitiesQuery_Clause_FileContents) isEntitiesQuery_Clause_Kind() {}
@@ -884,6 +1142,18 @@ func (*EntitiesQuery_Clause_Shard) isEntitiesQuery_Clause_Kind() {}
 
 func (*EntitiesQuery_Clause_Random) isEntitiesQuery_Clause_Kind() {}
 
+func (*EntitiesQuery_Clause_AnyOf_) isEntitiesQuery_Clause_Kind() {}
+
+func (*EntitiesQuery_Clause_Compare) isEntitiesQuery_Clause_Kind() {}
+
+func (*EntitiesQuery_Clause_Matches) isEntitiesQuery_Clause_Kind() {}
+
+func (*EntitiesQuery_Clause_Sort) isEntitiesQuery_Clause_Kind() {}
+
+func (*EntitiesQuery_Clause_Limit) isEntitiesQuery_Clause_Kind() {}
+
+func (*EntitiesQuery_Clause_Offset) isEntitiesQuery_Clause_Kind() {}
+
 func (m *EntitiesQuery_Clause) GetKind() isEntitiesQuery_Clause_Kind {
 	if m != nil {
 		return m.Kind
@@ -926,6 +1196,48 @@ func (m *EntitiesQuery_Clause) GetRandom() *EntitiesQuery_Clause_RandomSelection
 	return nil
 }
 
+func (m *EntitiesQuery_Clause) GetAnyOf() *EntitiesQuery_Clause_AnyOf {
+	if x, ok := m.GetKind().(*EntitiesQuery_Clause_AnyOf_); ok {
+		return x.AnyOf
+	}
+	return nil
+}
+
+func (m *EntitiesQuery_Clause) GetCompare() *EntitiesQuery_Clause_FileComparison {
+	if x, ok := m.GetKind().(*EntitiesQuery_Clause_Compare); ok {
+		return x.Compare
+	}
+	return nil
+}
+
+func (m *EntitiesQuery_Clause) GetMatches() *EntitiesQuery_Clause_FileMatchesPattern {
+	if x, ok := m.GetKind().(*EntitiesQuery_Clause_Matches); ok {
+		return x.Matches
+	}
+	return nil
+}
+
+func (m *EntitiesQuery_Clause) GetSort() *EntitiesQuery_Clause_Ordering {
+	if x, ok := m.GetKind().(*EntitiesQuery_Clause_Sort); ok {
+		return x.Sort
+	}
+	return nil
+}
+
+func (m *EntitiesQuery_Clause) GetLimit() int32 {
+	if x, ok := m.GetKind().(*EntitiesQuery_Clause_Limit); ok {
+		return x.Limit
+	}
+	return 0
+}
+
+func (m *EntitiesQuery_Clause) GetOffset() int32 {
+	if x, ok := m.GetKind().(*EntitiesQuery_Clause_Offset); ok {
+		return x.Offset
+	}
+	return 0
+}
+
 func (m *EntitiesQuery_Clause) GetInvert() bool {
 	if m != nil {
 		return m.Invert
@@ -941,6 +1253,12 @@ func (*EntitiesQuery_Clause) XXX_OneofFuncs() (func(msg proto.Message, b *proto.
 		(*EntitiesQuery_Clause_EntityId)(nil),
 		(*EntitiesQuery_Clause_Shard)(nil),
 		(*EntitiesQuery_Clause_Random)(nil),
+		(*EntitiesQuery_Clause_AnyOf_)(nil),
+		(*EntitiesQuery_Clause_Compare)(nil),
+		(*EntitiesQuery_Clause_Matches)(nil),
+		(*EntitiesQuery_Clause_Sort)(nil),
+		(*EntitiesQuery_Clause_Limit)(nil),
+		(*EntitiesQuery_Clause_Offset)(nil),
 	}
 }
 
@@ -969,6 +1287,32 @@ func _EntitiesQuery_Clause_OneofMarshaler(msg proto.Message, b *proto.Buffer) er
 		if err := b.EncodeMessage(x.Random); err != nil {
 			return err
 		}
+	case *EntitiesQuery_Clause_AnyOf_:
+		b.EncodeVarint(7<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.AnyOf); err != nil {
+			return err
+		}
+	case *EntitiesQuery_Clause_Compare:
+		b.EncodeVarint(8<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Compare); err != nil {
+			return err
+		}
+	case *EntitiesQuery_Clause_Matches:
+		b.EncodeVarint(9<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Matches); err != nil {
+			return err
+		}
+	case *EntitiesQuery_Clause_Sort:
+		b.EncodeVarint(10<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Sort); err != nil {
+			return err
+		}
+	case *EntitiesQuery_Clause_Limit:
+		b.EncodeVarint(11<<3 | proto.WireVarint)
+		b.EncodeVarint(uint64(x.Limit))
+	case *EntitiesQuery_Clause_Offset:
+		b.EncodeVarint(12<<3 | proto.WireVarint)
+		b.EncodeVarint(uint64(x.Offset))
 	case nil:
 	default:
 		return fmt.Errorf("EntitiesQuery_Clause.Kind has unexpected type %T", x)
@@ -1017,6 +1361,52 @@ func _EntitiesQuery_Clause_OneofUnmarshaler(msg proto.Message, tag, wire int, b
 		err := b.DecodeMessage(msg)
 		m.Kind = &EntitiesQuery_Clause_Random{msg}
 		return true, err
+	case 7: // kind.any_of
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(EntitiesQuery_Clause_AnyOf)
+		err := b.DecodeMessage(msg)
+		m.Kind = &EntitiesQuery_Clause_AnyOf_{msg}
+		return true, err
+	case 8: // kind.compare
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(EntitiesQuery_Clause_FileComparison)
+		err := b.DecodeMessage(msg)
+		m.Kind = &EntitiesQuery_Clause_Compare{msg}
+		return true, err
+	case 9: // kind.matches
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(EntitiesQuery_Clause_FileMatchesPattern)
+		err := b.DecodeMessage(msg)
+		m.Kind = &EntitiesQuery_Clause_Matches{msg}
+		return true, err
+	case 10: // kind.sort
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(EntitiesQuery_Clause_Ordering)
+		err := b.DecodeMessage(msg)
+		m.Kind = &EntitiesQuery_Clause_Sort{msg}
+		return true, err
+	case 11: // kind.limit
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Kind = &EntitiesQuery_Clause_Limit{int32(x)}
+		return true, err
+	case 12: // kind.offset
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Kind = &EntitiesQuery_Clause_Offset{int32(x)}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -1049,6 +1439,32 @@ func _EntitiesQuery_Clause_OneofSizer(msg proto.Message) (n int) {
 		n += 1 // tag and wire
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *EntitiesQuery_Clause_AnyOf_:
+		s := proto.Size(x.AnyOf)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *EntitiesQuery_Clause_Compare:
+		s := proto.Size(x.Compare)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *EntitiesQuery_Clause_Matches:
+		s := proto.Size(x.Matches)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *EntitiesQuery_Clause_Sort:
+		s := proto.Size(x.Sort)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *EntitiesQuery_Clause_Limit:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(x.Limit))
+	case *EntitiesQuery_Clause_Offset:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(x.Offset))
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -1072,7 +1488,7 @@ func (m *EntitiesQuery_Clause_FileHasTrimmedContents) String() string {
 }
 func (*EntitiesQuery_Clause_FileHasTrimmedContents) ProtoMessage() {}
 func (*EntitiesQuery_Clause_FileHasTrimmedContents) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{13, 0, 0}
+	return fileDescriptor_213b282dda0e8199, []int{14, 0, 0}
 }
 
 func (m *EntitiesQuery_Clause_FileHasTrimmedContents) XXX_Unmarshal(b []byte) error {
@@ -1118,7 +1534,7 @@ func (m *EntitiesQuery_Clause_EntityInShard) Reset()         { *m = EntitiesQuer
 func (m *EntitiesQuery_Clause_EntityInShard) String() string { return proto.CompactTextString(m) }
 func (*EntitiesQuery_Clause_EntityInShard) ProtoMessage()    {}
 func (*EntitiesQuery_Clause_EntityInShard) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{13, 0, 1}
+	return fileDescriptor_213b282dda0e8199, []int{14, 0, 1}
 }
 
 func (m *EntitiesQuery_Clause_EntityInShard) XXX_Unmarshal(b []byte) error {
@@ -1157,7 +1573,7 @@ func (m *EntitiesQuery_Clause_RandomSelection) Reset()         { *m = EntitiesQu
 func (m *EntitiesQuery_Clause_RandomSelection) String() string { return proto.CompactTextString(m) }
 func (*EntitiesQuery_Clause_RandomSelection) ProtoMessage()    {}
 func (*EntitiesQuery_Clause_RandomSelection) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{13, 0, 2}
+	return fileDescriptor_213b282dda0e8199, []int{14, 0, 2}
 }
 
 func (m *EntitiesQuery_Clause_RandomSelection) XXX_Unmarshal(b []byte) error {
@@ -1185,71 +1601,295 @@ func (m *EntitiesQuery_Clause_RandomSelection) GetNumber() int32 {
 	return 0
 }
 
-type AuthorshipMetadata struct {
-	Hostname             string   `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
-	Tool                 string   `protobuf:"bytes,2,opt,name=tool,proto3" json:"tool,omitempty"`
-	User                 string   `protobuf:"bytes,3,opt,name=user,proto3" json:"user,omitempty"`
-	QmfsVersioninfoJson  string   `protobuf:"bytes,4,opt,name=qmfs_versioninfo_json,json=qmfsVersioninfoJson,proto3" json:"qmfs_versioninfo_json,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+type EntitiesQuery_Clause_AnyOf struct {
+	// Alternative subqueries; at least one must be met.
+	Alternative          []*EntitiesQuery `protobuf:"bytes,1,rep,name=alternative,proto3" json:"alternative,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
-func (m *AuthorshipMetadata) Reset()         { *m = AuthorshipMetadata{} }
-func (m *AuthorshipMetadata) String() string { return proto.CompactTextString(m) }
-func (*AuthorshipMetadata) ProtoMessage()    {}
-func (*AuthorshipMetadata) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{14}
+func (m *EntitiesQuery_Clause_AnyOf) Reset()         { *m = EntitiesQuery_Clause_AnyOf{} }
+func (m *EntitiesQuery_Clause_AnyOf) String() string { return proto.CompactTextString(m) }
+func (*EntitiesQuery_Clause_AnyOf) ProtoMessage()    {}
+func (*EntitiesQuery_Clause_AnyOf) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{14, 0, 3}
 }
 
-func (m *AuthorshipMetadata) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_AuthorshipMetadata.Unmarshal(m, b)
+func (m *EntitiesQuery_Clause_AnyOf) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EntitiesQuery_Clause_AnyOf.Unmarshal(m, b)
 }
-func (m *AuthorshipMetadata) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_AuthorshipMetadata.Marshal(b, m, deterministic)
+func (m *EntitiesQuery_Clause_AnyOf) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EntitiesQuery_Clause_AnyOf.Marshal(b, m, deterministic)
 }
-func (m *AuthorshipMetadata) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_AuthorshipMetadata.Merge(m, src)
+func (m *EntitiesQuery_Clause_AnyOf) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EntitiesQuery_Clause_AnyOf.Merge(m, src)
 }
-func (m *AuthorshipMetadata) XXX_Size() int {
-	return xxx_messageInfo_AuthorshipMetadata.Size(m)
+func (m *EntitiesQuery_Clause_AnyOf) XXX_Size() int {
+	return xxx_messageInfo_EntitiesQuery_Clause_AnyOf.Size(m)
 }
-func (m *AuthorshipMetadata) XXX_DiscardUnknown() {
-	xxx_messageInfo_AuthorshipMetadata.DiscardUnknown(m)
+func (m *EntitiesQuery_Clause_AnyOf) XXX_DiscardUnknown() {
+	xxx_messageInfo_EntitiesQuery_Clause_AnyOf.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_AuthorshipMetadata proto.InternalMessageInfo
+var xxx_messageInfo_EntitiesQuery_Clause_AnyOf proto.InternalMessageInfo
 
-func (m *AuthorshipMetadata) GetHostname() string {
+func (m *EntitiesQuery_Clause_AnyOf) GetAlternative() []*EntitiesQuery {
 	if m != nil {
-		return m.Hostname
+		return m.Alternative
 	}
-	return ""
+	return nil
 }
 
-func (m *AuthorshipMetadata) GetTool() string {
-	if m != nil {
-		return m.Tool
-	}
-	return ""
+type EntitiesQuery_Clause_FileComparison struct {
+	Filename             string                                        `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Operator             EntitiesQuery_Clause_FileComparison_Operator  `protobuf:"varint,2,opt,name=operator,proto3,enum=qmfspb.EntitiesQuery_Clause_FileComparison_Operator" json:"operator,omitempty"`
+	Value                string                                        `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Semantics            EntitiesQuery_Clause_FileComparison_Semantics `protobuf:"varint,4,opt,name=semantics,proto3,enum=qmfspb.EntitiesQuery_Clause_FileComparison_Semantics" json:"semantics,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                                      `json:"-"`
+	XXX_unrecognized     []byte                                        `json:"-"`
+	XXX_sizecache        int32                                         `json:"-"`
 }
 
-func (m *AuthorshipMetadata) GetUser() string {
-	if m != nil {
-		return m.User
-	}
-	return ""
+func (m *EntitiesQuery_Clause_FileComparison) Reset()         { *m = EntitiesQuery_Clause_FileComparison{} }
+func (m *EntitiesQuery_Clause_FileComparison) String() string { return proto.CompactTextString(m) }
+func (*EntitiesQuery_Clause_FileComparison) ProtoMessage()    {}
+func (*EntitiesQuery_Clause_FileComparison) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{14, 0, 4}
 }
 
-func (m *AuthorshipMetadata) GetQmfsVersioninfoJson() string {
-	if m != nil {
-		return m.QmfsVersioninfoJson
+func (m *EntitiesQuery_Clause_FileComparison) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EntitiesQuery_Clause_FileComparison.Unmarshal(m, b)
+}
+func (m *EntitiesQuery_Clause_FileComparison) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EntitiesQuery_Clause_FileComparison.Marshal(b, m, deterministic)
+}
+func (m *EntitiesQuery_Clause_FileComparison) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EntitiesQuery_Clause_FileComparison.Merge(m, src)
+}
+func (m *EntitiesQuery_Clause_FileComparison) XXX_Size() int {
+	return xxx_messageInfo_EntitiesQuery_Clause_FileComparison.Size(m)
+}
+func (m *EntitiesQuery_Clause_FileComparison) XXX_DiscardUnknown() {
+	xxx_messageInfo_EntitiesQuery_Clause_FileComparison.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EntitiesQuery_Clause_FileComparison proto.InternalMessageInfo
+
+func (m *EntitiesQuery_Clause_FileComparison) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *EntitiesQuery_Clause_FileComparison) GetOperator() EntitiesQuery_Clause_FileComparison_Operator {
+	if m != nil {
+		return m.Operator
+	}
+	return EntitiesQuery_Clause_FileComparison_INVALID_OPERATOR
+}
+
+func (m *EntitiesQuery_Clause_FileComparison) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *EntitiesQuery_Clause_FileComparison) GetSemantics() EntitiesQuery_Clause_FileComparison_Semantics {
+	if m != nil {
+		return m.Semantics
+	}
+	return EntitiesQuery_Clause_FileComparison_INVALID_SEMANTICS
+}
+
+type EntitiesQuery_Clause_FileMatchesPattern struct {
+	Filename             string                                       `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Mode                 EntitiesQuery_Clause_FileMatchesPattern_Mode `protobuf:"varint,2,opt,name=mode,proto3,enum=qmfspb.EntitiesQuery_Clause_FileMatchesPattern_Mode" json:"mode,omitempty"`
+	Pattern              string                                       `protobuf:"bytes,3,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                                     `json:"-"`
+	XXX_unrecognized     []byte                                       `json:"-"`
+	XXX_sizecache        int32                                        `json:"-"`
+}
+
+func (m *EntitiesQuery_Clause_FileMatchesPattern) Reset() {
+	*m = EntitiesQuery_Clause_FileMatchesPattern{}
+}
+func (m *EntitiesQuery_Clause_FileMatchesPattern) String() string { return proto.CompactTextString(m) }
+func (*EntitiesQuery_Clause_FileMatchesPattern) ProtoMessage()    {}
+func (*EntitiesQuery_Clause_FileMatchesPattern) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{14, 0, 5}
+}
+
+func (m *EntitiesQuery_Clause_FileMatchesPattern) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EntitiesQuery_Clause_FileMatchesPattern.Unmarshal(m, b)
+}
+func (m *EntitiesQuery_Clause_FileMatchesPattern) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EntitiesQuery_Clause_FileMatchesPattern.Marshal(b, m, deterministic)
+}
+func (m *EntitiesQuery_Clause_FileMatchesPattern) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EntitiesQuery_Clause_FileMatchesPattern.Merge(m, src)
+}
+func (m *EntitiesQuery_Clause_FileMatchesPattern) XXX_Size() int {
+	return xxx_messageInfo_EntitiesQuery_Clause_FileMatchesPattern.Size(m)
+}
+func (m *EntitiesQuery_Clause_FileMatchesPattern) XXX_DiscardUnknown() {
+	xxx_messageInfo_EntitiesQuery_Clause_FileMatchesPattern.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EntitiesQuery_Clause_FileMatchesPattern proto.InternalMessageInfo
+
+func (m *EntitiesQuery_Clause_FileMatchesPattern) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *EntitiesQuery_Clause_FileMatchesPattern) GetMode() EntitiesQuery_Clause_FileMatchesPattern_Mode {
+	if m != nil {
+		return m.Mode
+	}
+	return EntitiesQuery_Clause_FileMatchesPattern_INVALID_MODE
+}
+
+func (m *EntitiesQuery_Clause_FileMatchesPattern) GetPattern() string {
+	if m != nil {
+		return m.Pattern
+	}
+	return ""
+}
+
+type EntitiesQuery_Clause_Ordering struct {
+	// Entities lacking the file are ordered last.
+	Filename   string `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Descending bool   `protobuf:"varint,2,opt,name=descending,proto3" json:"descending,omitempty"`
+	// Order by the numeric value of the trimmed contents rather than bytewise.
+	Numeric              bool     `protobuf:"varint,3,opt,name=numeric,proto3" json:"numeric,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EntitiesQuery_Clause_Ordering) Reset()         { *m = EntitiesQuery_Clause_Ordering{} }
+func (m *EntitiesQuery_Clause_Ordering) String() string { return proto.CompactTextString(m) }
+func (*EntitiesQuery_Clause_Ordering) ProtoMessage()    {}
+func (*EntitiesQuery_Clause_Ordering) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{14, 0, 6}
+}
+
+func (m *EntitiesQuery_Clause_Ordering) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EntitiesQuery_Clause_Ordering.Unmarshal(m, b)
+}
+func (m *EntitiesQuery_Clause_Ordering) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EntitiesQuery_Clause_Ordering.Marshal(b, m, deterministic)
+}
+func (m *EntitiesQuery_Clause_Ordering) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EntitiesQuery_Clause_Ordering.Merge(m, src)
+}
+func (m *EntitiesQuery_Clause_Ordering) XXX_Size() int {
+	return xxx_messageInfo_EntitiesQuery_Clause_Ordering.Size(m)
+}
+func (m *EntitiesQuery_Clause_Ordering) XXX_DiscardUnknown() {
+	xxx_messageInfo_EntitiesQuery_Clause_Ordering.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EntitiesQuery_Clause_Ordering proto.InternalMessageInfo
+
+func (m *EntitiesQuery_Clause_Ordering) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *EntitiesQuery_Clause_Ordering) GetDescending() bool {
+	if m != nil {
+		return m.Descending
+	}
+	return false
+}
+
+func (m *EntitiesQuery_Clause_Ordering) GetNumeric() bool {
+	if m != nil {
+		return m.Numeric
+	}
+	return false
+}
+
+type AuthorshipMetadata struct {
+	Hostname             string   `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Tool                 string   `protobuf:"bytes,2,opt,name=tool,proto3" json:"tool,omitempty"`
+	User                 string   `protobuf:"bytes,3,opt,name=user,proto3" json:"user,omitempty"`
+	QmfsVersioninfoJson  string   `protobuf:"bytes,4,opt,name=qmfs_versioninfo_json,json=qmfsVersioninfoJson,proto3" json:"qmfs_versioninfo_json,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuthorshipMetadata) Reset()         { *m = AuthorshipMetadata{} }
+func (m *AuthorshipMetadata) String() string { return proto.CompactTextString(m) }
+func (*AuthorshipMetadata) ProtoMessage()    {}
+func (*AuthorshipMetadata) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{15}
+}
+
+func (m *AuthorshipMetadata) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthorshipMetadata.Unmarshal(m, b)
+}
+func (m *AuthorshipMetadata) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthorshipMetadata.Marshal(b, m, deterministic)
+}
+func (m *AuthorshipMetadata) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthorshipMetadata.Merge(m, src)
+}
+func (m *AuthorshipMetadata) XXX_Size() int {
+	return xxx_messageInfo_AuthorshipMetadata.Size(m)
+}
+func (m *AuthorshipMetadata) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthorshipMetadata.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuthorshipMetadata proto.InternalMessageInfo
+
+func (m *AuthorshipMetadata) GetHostname() string {
+	if m != nil {
+		return m.Hostname
+	}
+	return ""
+}
+
+func (m *AuthorshipMetadata) GetTool() string {
+	if m != nil {
+		return m.Tool
+	}
+	return ""
+}
+
+func (m *AuthorshipMetadata) GetUser() string {
+	if m != nil {
+		return m.User
+	}
+	return ""
+}
+
+func (m *AuthorshipMetadata) GetQmfsVersioninfoJson() string {
+	if m != nil {
+		return m.QmfsVersioninfoJson
 	}
 	return ""
 }
 
 type QueryEntitiesRequest struct {
 	Namespace string `protobuf:"bytes,5,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// If set, evaluate the query against the state at this point in time.
+	// Clauses that depend on file contents beyond their checksums then fail
+	// with FailedPrecondition unless the database keeps the contents of old
+	// revisions.
+	AsOf *Timestamp `protobuf:"bytes,6,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	// Files to return along with each matching entity, where present.
+	IncludeFilename []string `protobuf:"bytes,7,rep,name=include_filename,json=includeFilename,proto3" json:"include_filename,omitempty"`
 	// Types that are valid to be assigned to Kind:
 	//	*QueryEntitiesRequest_RawQuery
 	//	*QueryEntitiesRequest_ParsedQuery
@@ -1265,7 +1905,7 @@ func (m *QueryEntitiesRequest) Reset()         { *m = QueryEntitiesRequest{} }
 func (m *QueryEntitiesRequest) String() string { return proto.CompactTextString(m) }
 func (*QueryEntitiesRequest) ProtoMessage()    {}
 func (*QueryEntitiesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{15}
+	return fileDescriptor_213b282dda0e8199, []int{16}
 }
 
 func (m *QueryEntitiesRequest) XXX_Unmarshal(b []byte) error {
@@ -1293,6 +1933,20 @@ func (m *QueryEntitiesRequest) GetNamespace() string {
 	return ""
 }
 
+func (m *QueryEntitiesRequest) GetAsOf() *Timestamp {
+	if m != nil {
+		return m.AsOf
+	}
+	return nil
+}
+
+func (m *QueryEntitiesRequest) GetIncludeFilename() []string {
+	if m != nil {
+		return m.IncludeFilename
+	}
+	return nil
+}
+
 type isQueryEntitiesRequest_Kind interface {
 	isQueryEntitiesRequest_Kind()
 }
@@ -1459,129 +2113,387 @@ func _QueryEntitiesRequest_OneofSizer(msg proto.Message) (n int) {
 	return n
 }
 
-type QueryEntitiesResponse struct {
-	EntityId             string   `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+type AggregateEntitiesRequest struct {
+	Query                *QueryEntitiesRequest             `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Function             AggregateEntitiesRequest_Function `protobuf:"varint,2,opt,name=function,proto3,enum=qmfspb.AggregateEntitiesRequest_Function" json:"function,omitempty"`
+	Filename             string                            `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                          `json:"-"`
+	XXX_unrecognized     []byte                            `json:"-"`
+	XXX_sizecache        int32                             `json:"-"`
 }
 
-func (m *QueryEntitiesResponse) Reset()         { *m = QueryEntitiesResponse{} }
-func (m *QueryEntitiesResponse) String() string { return proto.CompactTextString(m) }
-func (*QueryEntitiesResponse) ProtoMessage()    {}
-func (*QueryEntitiesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{16}
+func (m *AggregateEntitiesRequest) Reset()         { *m = AggregateEntitiesRequest{} }
+func (m *AggregateEntitiesRequest) String() string { return proto.CompactTextString(m) }
+func (*AggregateEntitiesRequest) ProtoMessage()    {}
+func (*AggregateEntitiesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{17}
 }
 
-func (m *QueryEntitiesResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_QueryEntitiesResponse.Unmarshal(m, b)
+func (m *AggregateEntitiesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AggregateEntitiesRequest.Unmarshal(m, b)
 }
-func (m *QueryEntitiesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_QueryEntitiesResponse.Marshal(b, m, deterministic)
+func (m *AggregateEntitiesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AggregateEntitiesRequest.Marshal(b, m, deterministic)
 }
-func (m *QueryEntitiesResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_QueryEntitiesResponse.Merge(m, src)
+func (m *AggregateEntitiesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AggregateEntitiesRequest.Merge(m, src)
 }
-func (m *QueryEntitiesResponse) XXX_Size() int {
-	return xxx_messageInfo_QueryEntitiesResponse.Size(m)
+func (m *AggregateEntitiesRequest) XXX_Size() int {
+	return xxx_messageInfo_AggregateEntitiesRequest.Size(m)
 }
-func (m *QueryEntitiesResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_QueryEntitiesResponse.DiscardUnknown(m)
+func (m *AggregateEntitiesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AggregateEntitiesRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_QueryEntitiesResponse proto.InternalMessageInfo
+var xxx_messageInfo_AggregateEntitiesRequest proto.InternalMessageInfo
 
-func (m *QueryEntitiesResponse) GetEntityId() string {
+func (m *AggregateEntitiesRequest) GetQuery() *QueryEntitiesRequest {
 	if m != nil {
-		return m.EntityId
+		return m.Query
+	}
+	return nil
+}
+
+func (m *AggregateEntitiesRequest) GetFunction() AggregateEntitiesRequest_Function {
+	if m != nil {
+		return m.Function
+	}
+	return AggregateEntitiesRequest_INVALID_FUNCTION
+}
+
+func (m *AggregateEntitiesRequest) GetFilename() string {
+	if m != nil {
+		return m.Filename
 	}
 	return ""
 }
 
-type ListNamespacesRequest struct {
+type AggregateEntitiesResponse struct {
+	// Number of matching entities.
+	Count int64 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	// Number of values aggregated; zero for COUNT.
+	ValueCount int64 `protobuf:"varint,2,opt,name=value_count,json=valueCount,proto3" json:"value_count,omitempty"`
+	// Only meaningful if value_count is nonzero.
+	Value                float64  `protobuf:"fixed64,3,opt,name=value,proto3" json:"value,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *ListNamespacesRequest) Reset()         { *m = ListNamespacesRequest{} }
-func (m *ListNamespacesRequest) String() string { return proto.CompactTextString(m) }
-func (*ListNamespacesRequest) ProtoMessage()    {}
-func (*ListNamespacesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{17}
+func (m *AggregateEntitiesResponse) Reset()         { *m = AggregateEntitiesResponse{} }
+func (m *AggregateEntitiesResponse) String() string { return proto.CompactTextString(m) }
+func (*AggregateEntitiesResponse) ProtoMessage()    {}
+func (*AggregateEntitiesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{18}
 }
 
-func (m *ListNamespacesRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_ListNamespacesRequest.Unmarshal(m, b)
+func (m *AggregateEntitiesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AggregateEntitiesResponse.Unmarshal(m, b)
 }
-func (m *ListNamespacesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_ListNamespacesRequest.Marshal(b, m, deterministic)
+func (m *AggregateEntitiesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AggregateEntitiesResponse.Marshal(b, m, deterministic)
 }
-func (m *ListNamespacesRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ListNamespacesRequest.Merge(m, src)
+func (m *AggregateEntitiesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AggregateEntitiesResponse.Merge(m, src)
 }
-func (m *ListNamespacesRequest) XXX_Size() int {
-	return xxx_messageInfo_ListNamespacesRequest.Size(m)
+func (m *AggregateEntitiesResponse) XXX_Size() int {
+	return xxx_messageInfo_AggregateEntitiesResponse.Size(m)
 }
-func (m *ListNamespacesRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_ListNamespacesRequest.DiscardUnknown(m)
+func (m *AggregateEntitiesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AggregateEntitiesResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_ListNamespacesRequest proto.InternalMessageInfo
+var xxx_messageInfo_AggregateEntitiesResponse proto.InternalMessageInfo
 
-type ListNamespacesResponse struct {
-	Namespace            []string `protobuf:"bytes,1,rep,name=namespace,proto3" json:"namespace,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *AggregateEntitiesResponse) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
 }
 
-func (m *ListNamespacesResponse) Reset()         { *m = ListNamespacesResponse{} }
-func (m *ListNamespacesResponse) String() string { return proto.CompactTextString(m) }
-func (*ListNamespacesResponse) ProtoMessage()    {}
-func (*ListNamespacesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{18}
+func (m *AggregateEntitiesResponse) GetValueCount() int64 {
+	if m != nil {
+		return m.ValueCount
+	}
+	return 0
 }
 
-func (m *ListNamespacesResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_ListNamespacesResponse.Unmarshal(m, b)
+func (m *AggregateEntitiesResponse) GetValue() float64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
 }
-func (m *ListNamespacesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_ListNamespacesResponse.Marshal(b, m, deterministic)
+
+type CountValuesRequest struct {
+	Query                *QueryEntitiesRequest `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Filename             string                `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
 }
-func (m *ListNamespacesResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ListNamespacesResponse.Merge(m, src)
+
+func (m *CountValuesRequest) Reset()         { *m = CountValuesRequest{} }
+func (m *CountValuesRequest) String() string { return proto.CompactTextString(m) }
+func (*CountValuesRequest) ProtoMessage()    {}
+func (*CountValuesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{19}
 }
-func (m *ListNamespacesResponse) XXX_Size() int {
-	return xxx_messageInfo_ListNamespacesResponse.Size(m)
+
+func (m *CountValuesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CountValuesRequest.Unmarshal(m, b)
 }
-func (m *ListNamespacesResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_ListNamespacesResponse.DiscardUnknown(m)
+func (m *CountValuesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CountValuesRequest.Marshal(b, m, deterministic)
+}
+func (m *CountValuesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CountValuesRequest.Merge(m, src)
+}
+func (m *CountValuesRequest) XXX_Size() int {
+	return xxx_messageInfo_CountValuesRequest.Size(m)
+}
+func (m *CountValuesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CountValuesRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_ListNamespacesResponse proto.InternalMessageInfo
+var xxx_messageInfo_CountValuesRequest proto.InternalMessageInfo
 
-func (m *ListNamespacesResponse) GetNamespace() []string {
+func (m *CountValuesRequest) GetQuery() *QueryEntitiesRequest {
 	if m != nil {
-		return m.Namespace
+		return m.Query
 	}
 	return nil
 }
 
-type SizeMetadata struct {
-	TotalRows            int64    `protobuf:"varint,2,opt,name=total_rows,json=totalRows,proto3" json:"total_rows,omitempty"`
-	ActiveRows           int64    `protobuf:"varint,3,opt,name=active_rows,json=activeRows,proto3" json:"active_rows,omitempty"`
-	TotalStoredDataBytes int64    `protobuf:"varint,4,opt,name=total_stored_data_bytes,json=totalStoredDataBytes,proto3" json:"total_stored_data_bytes,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *CountValuesRequest) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
 }
 
-func (m *SizeMetadata) Reset()         { *m = SizeMetadata{} }
+type CountValuesResponse struct {
+	// Most common values first.
+	Value                []*CountValuesResponse_Value `protobuf:"bytes,1,rep,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
+	XXX_unrecognized     []byte                       `json:"-"`
+	XXX_sizecache        int32                        `json:"-"`
+}
+
+func (m *CountValuesResponse) Reset()         { *m = CountValuesResponse{} }
+func (m *CountValuesResponse) String() string { return proto.CompactTextString(m) }
+func (*CountValuesResponse) ProtoMessage()    {}
+func (*CountValuesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{20}
+}
+
+func (m *CountValuesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CountValuesResponse.Unmarshal(m, b)
+}
+func (m *CountValuesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CountValuesResponse.Marshal(b, m, deterministic)
+}
+func (m *CountValuesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CountValuesResponse.Merge(m, src)
+}
+func (m *CountValuesResponse) XXX_Size() int {
+	return xxx_messageInfo_CountValuesResponse.Size(m)
+}
+func (m *CountValuesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CountValuesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CountValuesResponse proto.InternalMessageInfo
+
+func (m *CountValuesResponse) GetValue() []*CountValuesResponse_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+type CountValuesResponse_Value struct {
+	// Trimmed contents of the file.
+	Value string `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	// Number of matching entities having this value.
+	Count                int64    `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CountValuesResponse_Value) Reset()         { *m = CountValuesResponse_Value{} }
+func (m *CountValuesResponse_Value) String() string { return proto.CompactTextString(m) }
+func (*CountValuesResponse_Value) ProtoMessage()    {}
+func (*CountValuesResponse_Value) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{20, 0}
+}
+
+func (m *CountValuesResponse_Value) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CountValuesResponse_Value.Unmarshal(m, b)
+}
+func (m *CountValuesResponse_Value) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CountValuesResponse_Value.Marshal(b, m, deterministic)
+}
+func (m *CountValuesResponse_Value) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CountValuesResponse_Value.Merge(m, src)
+}
+func (m *CountValuesResponse_Value) XXX_Size() int {
+	return xxx_messageInfo_CountValuesResponse_Value.Size(m)
+}
+func (m *CountValuesResponse_Value) XXX_DiscardUnknown() {
+	xxx_messageInfo_CountValuesResponse_Value.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CountValuesResponse_Value proto.InternalMessageInfo
+
+func (m *CountValuesResponse_Value) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *CountValuesResponse_Value) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+type QueryEntitiesResponse struct {
+	EntityId string `protobuf:"bytes,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
+	// Those of the requested include_filename files the entity has.
+	File                 []*EntityFile `protobuf:"bytes,2,rep,name=file,proto3" json:"file,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *QueryEntitiesResponse) Reset()         { *m = QueryEntitiesResponse{} }
+func (m *QueryEntitiesResponse) String() string { return proto.CompactTextString(m) }
+func (*QueryEntitiesResponse) ProtoMessage()    {}
+func (*QueryEntitiesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{21}
+}
+
+func (m *QueryEntitiesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_QueryEntitiesResponse.Unmarshal(m, b)
+}
+func (m *QueryEntitiesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_QueryEntitiesResponse.Marshal(b, m, deterministic)
+}
+func (m *QueryEntitiesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueryEntitiesResponse.Merge(m, src)
+}
+func (m *QueryEntitiesResponse) XXX_Size() int {
+	return xxx_messageInfo_QueryEntitiesResponse.Size(m)
+}
+func (m *QueryEntitiesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueryEntitiesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueryEntitiesResponse proto.InternalMessageInfo
+
+func (m *QueryEntitiesResponse) GetEntityId() string {
+	if m != nil {
+		return m.EntityId
+	}
+	return ""
+}
+
+func (m *QueryEntitiesResponse) GetFile() []*EntityFile {
+	if m != nil {
+		return m.File
+	}
+	return nil
+}
+
+type ListNamespacesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListNamespacesRequest) Reset()         { *m = ListNamespacesRequest{} }
+func (m *ListNamespacesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListNamespacesRequest) ProtoMessage()    {}
+func (*ListNamespacesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{22}
+}
+
+func (m *ListNamespacesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListNamespacesRequest.Unmarshal(m, b)
+}
+func (m *ListNamespacesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListNamespacesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListNamespacesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListNamespacesRequest.Merge(m, src)
+}
+func (m *ListNamespacesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListNamespacesRequest.Size(m)
+}
+func (m *ListNamespacesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListNamespacesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListNamespacesRequest proto.InternalMessageInfo
+
+type ListNamespacesResponse struct {
+	Namespace            []string `protobuf:"bytes,1,rep,name=namespace,proto3" json:"namespace,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListNamespacesResponse) Reset()         { *m = ListNamespacesResponse{} }
+func (m *ListNamespacesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListNamespacesResponse) ProtoMessage()    {}
+func (*ListNamespacesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{23}
+}
+
+func (m *ListNamespacesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListNamespacesResponse.Unmarshal(m, b)
+}
+func (m *ListNamespacesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListNamespacesResponse.Marshal(b, m, deterministic)
+}
+func (m *ListNamespacesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListNamespacesResponse.Merge(m, src)
+}
+func (m *ListNamespacesResponse) XXX_Size() int {
+	return xxx_messageInfo_ListNamespacesResponse.Size(m)
+}
+func (m *ListNamespacesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListNamespacesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListNamespacesResponse proto.InternalMessageInfo
+
+func (m *ListNamespacesResponse) GetNamespace() []string {
+	if m != nil {
+		return m.Namespace
+	}
+	return nil
+}
+
+type SizeMetadata struct {
+	TotalRows            int64    `protobuf:"varint,2,opt,name=total_rows,json=totalRows,proto3" json:"total_rows,omitempty"`
+	ActiveRows           int64    `protobuf:"varint,3,opt,name=active_rows,json=activeRows,proto3" json:"active_rows,omitempty"`
+	TotalStoredDataBytes int64    `protobuf:"varint,4,opt,name=total_stored_data_bytes,json=totalStoredDataBytes,proto3" json:"total_stored_data_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SizeMetadata) Reset()         { *m = SizeMetadata{} }
 func (m *SizeMetadata) String() string { return proto.CompactTextString(m) }
 func (*SizeMetadata) ProtoMessage()    {}
 func (*SizeMetadata) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{19}
+	return fileDescriptor_213b282dda0e8199, []int{24}
 }
 
 func (m *SizeMetadata) XXX_Unmarshal(b []byte) error {
@@ -1634,7 +2546,7 @@ func (m *ShardingKey) Reset()         { *m = ShardingKey{} }
 func (m *ShardingKey) String() string { return proto.CompactTextString(m) }
 func (*ShardingKey) ProtoMessage()    {}
 func (*ShardingKey) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{20}
+	return fileDescriptor_213b282dda0e8199, []int{25}
 }
 
 func (m *ShardingKey) XXX_Unmarshal(b []byte) error {
@@ -1675,7 +2587,7 @@ func (m *DatabaseMetadata) Reset()         { *m = DatabaseMetadata{} }
 func (m *DatabaseMetadata) String() string { return proto.CompactTextString(m) }
 func (*DatabaseMetadata) ProtoMessage()    {}
 func (*DatabaseMetadata) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{21}
+	return fileDescriptor_213b282dda0e8199, []int{26}
 }
 
 func (m *DatabaseMetadata) XXX_Unmarshal(b []byte) error {
@@ -1728,75 +2640,1122 @@ func (m *GetDatabaseMetadataRequest) Reset()         { *m = GetDatabaseMetadataR
 func (m *GetDatabaseMetadataRequest) String() string { return proto.CompactTextString(m) }
 func (*GetDatabaseMetadataRequest) ProtoMessage()    {}
 func (*GetDatabaseMetadataRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{22}
+	return fileDescriptor_213b282dda0e8199, []int{27}
 }
 
 func (m *GetDatabaseMetadataRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetDatabaseMetadataRequest.Unmarshal(m, b)
 }
-func (m *GetDatabaseMetadataRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GetDatabaseMetadataRequest.Marshal(b, m, deterministic)
+func (m *GetDatabaseMetadataRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetDatabaseMetadataRequest.Marshal(b, m, deterministic)
+}
+func (m *GetDatabaseMetadataRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetDatabaseMetadataRequest.Merge(m, src)
+}
+func (m *GetDatabaseMetadataRequest) XXX_Size() int {
+	return xxx_messageInfo_GetDatabaseMetadataRequest.Size(m)
+}
+func (m *GetDatabaseMetadataRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetDatabaseMetadataRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetDatabaseMetadataRequest proto.InternalMessageInfo
+
+func (m *GetDatabaseMetadataRequest) GetOnlyTimestamps() bool {
+	if m != nil {
+		return m.OnlyTimestamps
+	}
+	return false
+}
+
+type GetDatabaseMetadataResponse struct {
+	Metadata             *DatabaseMetadata `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *GetDatabaseMetadataResponse) Reset()         { *m = GetDatabaseMetadataResponse{} }
+func (m *GetDatabaseMetadataResponse) String() string { return proto.CompactTextString(m) }
+func (*GetDatabaseMetadataResponse) ProtoMessage()    {}
+func (*GetDatabaseMetadataResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{28}
+}
+
+func (m *GetDatabaseMetadataResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetDatabaseMetadataResponse.Unmarshal(m, b)
+}
+func (m *GetDatabaseMetadataResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetDatabaseMetadataResponse.Marshal(b, m, deterministic)
+}
+func (m *GetDatabaseMetadataResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetDatabaseMetadataResponse.Merge(m, src)
+}
+func (m *GetDatabaseMetadataResponse) XXX_Size() int {
+	return xxx_messageInfo_GetDatabaseMetadataResponse.Size(m)
+}
+func (m *GetDatabaseMetadataResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetDatabaseMetadataResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetDatabaseMetadataResponse proto.InternalMessageInfo
+
+func (m *GetDatabaseMetadataResponse) GetMetadata() *DatabaseMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type FileRevision struct {
+	Header             *EntityFileHeader   `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	AuthorshipMetadata *AuthorshipMetadata `protobuf:"bytes,2,opt,name=authorship_metadata,json=authorshipMetadata,proto3" json:"authorship_metadata,omitempty"`
+	// Whether this is the current revision of the file.
+	Active bool `protobuf:"varint,3,opt,name=active,proto3" json:"active,omitempty"`
+	// Whether the contents of this revision are still stored.
+	DataRetained         bool     `protobuf:"varint,4,opt,name=data_retained,json=dataRetained,proto3" json:"data_retained,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FileRevision) Reset()         { *m = FileRevision{} }
+func (m *FileRevision) String() string { return proto.CompactTextString(m) }
+func (*FileRevision) ProtoMessage()    {}
+func (*FileRevision) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{29}
+}
+
+func (m *FileRevision) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileRevision.Unmarshal(m, b)
+}
+func (m *FileRevision) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileRevision.Marshal(b, m, deterministic)
+}
+func (m *FileRevision) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileRevision.Merge(m, src)
+}
+func (m *FileRevision) XXX_Size() int {
+	return xxx_messageInfo_FileRevision.Size(m)
+}
+func (m *FileRevision) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileRevision.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileRevision proto.InternalMessageInfo
+
+func (m *FileRevision) GetHeader() *EntityFileHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *FileRevision) GetAuthorshipMetadata() *AuthorshipMetadata {
+	if m != nil {
+		return m.AuthorshipMetadata
+	}
+	return nil
+}
+
+func (m *FileRevision) GetActive() bool {
+	if m != nil {
+		return m.Active
+	}
+	return false
+}
+
+func (m *FileRevision) GetDataRetained() bool {
+	if m != nil {
+		return m.DataRetained
+	}
+	return false
+}
+
+// A revision of a file, as stored in an export archive.
+type ArchivedRevision struct {
+	File               *EntityFile         `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	AuthorshipMetadata *AuthorshipMetadata `protobuf:"bytes,2,opt,name=authorship_metadata,json=authorshipMetadata,proto3" json:"authorship_metadata,omitempty"`
+	// Whether this was the current revision of the file when exported.
+	Active bool `protobuf:"varint,3,opt,name=active,proto3" json:"active,omitempty"`
+	// Whether the contents of this revision were still stored when exported.
+	DataRetained         bool     `protobuf:"varint,4,opt,name=data_retained,json=dataRetained,proto3" json:"data_retained,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ArchivedRevision) Reset()         { *m = ArchivedRevision{} }
+func (m *ArchivedRevision) String() string { return proto.CompactTextString(m) }
+func (*ArchivedRevision) ProtoMessage()    {}
+func (*ArchivedRevision) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{30}
+}
+
+func (m *ArchivedRevision) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ArchivedRevision.Unmarshal(m, b)
+}
+func (m *ArchivedRevision) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ArchivedRevision.Marshal(b, m, deterministic)
+}
+func (m *ArchivedRevision) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ArchivedRevision.Merge(m, src)
+}
+func (m *ArchivedRevision) XXX_Size() int {
+	return xxx_messageInfo_ArchivedRevision.Size(m)
+}
+func (m *ArchivedRevision) XXX_DiscardUnknown() {
+	xxx_messageInfo_ArchivedRevision.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ArchivedRevision proto.InternalMessageInfo
+
+func (m *ArchivedRevision) GetFile() *EntityFile {
+	if m != nil {
+		return m.File
+	}
+	return nil
+}
+
+func (m *ArchivedRevision) GetAuthorshipMetadata() *AuthorshipMetadata {
+	if m != nil {
+		return m.AuthorshipMetadata
+	}
+	return nil
+}
+
+func (m *ArchivedRevision) GetActive() bool {
+	if m != nil {
+		return m.Active
+	}
+	return false
+}
+
+func (m *ArchivedRevision) GetDataRetained() bool {
+	if m != nil {
+		return m.DataRetained
+	}
+	return false
+}
+
+type ListFileRevisionsRequest struct {
+	Namespace            string   `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	EntityId             string   `protobuf:"bytes,2,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
+	Filename             string   `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListFileRevisionsRequest) Reset()         { *m = ListFileRevisionsRequest{} }
+func (m *ListFileRevisionsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListFileRevisionsRequest) ProtoMessage()    {}
+func (*ListFileRevisionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{31}
+}
+
+func (m *ListFileRevisionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFileRevisionsRequest.Unmarshal(m, b)
+}
+func (m *ListFileRevisionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFileRevisionsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListFileRevisionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFileRevisionsRequest.Merge(m, src)
+}
+func (m *ListFileRevisionsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListFileRevisionsRequest.Size(m)
+}
+func (m *ListFileRevisionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFileRevisionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFileRevisionsRequest proto.InternalMessageInfo
+
+func (m *ListFileRevisionsRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *ListFileRevisionsRequest) GetEntityId() string {
+	if m != nil {
+		return m.EntityId
+	}
+	return ""
+}
+
+func (m *ListFileRevisionsRequest) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+type ListFileRevisionsResponse struct {
+	// Revisions, oldest first. Includes deletions (as tombstones).
+	Revision             []*FileRevision `protobuf:"bytes,1,rep,name=revision,proto3" json:"revision,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ListFileRevisionsResponse) Reset()         { *m = ListFileRevisionsResponse{} }
+func (m *ListFileRevisionsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListFileRevisionsResponse) ProtoMessage()    {}
+func (*ListFileRevisionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{32}
+}
+
+func (m *ListFileRevisionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListFileRevisionsResponse.Unmarshal(m, b)
+}
+func (m *ListFileRevisionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListFileRevisionsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListFileRevisionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListFileRevisionsResponse.Merge(m, src)
+}
+func (m *ListFileRevisionsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListFileRevisionsResponse.Size(m)
+}
+func (m *ListFileRevisionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListFileRevisionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListFileRevisionsResponse proto.InternalMessageInfo
+
+func (m *ListFileRevisionsResponse) GetRevision() []*FileRevision {
+	if m != nil {
+		return m.Revision
+	}
+	return nil
+}
+
+type ReadFileRevisionRequest struct {
+	Namespace string `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	EntityId  string `protobuf:"bytes,2,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
+	Filename  string `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	RowGuid   string `protobuf:"bytes,4,opt,name=row_guid,json=rowGuid,proto3" json:"row_guid,omitempty"`
+	// If set, only the header and authorship metadata are returned, which
+	// also works for revisions whose contents were not retained.
+	HeaderOnly           bool     `protobuf:"varint,5,opt,name=header_only,json=headerOnly,proto3" json:"header_only,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReadFileRevisionRequest) Reset()         { *m = ReadFileRevisionRequest{} }
+func (m *ReadFileRevisionRequest) String() string { return proto.CompactTextString(m) }
+func (*ReadFileRevisionRequest) ProtoMessage()    {}
+func (*ReadFileRevisionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{33}
+}
+
+func (m *ReadFileRevisionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReadFileRevisionRequest.Unmarshal(m, b)
+}
+func (m *ReadFileRevisionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReadFileRevisionRequest.Marshal(b, m, deterministic)
+}
+func (m *ReadFileRevisionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReadFileRevisionRequest.Merge(m, src)
+}
+func (m *ReadFileRevisionRequest) XXX_Size() int {
+	return xxx_messageInfo_ReadFileRevisionRequest.Size(m)
+}
+func (m *ReadFileRevisionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReadFileRevisionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReadFileRevisionRequest proto.InternalMessageInfo
+
+func (m *ReadFileRevisionRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *ReadFileRevisionRequest) GetEntityId() string {
+	if m != nil {
+		return m.EntityId
+	}
+	return ""
+}
+
+func (m *ReadFileRevisionRequest) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *ReadFileRevisionRequest) GetRowGuid() string {
+	if m != nil {
+		return m.RowGuid
+	}
+	return ""
+}
+
+func (m *ReadFileRevisionRequest) GetHeaderOnly() bool {
+	if m != nil {
+		return m.HeaderOnly
+	}
+	return false
+}
+
+type ReadFileRevisionResponse struct {
+	File                 *EntityFile         `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	AuthorshipMetadata   *AuthorshipMetadata `protobuf:"bytes,2,opt,name=authorship_metadata,json=authorshipMetadata,proto3" json:"authorship_metadata,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *ReadFileRevisionResponse) Reset()         { *m = ReadFileRevisionResponse{} }
+func (m *ReadFileRevisionResponse) String() string { return proto.CompactTextString(m) }
+func (*ReadFileRevisionResponse) ProtoMessage()    {}
+func (*ReadFileRevisionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{34}
+}
+
+func (m *ReadFileRevisionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReadFileRevisionResponse.Unmarshal(m, b)
+}
+func (m *ReadFileRevisionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReadFileRevisionResponse.Marshal(b, m, deterministic)
+}
+func (m *ReadFileRevisionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReadFileRevisionResponse.Merge(m, src)
+}
+func (m *ReadFileRevisionResponse) XXX_Size() int {
+	return xxx_messageInfo_ReadFileRevisionResponse.Size(m)
+}
+func (m *ReadFileRevisionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReadFileRevisionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReadFileRevisionResponse proto.InternalMessageInfo
+
+func (m *ReadFileRevisionResponse) GetFile() *EntityFile {
+	if m != nil {
+		return m.File
+	}
+	return nil
+}
+
+func (m *ReadFileRevisionResponse) GetAuthorshipMetadata() *AuthorshipMetadata {
+	if m != nil {
+		return m.AuthorshipMetadata
+	}
+	return nil
+}
+
+type BatchOperation struct {
+	// Types that are valid to be assigned to Kind:
+	//	*BatchOperation_Write
+	//	*BatchOperation_Delete
+	Kind                 isBatchOperation_Kind `protobuf_oneof:"kind"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *BatchOperation) Reset()         { *m = BatchOperation{} }
+func (m *BatchOperation) String() string { return proto.CompactTextString(m) }
+func (*BatchOperation) ProtoMessage()    {}
+func (*BatchOperation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{35}
+}
+
+func (m *BatchOperation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchOperation.Unmarshal(m, b)
+}
+func (m *BatchOperation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchOperation.Marshal(b, m, deterministic)
+}
+func (m *BatchOperation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchOperation.Merge(m, src)
+}
+func (m *BatchOperation) XXX_Size() int {
+	return xxx_messageInfo_BatchOperation.Size(m)
+}
+func (m *BatchOperation) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchOperation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchOperation proto.InternalMessageInfo
+
+type isBatchOperation_Kind interface {
+	isBatchOperation_Kind()
+}
+
+type BatchOperation_Write struct {
+	Write *WriteFileRequest `protobuf:"bytes,1,opt,name=write,proto3,oneof"`
+}
+
+type BatchOperation_Delete struct {
+	Delete *DeleteFileRequest `protobuf:"bytes,2,opt,name=delete,proto3,oneof"`
+}
+
+func (*BatchOperation_Write) isBatchOperation_Kind() {}
+
+func (*BatchOperation_Delete) isBatchOperation_Kind() {}
+
+func (m *BatchOperation) GetKind() isBatchOperation_Kind {
+	if m != nil {
+		return m.Kind
+	}
+	return nil
+}
+
+func (m *BatchOperation) GetWrite() *WriteFileRequest {
+	if x, ok := m.GetKind().(*BatchOperation_Write); ok {
+		return x.Write
+	}
+	return nil
+}
+
+func (m *BatchOperation) GetDelete() *DeleteFileRequest {
+	if x, ok := m.GetKind().(*BatchOperation_Delete); ok {
+		return x.Delete
+	}
+	return nil
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*BatchOperation) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _BatchOperation_OneofMarshaler, _BatchOperation_OneofUnmarshaler, _BatchOperation_OneofSizer, []interface{}{
+		(*BatchOperation_Write)(nil),
+		(*BatchOperation_Delete)(nil),
+	}
+}
+
+func _BatchOperation_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*BatchOperation)
+	// kind
+	switch x := m.Kind.(type) {
+	case *BatchOperation_Write:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Write); err != nil {
+			return err
+		}
+	case *BatchOperation_Delete:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Delete); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		return fmt.Errorf("BatchOperation.Kind has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _BatchOperation_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*BatchOperation)
+	switch tag {
+	case 1: // kind.write
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(WriteFileRequest)
+		err := b.DecodeMessage(msg)
+		m.Kind = &BatchOperation_Write{msg}
+		return true, err
+	case 2: // kind.delete
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(DeleteFileRequest)
+		err := b.DecodeMessage(msg)
+		m.Kind = &BatchOperation_Delete{msg}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _BatchOperation_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*BatchOperation)
+	// kind
+	switch x := m.Kind.(type) {
+	case *BatchOperation_Write:
+		s := proto.Size(x.Write)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *BatchOperation_Delete:
+		s := proto.Size(x.Delete)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+type BatchRequest struct {
+	// Operations are applied in order, all in one transaction. If any of
+	// them fails, none of them take effect.
+	Operation            []*BatchOperation `protobuf:"bytes,1,rep,name=operation,proto3" json:"operation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *BatchRequest) Reset()         { *m = BatchRequest{} }
+func (m *BatchRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchRequest) ProtoMessage()    {}
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{36}
+}
+
+func (m *BatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchRequest.Unmarshal(m, b)
+}
+func (m *BatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchRequest.Marshal(b, m, deterministic)
+}
+func (m *BatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchRequest.Merge(m, src)
+}
+func (m *BatchRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchRequest.Size(m)
+}
+func (m *BatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchRequest proto.InternalMessageInfo
+
+func (m *BatchRequest) GetOperation() []*BatchOperation {
+	if m != nil {
+		return m.Operation
+	}
+	return nil
+}
+
+type BatchResponse struct {
+	// Resulting file headers, one for each operation, in order.
+	Header               []*EntityFileHeader `protobuf:"bytes,1,rep,name=header,proto3" json:"header,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *BatchResponse) Reset()         { *m = BatchResponse{} }
+func (m *BatchResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchResponse) ProtoMessage()    {}
+func (*BatchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{37}
+}
+
+func (m *BatchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchResponse.Unmarshal(m, b)
+}
+func (m *BatchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchResponse.Marshal(b, m, deterministic)
+}
+func (m *BatchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchResponse.Merge(m, src)
+}
+func (m *BatchResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchResponse.Size(m)
+}
+func (m *BatchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchResponse proto.InternalMessageInfo
+
+func (m *BatchResponse) GetHeader() []*EntityFileHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+// Detail of the error returned for a failed Batch, identifying the
+// operation that failed.
+type BatchOperationFailure struct {
+	Index                int32    `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchOperationFailure) Reset()         { *m = BatchOperationFailure{} }
+func (m *BatchOperationFailure) String() string { return proto.CompactTextString(m) }
+func (*BatchOperationFailure) ProtoMessage()    {}
+func (*BatchOperationFailure) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{38}
+}
+
+func (m *BatchOperationFailure) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchOperationFailure.Unmarshal(m, b)
+}
+func (m *BatchOperationFailure) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchOperationFailure.Marshal(b, m, deterministic)
+}
+func (m *BatchOperationFailure) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchOperationFailure.Merge(m, src)
+}
+func (m *BatchOperationFailure) XXX_Size() int {
+	return xxx_messageInfo_BatchOperationFailure.Size(m)
+}
+func (m *BatchOperationFailure) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchOperationFailure.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchOperationFailure proto.InternalMessageInfo
+
+func (m *BatchOperationFailure) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+type WatchChangesRequest struct {
+	// Only report changes in this namespace, unless all_namespaces is set.
+	Namespace     string `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	AllNamespaces bool   `protobuf:"varint,2,opt,name=all_namespaces,json=allNamespaces,proto3" json:"all_namespaces,omitempty"`
+	// Resume after the change with this sequence number, as reported in a
+	// previous ChangeEvent. Zero means replaying all retained history.
+	AfterSequence int64 `protobuf:"varint,3,opt,name=after_sequence,json=afterSequence,proto3" json:"after_sequence,omitempty"`
+	// Skip existing history and only report changes from now on.
+	FromNow bool `protobuf:"varint,4,opt,name=from_now,json=fromNow,proto3" json:"from_now,omitempty"`
+	// Only report changes to entities that match this query just before or
+	// just after the change, so that changes making an entity stop matching
+	// (including deletions) are reported too. Clauses that depend on the
+	// contents of old revisions are rejected unless those are kept.
+	Query                *EntitiesQuery `protobuf:"bytes,5,opt,name=query,proto3" json:"query,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *WatchChangesRequest) Reset()         { *m = WatchChangesRequest{} }
+func (m *WatchChangesRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchChangesRequest) ProtoMessage()    {}
+func (*WatchChangesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{39}
+}
+
+func (m *WatchChangesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchChangesRequest.Unmarshal(m, b)
+}
+func (m *WatchChangesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchChangesRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchChangesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchChangesRequest.Merge(m, src)
+}
+func (m *WatchChangesRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchChangesRequest.Size(m)
+}
+func (m *WatchChangesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchChangesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchChangesRequest proto.InternalMessageInfo
+
+func (m *WatchChangesRequest) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *WatchChangesRequest) GetAllNamespaces() bool {
+	if m != nil {
+		return m.AllNamespaces
+	}
+	return false
+}
+
+func (m *WatchChangesRequest) GetAfterSequence() int64 {
+	if m != nil {
+		return m.AfterSequence
+	}
+	return 0
+}
+
+func (m *WatchChangesRequest) GetFromNow() bool {
+	if m != nil {
+		return m.FromNow
+	}
+	return false
+}
+
+func (m *WatchChangesRequest) GetQuery() *EntitiesQuery {
+	if m != nil {
+		return m.Query
+	}
+	return nil
+}
+
+type ChangeEvent struct {
+	// Header of the new revision; deletions are reported as tombstones.
+	Header               *EntityFileHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Sequence             int64             `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *ChangeEvent) Reset()         { *m = ChangeEvent{} }
+func (m *ChangeEvent) String() string { return proto.CompactTextString(m) }
+func (*ChangeEvent) ProtoMessage()    {}
+func (*ChangeEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{40}
+}
+
+func (m *ChangeEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChangeEvent.Unmarshal(m, b)
+}
+func (m *ChangeEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChangeEvent.Marshal(b, m, deterministic)
+}
+func (m *ChangeEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChangeEvent.Merge(m, src)
+}
+func (m *ChangeEvent) XXX_Size() int {
+	return xxx_messageInfo_ChangeEvent.Size(m)
+}
+func (m *ChangeEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChangeEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChangeEvent proto.InternalMessageInfo
+
+func (m *ChangeEvent) GetHeader() *EntityFileHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *ChangeEvent) GetSequence() int64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+type CompactRequest struct {
+	// Revisions superseded longer ago than this are deleted, as are deletion
+	// records (tombstones) older than this.
+	RetentionSeconds int64 `protobuf:"varint,1,opt,name=retention_seconds,json=retentionSeconds,proto3" json:"retention_seconds,omitempty"`
+	// Skip reclaiming disk space with VACUUM after deleting rows.
+	SkipVacuum           bool     `protobuf:"varint,2,opt,name=skip_vacuum,json=skipVacuum,proto3" json:"skip_vacuum,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CompactRequest) Reset()         { *m = CompactRequest{} }
+func (m *CompactRequest) String() string { return proto.CompactTextString(m) }
+func (*CompactRequest) ProtoMessage()    {}
+func (*CompactRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{41}
+}
+
+func (m *CompactRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompactRequest.Unmarshal(m, b)
+}
+func (m *CompactRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompactRequest.Marshal(b, m, deterministic)
+}
+func (m *CompactRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompactRequest.Merge(m, src)
+}
+func (m *CompactRequest) XXX_Size() int {
+	return xxx_messageInfo_CompactRequest.Size(m)
+}
+func (m *CompactRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompactRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CompactRequest proto.InternalMessageInfo
+
+func (m *CompactRequest) GetRetentionSeconds() int64 {
+	if m != nil {
+		return m.RetentionSeconds
+	}
+	return 0
+}
+
+func (m *CompactRequest) GetSkipVacuum() bool {
+	if m != nil {
+		return m.SkipVacuum
+	}
+	return false
+}
+
+type CompactResponse struct {
+	DeletedRevisions     int64         `protobuf:"varint,1,opt,name=deleted_revisions,json=deletedRevisions,proto3" json:"deleted_revisions,omitempty"`
+	DeletedTombstones    int64         `protobuf:"varint,2,opt,name=deleted_tombstones,json=deletedTombstones,proto3" json:"deleted_tombstones,omitempty"`
+	BytesReclaimed       int64         `protobuf:"varint,3,opt,name=bytes_reclaimed,json=bytesReclaimed,proto3" json:"bytes_reclaimed,omitempty"`
+	Size                 *SizeMetadata `protobuf:"bytes,4,opt,name=size,proto3" json:"size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *CompactResponse) Reset()         { *m = CompactResponse{} }
+func (m *CompactResponse) String() string { return proto.CompactTextString(m) }
+func (*CompactResponse) ProtoMessage()    {}
+func (*CompactResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{42}
+}
+
+func (m *CompactResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompactResponse.Unmarshal(m, b)
+}
+func (m *CompactResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompactResponse.Marshal(b, m, deterministic)
+}
+func (m *CompactResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompactResponse.Merge(m, src)
+}
+func (m *CompactResponse) XXX_Size() int {
+	return xxx_messageInfo_CompactResponse.Size(m)
+}
+func (m *CompactResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompactResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CompactResponse proto.InternalMessageInfo
+
+func (m *CompactResponse) GetDeletedRevisions() int64 {
+	if m != nil {
+		return m.DeletedRevisions
+	}
+	return 0
+}
+
+func (m *CompactResponse) GetDeletedTombstones() int64 {
+	if m != nil {
+		return m.DeletedTombstones
+	}
+	return 0
+}
+
+func (m *CompactResponse) GetBytesReclaimed() int64 {
+	if m != nil {
+		return m.BytesReclaimed
+	}
+	return 0
+}
+
+func (m *CompactResponse) GetSize() *SizeMetadata {
+	if m != nil {
+		return m.Size
+	}
+	return nil
+}
+
+type PullChangesRequest struct {
+	// Return revisions written after the one with this sequence number, as
+	// reported in a previous PullChangesResponse. Zero means from the start.
+	AfterSequence int64 `protobuf:"varint,1,opt,name=after_sequence,json=afterSequence,proto3" json:"after_sequence,omitempty"`
+	// Maximum number of revisions to return; zero means a server default.
+	Limit                int32    `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PullChangesRequest) Reset()         { *m = PullChangesRequest{} }
+func (m *PullChangesRequest) String() string { return proto.CompactTextString(m) }
+func (*PullChangesRequest) ProtoMessage()    {}
+func (*PullChangesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{43}
+}
+
+func (m *PullChangesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PullChangesRequest.Unmarshal(m, b)
+}
+func (m *PullChangesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PullChangesRequest.Marshal(b, m, deterministic)
+}
+func (m *PullChangesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PullChangesRequest.Merge(m, src)
+}
+func (m *PullChangesRequest) XXX_Size() int {
+	return xxx_messageInfo_PullChangesRequest.Size(m)
+}
+func (m *PullChangesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PullChangesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PullChangesRequest proto.InternalMessageInfo
+
+func (m *PullChangesRequest) GetAfterSequence() int64 {
+	if m != nil {
+		return m.AfterSequence
+	}
+	return 0
+}
+
+func (m *PullChangesRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type PullChangesResponse struct {
+	// Revisions of all namespaces, including history and deletions, in the
+	// order they were written.
+	Revision []*ArchivedRevision `protobuf:"bytes,1,rep,name=revision,proto3" json:"revision,omitempty"`
+	// Sequence number of the last revision returned, to resume from. If no
+	// revisions were returned, this is the after_sequence of the request.
+	LastSequence         int64    `protobuf:"varint,2,opt,name=last_sequence,json=lastSequence,proto3" json:"last_sequence,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PullChangesResponse) Reset()         { *m = PullChangesResponse{} }
+func (m *PullChangesResponse) String() string { return proto.CompactTextString(m) }
+func (*PullChangesResponse) ProtoMessage()    {}
+func (*PullChangesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{44}
+}
+
+func (m *PullChangesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PullChangesResponse.Unmarshal(m, b)
+}
+func (m *PullChangesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PullChangesResponse.Marshal(b, m, deterministic)
+}
+func (m *PullChangesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PullChangesResponse.Merge(m, src)
+}
+func (m *PullChangesResponse) XXX_Size() int {
+	return xxx_messageInfo_PullChangesResponse.Size(m)
+}
+func (m *PullChangesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PullChangesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PullChangesResponse proto.InternalMessageInfo
+
+func (m *PullChangesResponse) GetRevision() []*ArchivedRevision {
+	if m != nil {
+		return m.Revision
+	}
+	return nil
+}
+
+func (m *PullChangesResponse) GetLastSequence() int64 {
+	if m != nil {
+		return m.LastSequence
+	}
+	return 0
+}
+
+type PushChangesRequest struct {
+	// Revisions to merge, in the order they were written. Revisions already
+	// present are skipped.
+	Revision             []*ArchivedRevision `protobuf:"bytes,1,rep,name=revision,proto3" json:"revision,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *PushChangesRequest) Reset()         { *m = PushChangesRequest{} }
+func (m *PushChangesRequest) String() string { return proto.CompactTextString(m) }
+func (*PushChangesRequest) ProtoMessage()    {}
+func (*PushChangesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{45}
+}
+
+func (m *PushChangesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PushChangesRequest.Unmarshal(m, b)
+}
+func (m *PushChangesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PushChangesRequest.Marshal(b, m, deterministic)
 }
-func (m *GetDatabaseMetadataRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetDatabaseMetadataRequest.Merge(m, src)
+func (m *PushChangesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PushChangesRequest.Merge(m, src)
 }
-func (m *GetDatabaseMetadataRequest) XXX_Size() int {
-	return xxx_messageInfo_GetDatabaseMetadataRequest.Size(m)
+func (m *PushChangesRequest) XXX_Size() int {
+	return xxx_messageInfo_PushChangesRequest.Size(m)
 }
-func (m *GetDatabaseMetadataRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_GetDatabaseMetadataRequest.DiscardUnknown(m)
+func (m *PushChangesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PushChangesRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GetDatabaseMetadataRequest proto.InternalMessageInfo
+var xxx_messageInfo_PushChangesRequest proto.InternalMessageInfo
 
-func (m *GetDatabaseMetadataRequest) GetOnlyTimestamps() bool {
+func (m *PushChangesRequest) GetRevision() []*ArchivedRevision {
 	if m != nil {
-		return m.OnlyTimestamps
+		return m.Revision
 	}
-	return false
+	return nil
 }
 
-type GetDatabaseMetadataResponse struct {
-	Metadata             *DatabaseMetadata `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
-	XXX_unrecognized     []byte            `json:"-"`
-	XXX_sizecache        int32             `json:"-"`
+type PushChangesResponse struct {
+	Applied              int64    `protobuf:"varint,1,opt,name=applied,proto3" json:"applied,omitempty"`
+	Skipped              int64    `protobuf:"varint,2,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *GetDatabaseMetadataResponse) Reset()         { *m = GetDatabaseMetadataResponse{} }
-func (m *GetDatabaseMetadataResponse) String() string { return proto.CompactTextString(m) }
-func (*GetDatabaseMetadataResponse) ProtoMessage()    {}
-func (*GetDatabaseMetadataResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_213b282dda0e8199, []int{23}
+func (m *PushChangesResponse) Reset()         { *m = PushChangesResponse{} }
+func (m *PushChangesResponse) String() string { return proto.CompactTextString(m) }
+func (*PushChangesResponse) ProtoMessage()    {}
+func (*PushChangesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_213b282dda0e8199, []int{46}
 }
 
-func (m *GetDatabaseMetadataResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GetDatabaseMetadataResponse.Unmarshal(m, b)
+func (m *PushChangesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PushChangesResponse.Unmarshal(m, b)
 }
-func (m *GetDatabaseMetadataResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GetDatabaseMetadataResponse.Marshal(b, m, deterministic)
+func (m *PushChangesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PushChangesResponse.Marshal(b, m, deterministic)
 }
-func (m *GetDatabaseMetadataResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetDatabaseMetadataResponse.Merge(m, src)
+func (m *PushChangesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PushChangesResponse.Merge(m, src)
 }
-func (m *GetDatabaseMetadataResponse) XXX_Size() int {
-	return xxx_messageInfo_GetDatabaseMetadataResponse.Size(m)
+func (m *PushChangesResponse) XXX_Size() int {
+	return xxx_messageInfo_PushChangesResponse.Size(m)
 }
-func (m *GetDatabaseMetadataResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_GetDatabaseMetadataResponse.DiscardUnknown(m)
+func (m *PushChangesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PushChangesResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GetDatabaseMetadataResponse proto.InternalMessageInfo
+var xxx_messageInfo_PushChangesResponse proto.InternalMessageInfo
 
-func (m *GetDatabaseMetadataResponse) GetMetadata() *DatabaseMetadata {
+func (m *PushChangesResponse) GetApplied() int64 {
 	if m != nil {
-		return m.Metadata
+		return m.Applied
 	}
-	return nil
+	return 0
+}
+
+func (m *PushChangesResponse) GetSkipped() int64 {
+	if m != nil {
+		return m.Skipped
+	}
+	return 0
 }
 
 func init() {
 	proto.RegisterEnum("qmfspb.DeletionType", DeletionType_name, DeletionType_value)
+	proto.RegisterEnum("qmfspb.EntitiesQuery_Clause_FileComparison_Operator", EntitiesQuery_Clause_FileComparison_Operator_name, EntitiesQuery_Clause_FileComparison_Operator_value)
+	proto.RegisterEnum("qmfspb.EntitiesQuery_Clause_FileComparison_Semantics", EntitiesQuery_Clause_FileComparison_Semantics_name, EntitiesQuery_Clause_FileComparison_Semantics_value)
+	proto.RegisterEnum("qmfspb.EntitiesQuery_Clause_FileMatchesPattern_Mode", EntitiesQuery_Clause_FileMatchesPattern_Mode_name, EntitiesQuery_Clause_FileMatchesPattern_Mode_value)
+	proto.RegisterEnum("qmfspb.AggregateEntitiesRequest_Function", AggregateEntitiesRequest_Function_name, AggregateEntitiesRequest_Function_value)
 	proto.RegisterType((*Timestamp)(nil), "qmfspb.Timestamp")
 	proto.RegisterType((*Checksums)(nil), "qmfspb.Checksums")
 	proto.RegisterType((*EntityFileHeader)(nil), "qmfspb.EntityFileHeader")
@@ -1809,6 +3768,7 @@ func init() {
 	proto.RegisterType((*ReadFileResponse)(nil), "qmfspb.ReadFileResponse")
 	proto.RegisterType((*WriteFileRequest)(nil), "qmfspb.WriteFileRequest")
 	proto.RegisterType((*WriteFileResponse)(nil), "qmfspb.WriteFileResponse")
+	proto.RegisterType((*RevisionConflict)(nil), "qmfspb.RevisionConflict")
 	proto.RegisterType((*DeleteFileRequest)(nil), "qmfspb.DeleteFileRequest")
 	proto.RegisterType((*DeleteFileResponse)(nil), "qmfspb.DeleteFileResponse")
 	proto.RegisterType((*EntitiesQuery)(nil), "qmfspb.EntitiesQuery")
@@ -1816,8 +3776,17 @@ func init() {
 	proto.RegisterType((*EntitiesQuery_Clause_FileHasTrimmedContents)(nil), "qmfspb.EntitiesQuery.Clause.FileHasTrimmedContents")
 	proto.RegisterType((*EntitiesQuery_Clause_EntityInShard)(nil), "qmfspb.EntitiesQuery.Clause.EntityInShard")
 	proto.RegisterType((*EntitiesQuery_Clause_RandomSelection)(nil), "qmfspb.EntitiesQuery.Clause.RandomSelection")
+	proto.RegisterType((*EntitiesQuery_Clause_AnyOf)(nil), "qmfspb.EntitiesQuery.Clause.AnyOf")
+	proto.RegisterType((*EntitiesQuery_Clause_FileComparison)(nil), "qmfspb.EntitiesQuery.Clause.FileComparison")
+	proto.RegisterType((*EntitiesQuery_Clause_FileMatchesPattern)(nil), "qmfspb.EntitiesQuery.Clause.FileMatchesPattern")
+	proto.RegisterType((*EntitiesQuery_Clause_Ordering)(nil), "qmfspb.EntitiesQuery.Clause.Ordering")
 	proto.RegisterType((*AuthorshipMetadata)(nil), "qmfspb.AuthorshipMetadata")
 	proto.RegisterType((*QueryEntitiesRequest)(nil), "qmfspb.QueryEntitiesRequest")
+	proto.RegisterType((*AggregateEntitiesRequest)(nil), "qmfspb.AggregateEntitiesRequest")
+	proto.RegisterType((*AggregateEntitiesResponse)(nil), "qmfspb.AggregateEntitiesResponse")
+	proto.RegisterType((*CountValuesRequest)(nil), "qmfspb.CountValuesRequest")
+	proto.RegisterType((*CountValuesResponse)(nil), "qmfspb.CountValuesResponse")
+	proto.RegisterType((*CountValuesResponse_Value)(nil), "qmfspb.CountValuesResponse.Value")
 	proto.RegisterType((*QueryEntitiesResponse)(nil), "qmfspb.QueryEntitiesResponse")
 	proto.RegisterType((*ListNamespacesRequest)(nil), "qmfspb.ListNamespacesRequest")
 	proto.RegisterType((*ListNamespacesResponse)(nil), "qmfspb.ListNamespacesResponse")
@@ -1826,104 +3795,212 @@ func init() {
 	proto.RegisterType((*DatabaseMetadata)(nil), "qmfspb.DatabaseMetadata")
 	proto.RegisterType((*GetDatabaseMetadataRequest)(nil), "qmfspb.GetDatabaseMetadataRequest")
 	proto.RegisterType((*GetDatabaseMetadataResponse)(nil), "qmfspb.GetDatabaseMetadataResponse")
+	proto.RegisterType((*FileRevision)(nil), "qmfspb.FileRevision")
+	proto.RegisterType((*ArchivedRevision)(nil), "qmfspb.ArchivedRevision")
+	proto.RegisterType((*ListFileRevisionsRequest)(nil), "qmfspb.ListFileRevisionsRequest")
+	proto.RegisterType((*ListFileRevisionsResponse)(nil), "qmfspb.ListFileRevisionsResponse")
+	proto.RegisterType((*ReadFileRevisionRequest)(nil), "qmfspb.ReadFileRevisionRequest")
+	proto.RegisterType((*ReadFileRevisionResponse)(nil), "qmfspb.ReadFileRevisionResponse")
+	proto.RegisterType((*BatchOperation)(nil), "qmfspb.BatchOperation")
+	proto.RegisterType((*BatchRequest)(nil), "qmfspb.BatchRequest")
+	proto.RegisterType((*BatchResponse)(nil), "qmfspb.BatchResponse")
+	proto.RegisterType((*BatchOperationFailure)(nil), "qmfspb.BatchOperationFailure")
+	proto.RegisterType((*WatchChangesRequest)(nil), "qmfspb.WatchChangesRequest")
+	proto.RegisterType((*ChangeEvent)(nil), "qmfspb.ChangeEvent")
+	proto.RegisterType((*CompactRequest)(nil), "qmfspb.CompactRequest")
+	proto.RegisterType((*CompactResponse)(nil), "qmfspb.CompactResponse")
+	proto.RegisterType((*PullChangesRequest)(nil), "qmfspb.PullChangesRequest")
+	proto.RegisterType((*PullChangesResponse)(nil), "qmfspb.PullChangesResponse")
+	proto.RegisterType((*PushChangesRequest)(nil), "qmfspb.PushChangesRequest")
+	proto.RegisterType((*PushChangesResponse)(nil), "qmfspb.PushChangesResponse")
 }
 
 func init() { proto.RegisterFile("qmfs.proto", fileDescriptor_213b282dda0e8199) }
 
 var fileDescriptor_213b282dda0e8199 = []byte{
-	// 1463 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x57, 0x4f, 0x6f, 0x13, 0x47,
-	0x14, 0xf7, 0xda, 0x8e, 0xb1, 0x9f, 0x9d, 0xc4, 0x99, 0x24, 0xe0, 0x2c, 0xa4, 0xa4, 0x8b, 0x68,
-	0x53, 0x54, 0x05, 0x64, 0x02, 0x6a, 0xe9, 0xa1, 0x22, 0x89, 0x83, 0x53, 0x42, 0x80, 0x49, 0x44,
-	0x45, 0x2f, 0xdb, 0x89, 0x77, 0x88, 0xb7, 0x59, 0xef, 0x9a, 0x9d, 0x71, 0x82, 0xb9, 0xf7, 0x50,
-	0xa9, 0xc7, 0x9e, 0x7b, 0xad, 0xda, 0x0f, 0xd1, 0xcf, 0xd0, 0x4f, 0xd3, 0x73, 0x35, 0x7f, 0xf6,
-	0x6f, 0x8c, 0x45, 0x91, 0xaa, 0xde, 0x3c, 0xef, 0xfd, 0xde, 0x9b, 0xdf, 0xfc, 0xde, 0x9b, 0xd9,
-	0x67, 0x80, 0xd7, 0x83, 0x57, 0x6c, 0x63, 0x18, 0x06, 0x3c, 0x40, 0x15, 0xf1, 0x7b, 0x78, 0x6c,
-	0xad, 0x43, 0xed, 0xc8, 0x1d, 0x50, 0xc6, 0xc9, 0x60, 0x88, 0xae, 0x42, 0x6d, 0xe4, 0xbb, 0x6f,
-	0x6c, 0x9f, 0xf8, 0x41, 0xcb, 0x58, 0x33, 0xd6, 0x4b, 0xb8, 0x2a, 0x0c, 0x07, 0xc4, 0x0f, 0xac,
-	0x9f, 0x0c, 0xa8, 0x6d, 0xf7, 0x69, 0xef, 0x94, 0x8d, 0x06, 0x0c, 0x5d, 0x86, 0x8a, 0x47, 0xfd,
-	0x13, 0xde, 0xd7, 0x38, 0xbd, 0x12, 0x76, 0xd6, 0x27, 0xed, 0x7b, 0xf7, 0x5b, 0xc5, 0x35, 0x63,
-	0xbd, 0x81, 0xf5, 0x0a, 0xdd, 0x84, 0x39, 0x1e, 0xba, 0x83, 0x01, 0x75, 0x6c, 0x1d, 0x57, 0x92,
-	0x71, 0xb3, 0xda, 0xba, 0xaf, 0xc2, 0x53, 0x30, 0x9d, 0xa6, 0x2c, 0xd3, 0x44, 0xb0, 0x43, 0x69,
-	0xb4, 0x7e, 0x2b, 0x42, 0xb3, 0xe3, 0x73, 0x97, 0x8f, 0x77, 0x5d, 0x8f, 0x76, 0x29, 0x71, 0x68,
-	0x28, 0xd8, 0x53, 0x69, 0xb3, 0x5d, 0x47, 0xb2, 0xaa, 0xe1, 0xaa, 0x32, 0xec, 0x39, 0xc8, 0x84,
-	0xea, 0x2b, 0xd7, 0xa3, 0x3e, 0x19, 0x50, 0xc9, 0xac, 0x86, 0xe3, 0x35, 0xba, 0x0d, 0xb5, 0x5e,
-	0x74, 0x30, 0x49, 0xab, 0xde, 0x5e, 0xd8, 0x50, 0xfa, 0x6c, 0xc4, 0x27, 0xc6, 0x09, 0x06, 0x6d,
-	0x42, 0xc3, 0x23, 0x8c, 0xdb, 0xbd, 0x3e, 0xf1, 0x4f, 0xa8, 0x23, 0x39, 0xa6, 0x62, 0x62, 0x41,
-	0x71, 0x5d, 0xc0, 0xb6, 0x15, 0x0a, 0xad, 0x40, 0x35, 0x0c, 0xce, 0xed, 0x93, 0x91, 0xeb, 0xb4,
-	0x66, 0x24, 0x85, 0x4b, 0x61, 0x70, 0xfe, 0x68, 0xe4, 0x3a, 0xe8, 0x1a, 0xd4, 0x78, 0x30, 0x38,
-	0x66, 0x3c, 0xf0, 0x69, 0xab, 0xb2, 0x66, 0xac, 0x57, 0x71, 0x62, 0x10, 0x5e, 0xc1, 0x93, 0x0d,
-	0x49, 0x8f, 0xb6, 0x2e, 0xc9, 0xc8, 0xc4, 0x20, 0xbc, 0x8e, 0x1b, 0xd2, 0x1e, 0x0f, 0xc2, 0x71,
-	0xab, 0xaa, 0x62, 0x63, 0x83, 0xf5, 0x87, 0x01, 0x15, 0xa5, 0xd4, 0x74, 0x7d, 0x6e, 0xc3, 0x8c,
-	0xd0, 0x83, 0xb5, 0x8a, 0x6b, 0xa5, 0xf5, 0x7a, 0x7b, 0x25, 0x3a, 0x8b, 0x8a, 0xdd, 0x10, 0x32,
-	0xb3, 0x8e, 0xcf, 0xc3, 0x31, 0x56, 0x38, 0x13, 0x03, 0x24, 0x46, 0xd4, 0x84, 0xd2, 0x29, 0x1d,
-	0xeb, 0xac, 0xe2, 0x27, 0xda, 0x80, 0x99, 0x33, 0xe2, 0x8d, 0x94, 0xda, 0xf5, 0x76, 0x2b, 0x9b,
-	0x30, 0x29, 0x1b, 0x56, 0xb0, 0x07, 0xc5, 0x2f, 0x0c, 0x0b, 0x03, 0x24, 0x6e, 0x74, 0x07, 0x2a,
-	0x7d, 0x09, 0x91, 0x69, 0xa7, 0xa5, 0xd0, 0x38, 0x84, 0xa0, 0xec, 0x10, 0x4e, 0x74, 0xeb, 0xc9,
-	0xdf, 0xd6, 0x13, 0x68, 0x3e, 0xa2, 0x5c, 0x85, 0x60, 0xfa, 0x7a, 0x44, 0x19, 0x9f, 0xae, 0x44,
-	0x46, 0xed, 0x62, 0x4e, 0x6d, 0xeb, 0x2b, 0x58, 0x48, 0xa5, 0x63, 0xc3, 0xc0, 0x67, 0x14, 0x7d,
-	0x02, 0x15, 0x15, 0xae, 0x99, 0xce, 0x65, 0x99, 0x62, 0xed, 0xb5, 0xfa, 0x30, 0x8f, 0x29, 0x71,
-	0x04, 0xf3, 0xf7, 0xa2, 0x32, 0xad, 0x69, 0x33, 0x34, 0x4b, 0x79, 0x9a, 0x0f, 0xa0, 0x99, 0xec,
-	0x14, 0xb3, 0x2c, 0x8b, 0x68, 0xcd, 0x11, 0x5d, 0x54, 0x13, 0x4b, 0xbf, 0xf5, 0x4b, 0x11, 0x9a,
-	0xdf, 0x86, 0x2e, 0xa7, 0x69, 0x9e, 0x99, 0xed, 0x2a, 0xf9, 0x1e, 0xfc, 0xe0, 0x53, 0x44, 0x15,
-	0x2b, 0x25, 0x15, 0x43, 0xb7, 0x60, 0x21, 0xf0, 0x1c, 0x3b, 0xa4, 0x67, 0x2e, 0x73, 0x03, 0x5f,
-	0x5d, 0x98, 0xb2, 0x0c, 0x9c, 0x0f, 0x3c, 0x07, 0x6b, 0xbb, 0xbc, 0x38, 0x8f, 0x61, 0x91, 0x8c,
-	0x78, 0x3f, 0x08, 0x59, 0xdf, 0x1d, 0xda, 0x03, 0xca, 0x89, 0x4c, 0x37, 0x23, 0x8f, 0x68, 0x46,
-	0x47, 0x7c, 0x18, 0x43, 0x9e, 0x68, 0x04, 0x46, 0xe4, 0x82, 0x2d, 0x7b, 0x93, 0x2e, 0xe5, 0x6f,
-	0x52, 0x07, 0x16, 0x52, 0xaa, 0x68, 0x4d, 0xff, 0x75, 0x8f, 0x5a, 0xbf, 0x16, 0x61, 0x61, 0x87,
-	0x7a, 0x34, 0x2b, 0xef, 0x7f, 0xd3, 0x06, 0xff, 0x9f, 0x94, 0x5f, 0xc2, 0xac, 0x23, 0x0e, 0x29,
-	0x36, 0xe5, 0xe3, 0xa1, 0x6a, 0x99, 0xb9, 0xf6, 0x52, 0x94, 0x66, 0x47, 0x3b, 0x8f, 0xc6, 0x43,
-	0x8a, 0x1b, 0x4e, 0x6a, 0x65, 0xed, 0x02, 0x4a, 0xeb, 0xf3, 0xc1, 0x42, 0xff, 0x59, 0x86, 0x59,
-	0xe9, 0x74, 0x29, 0x7b, 0x3e, 0xa2, 0xe1, 0x18, 0x6d, 0x42, 0xa5, 0xe7, 0x91, 0x11, 0x13, 0x57,
-	0x40, 0x3c, 0x72, 0xd7, 0x32, 0x39, 0x22, 0xd8, 0xc6, 0xb6, 0xc4, 0x60, 0x8d, 0x35, 0xff, 0x2e,
-	0x41, 0x45, 0x99, 0xd0, 0xc7, 0x50, 0x17, 0xc2, 0xdb, 0xf4, 0x8d, 0xcb, 0x38, 0x53, 0x75, 0xea,
-	0x16, 0x30, 0x08, 0x63, 0x47, 0xda, 0xd0, 0x77, 0x30, 0x2b, 0x21, 0xbd, 0xc0, 0xe7, 0xd4, 0xe7,
-	0x4c, 0x3f, 0x7f, 0x77, 0xa7, 0x6d, 0x25, 0x5f, 0xd7, 0x2e, 0x61, 0x47, 0xea, 0x1b, 0xb7, 0xad,
-	0x43, 0xbb, 0x05, 0xdc, 0x10, 0xb9, 0xa2, 0x35, 0x5a, 0x4d, 0x37, 0x49, 0x59, 0x6f, 0x9e, 0xb4,
-	0xc9, 0x16, 0xcc, 0xb0, 0x3e, 0x09, 0x1d, 0x5d, 0xb2, 0x5b, 0x53, 0xb7, 0x54, 0xb2, 0xed, 0xf9,
-	0x87, 0x22, 0xa2, 0x5b, 0xc0, 0x2a, 0x14, 0xed, 0x42, 0x25, 0x24, 0xbe, 0x13, 0x0c, 0x64, 0xc1,
-	0xea, 0xed, 0xcf, 0xa7, 0x26, 0xc1, 0x12, 0x7a, 0x48, 0x3d, 0xda, 0x13, 0xe5, 0xeb, 0x16, 0xb0,
-	0x8e, 0x16, 0x63, 0x80, 0xeb, 0x9f, 0xd1, 0x90, 0xcb, 0x9e, 0xac, 0x62, 0xbd, 0x32, 0x9f, 0xc1,
-	0xe5, 0xc9, 0x87, 0xcd, 0x34, 0xb9, 0x91, 0x6b, 0x72, 0x13, 0xaa, 0x19, 0x3d, 0x6b, 0x38, 0x5e,
-	0x9b, 0x37, 0x75, 0x95, 0xa3, 0xb3, 0xa0, 0xa5, 0x48, 0x06, 0x51, 0xe4, 0x9a, 0x3e, 0x98, 0xf9,
-	0x19, 0xcc, 0xe7, 0xd8, 0x0a, 0x8e, 0xfe, 0x68, 0x70, 0xac, 0x5b, 0x6a, 0x06, 0xeb, 0xd5, 0x56,
-	0x05, 0xca, 0xa7, 0xae, 0xef, 0x58, 0x3f, 0x1b, 0x80, 0x2e, 0xb6, 0xbb, 0x20, 0xd3, 0x0f, 0x18,
-	0x4f, 0x13, 0x8d, 0xd6, 0xe2, 0x39, 0xe3, 0x41, 0xe0, 0x69, 0x92, 0xf2, 0xb7, 0xb0, 0x8d, 0x18,
-	0x0d, 0xf5, 0xe5, 0x94, 0xbf, 0x51, 0x1b, 0x96, 0x85, 0xae, 0xf6, 0x19, 0x0d, 0xc5, 0xfd, 0x73,
-	0xfd, 0x57, 0x81, 0xfd, 0x03, 0x0b, 0x7c, 0x7d, 0x37, 0x17, 0x85, 0xf3, 0x45, 0xe2, 0xfb, 0x86,
-	0x05, 0xbe, 0xf5, 0x97, 0x01, 0x4b, 0x52, 0xfd, 0xa8, 0x14, 0x13, 0x9f, 0xe6, 0x99, 0xfc, 0x13,
-	0xb0, 0x0a, 0xb5, 0x90, 0x9c, 0xdb, 0xaf, 0x45, 0x64, 0xdc, 0xb1, 0xd5, 0x90, 0x9c, 0xab, 0x3b,
-	0xf1, 0x00, 0x1a, 0x43, 0x12, 0x32, 0xea, 0x68, 0x84, 0x6a, 0xd7, 0xe5, 0x89, 0x65, 0xef, 0x16,
-	0x70, 0x5d, 0x81, 0x55, 0x2c, 0x82, 0x12, 0xf1, 0x3c, 0x55, 0xe1, 0x6e, 0x01, 0x8b, 0x05, 0xba,
-	0x01, 0x8d, 0x3e, 0x61, 0x76, 0x5c, 0xca, 0xa8, 0x4d, 0xeb, 0x7d, 0xc2, 0x76, 0xb5, 0x31, 0x56,
-	0x78, 0x13, 0x96, 0x73, 0x27, 0xd2, 0xb7, 0x7d, 0xda, 0x73, 0x68, 0x5d, 0x81, 0xe5, 0x7d, 0x97,
-	0xf1, 0x83, 0xe8, 0x88, 0x91, 0x10, 0xd6, 0x7d, 0xb8, 0x9c, 0x77, 0xe8, 0x7c, 0x19, 0x89, 0x54,
-	0x5f, 0xa4, 0x3e, 0x96, 0x3f, 0x1a, 0xd0, 0x38, 0x74, 0xdf, 0xd2, 0xb8, 0xc4, 0xab, 0x00, 0x3c,
-	0xe0, 0xc4, 0xb3, 0xc3, 0xe0, 0x5c, 0x75, 0x5c, 0x49, 0xcc, 0x63, 0x9c, 0x78, 0x38, 0x38, 0x67,
-	0xe8, 0x3a, 0xd4, 0x49, 0x8f, 0xbb, 0x67, 0x54, 0xf9, 0xd5, 0x20, 0x0b, 0xca, 0x24, 0x01, 0xf7,
-	0xe0, 0x8a, 0x8a, 0x67, 0x3c, 0x08, 0xa9, 0x63, 0x8b, 0xa4, 0xf6, 0xf1, 0x98, 0x53, 0x26, 0xf5,
-	0x28, 0xe1, 0x25, 0xe9, 0x3e, 0x94, 0xde, 0x1d, 0xc2, 0xc9, 0x96, 0xf0, 0x59, 0xd7, 0xa1, 0x2e,
-	0x5b, 0xd8, 0xf5, 0x4f, 0x1e, 0xd3, 0xcc, 0x4c, 0xd5, 0x90, 0x33, 0x95, 0x18, 0xe6, 0x9a, 0x02,
-	0x7e, 0x4c, 0x58, 0x42, 0x36, 0x3f, 0x8c, 0x1a, 0xef, 0x35, 0x8c, 0xae, 0x43, 0x99, 0xb9, 0x6f,
-	0xa3, 0xe9, 0x2c, 0x7e, 0x97, 0xd3, 0x32, 0x60, 0x89, 0x40, 0xf7, 0xa1, 0xc1, 0x34, 0x2b, 0x5b,
-	0xf0, 0x51, 0x03, 0xf2, 0x62, 0x1c, 0x91, 0x30, 0xc6, 0x75, 0x96, 0x2c, 0xac, 0x0e, 0x98, 0x8f,
-	0x28, 0xcf, 0xd3, 0x8d, 0x9a, 0xf6, 0x53, 0x98, 0x0f, 0x7c, 0x6f, 0x6c, 0xf3, 0x88, 0x9e, 0x7a,
-	0x4e, 0xab, 0x78, 0x4e, 0x98, 0x63, 0xd2, 0xcc, 0x3a, 0x84, 0xab, 0x13, 0xd3, 0xe8, 0xca, 0x6e,
-	0x42, 0x35, 0xfe, 0x54, 0xe5, 0xbe, 0x0c, 0x17, 0x62, 0x62, 0xe4, 0xad, 0x53, 0x68, 0xa4, 0xbf,
-	0x40, 0x68, 0x05, 0x96, 0xf7, 0x0e, 0x5e, 0x3c, 0xdc, 0xdf, 0xdb, 0xb1, 0x77, 0x3a, 0xfb, 0x9d,
-	0xa3, 0xbd, 0xa7, 0x07, 0xf6, 0xd1, 0xcb, 0x67, 0x9d, 0x66, 0x01, 0xcd, 0x01, 0x48, 0x53, 0xc7,
-	0x7e, 0x78, 0xf0, 0xb2, 0x69, 0xa0, 0x79, 0xa8, 0xeb, 0xf5, 0xee, 0xde, 0x7e, 0xa7, 0x59, 0x4c,
-	0x01, 0x76, 0xf6, 0x70, 0xb3, 0x94, 0x02, 0x1c, 0x3c, 0x3d, 0xe8, 0x34, 0xcb, 0xed, 0xdf, 0xcb,
-	0xd0, 0x7c, 0x1e, 0x91, 0x38, 0xa4, 0xe1, 0x99, 0xdb, 0xa3, 0xe8, 0x39, 0xcc, 0x65, 0x7b, 0x15,
-	0xad, 0x46, 0xbc, 0x27, 0x36, 0xb7, 0xf9, 0xd1, 0xbb, 0xdc, 0x4a, 0x08, 0xab, 0x80, 0x9e, 0xc1,
-	0x6c, 0xe6, 0x36, 0xa1, 0xf8, 0xfb, 0x36, 0xe9, 0xd9, 0x30, 0x57, 0xdf, 0xe1, 0x8d, 0xf2, 0xdd,
-	0x31, 0xd0, 0x16, 0xd4, 0xe2, 0x61, 0x17, 0xc5, 0xba, 0xe6, 0xc7, 0x69, 0x73, 0x65, 0x82, 0x27,
-	0x66, 0xb5, 0x05, 0xb5, 0x78, 0x6c, 0x4a, 0x72, 0xe4, 0xe7, 0xcb, 0x24, 0xc7, 0x85, 0x19, 0xcb,
-	0x2a, 0xa0, 0xaf, 0xa1, 0x1a, 0x4d, 0xb3, 0xe8, 0x4a, 0x04, 0xcc, 0x4d, 0xd2, 0x66, 0xeb, 0xa2,
-	0x23, 0x4e, 0xd0, 0x01, 0x48, 0x66, 0x0a, 0xb4, 0x92, 0x99, 0x42, 0x32, 0x34, 0xcc, 0x49, 0xae,
-	0x38, 0xcd, 0xf7, 0xb0, 0x38, 0xa1, 0x17, 0x91, 0x95, 0x3a, 0xff, 0x3b, 0xfa, 0xdd, 0xbc, 0x31,
-	0x15, 0x13, 0xed, 0x70, 0x5c, 0x91, 0xff, 0xce, 0xef, 0xfe, 0x13, 0x00, 0x00, 0xff, 0xff, 0xf1,
-	0xcf, 0xd0, 0xb9, 0xab, 0x0f, 0x00, 0x00,
+	// 2911 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x1a, 0xdb, 0x72, 0x1b, 0x49,
+	0xd5, 0xa3, 0x9b, 0xa5, 0x23, 0x59, 0x1e, 0xb7, 0xe3, 0x44, 0x9e, 0x6c, 0x36, 0xc9, 0x6c, 0x05,
+	0xbc, 0x1b, 0xd6, 0xbb, 0xe5, 0x64, 0xb3, 0xb0, 0xa1, 0x0a, 0x14, 0x5b, 0xb6, 0xb5, 0x6b, 0x4b,
+	0x4e, 0xcb, 0xc9, 0x5e, 0xa0, 0x98, 0x9d, 0x68, 0xda, 0xd6, 0x90, 0xd1, 0x8c, 0x32, 0x3d, 0xb2,
+	0xe3, 0x7d, 0xa1, 0x0a, 0x8a, 0x2a, 0xa8, 0xa2, 0x6a, 0xf9, 0x02, 0x5e, 0x78, 0xa3, 0xf8, 0x08,
+	0x1e, 0x28, 0x28, 0x78, 0xe6, 0x85, 0x07, 0xfe, 0x85, 0xea, 0xdb, 0x8c, 0x46, 0xb7, 0x5c, 0x60,
+	0x0b, 0xde, 0xa6, 0xcf, 0x39, 0x7d, 0xfa, 0xf4, 0xb9, 0xf4, 0xb9, 0x48, 0x00, 0xcf, 0xfa, 0x27,
+	0x74, 0x73, 0x10, 0x06, 0x51, 0x80, 0x0a, 0xec, 0x7b, 0xf0, 0xc4, 0xdc, 0x80, 0xd2, 0xb1, 0xdb,
+	0x27, 0x34, 0xb2, 0xfb, 0x03, 0x74, 0x15, 0x4a, 0x43, 0xdf, 0x7d, 0x6e, 0xf9, 0xb6, 0x1f, 0xd4,
+	0xb4, 0x1b, 0xda, 0x46, 0x16, 0x17, 0x19, 0xa0, 0x65, 0xfb, 0x81, 0xf9, 0x6b, 0x0d, 0x4a, 0xdb,
+	0x3d, 0xd2, 0x7d, 0x4a, 0x87, 0x7d, 0x8a, 0x2e, 0x43, 0xc1, 0x23, 0xfe, 0x69, 0xd4, 0x93, 0x74,
+	0x72, 0xc5, 0xe0, 0xb4, 0x67, 0x6f, 0x7d, 0x70, 0xaf, 0x96, 0xb9, 0xa1, 0x6d, 0x54, 0xb0, 0x5c,
+	0xa1, 0x5b, 0x50, 0x8d, 0x42, 0xb7, 0xdf, 0x27, 0x8e, 0x25, 0xf7, 0x65, 0xf9, 0xbe, 0x25, 0x09,
+	0x3d, 0x10, 0xdb, 0x47, 0xc8, 0x24, 0x9b, 0x1c, 0x67, 0xa3, 0xc8, 0x3a, 0x1c, 0x68, 0xfe, 0x33,
+	0x03, 0x7a, 0xc3, 0x8f, 0xdc, 0xe8, 0x62, 0xd7, 0xf5, 0xc8, 0x3e, 0xb1, 0x1d, 0x12, 0x32, 0xe9,
+	0x09, 0x87, 0x59, 0xae, 0xc3, 0xa5, 0x2a, 0xe1, 0xa2, 0x00, 0x34, 0x1d, 0x64, 0x40, 0xf1, 0xc4,
+	0xf5, 0x88, 0x6f, 0xf7, 0x09, 0x97, 0xac, 0x84, 0xe3, 0x35, 0x7a, 0x0f, 0x4a, 0x5d, 0x75, 0x31,
+	0x2e, 0x56, 0x79, 0x6b, 0x65, 0x53, 0xe8, 0x67, 0x33, 0xbe, 0x31, 0x4e, 0x68, 0xd0, 0x5d, 0xa8,
+	0x78, 0x36, 0x8d, 0xac, 0x6e, 0xcf, 0xf6, 0x4f, 0x89, 0x53, 0xcb, 0xa5, 0xf7, 0xc4, 0x0a, 0xc5,
+	0x65, 0x46, 0xb6, 0x2d, 0xa8, 0xd0, 0x3a, 0x14, 0xc3, 0xe0, 0xdc, 0x3a, 0x1d, 0xba, 0x4e, 0x2d,
+	0xcf, 0x45, 0x58, 0x0c, 0x83, 0xf3, 0xbd, 0xa1, 0xeb, 0xa0, 0x37, 0xa0, 0x14, 0x05, 0xfd, 0x27,
+	0x34, 0x0a, 0x7c, 0x52, 0x2b, 0xdc, 0xd0, 0x36, 0x8a, 0x38, 0x01, 0x30, 0x2c, 0x93, 0x93, 0x0e,
+	0xec, 0x2e, 0xa9, 0x2d, 0xf2, 0x9d, 0x09, 0x80, 0x61, 0x1d, 0x37, 0x24, 0xdd, 0x28, 0x08, 0x2f,
+	0x6a, 0x45, 0xb1, 0x37, 0x06, 0xa0, 0xf7, 0x01, 0xc8, 0xf3, 0x81, 0x1b, 0x12, 0x6a, 0xd9, 0x51,
+	0xad, 0x34, 0x4b, 0xd0, 0x92, 0x24, 0xaa, 0x47, 0xe6, 0x1f, 0x34, 0x28, 0x08, 0xdd, 0xce, 0xd7,
+	0xe8, 0x7b, 0x90, 0x67, 0x1a, 0xa4, 0xb5, 0xcc, 0x8d, 0xec, 0x46, 0x79, 0x6b, 0x5d, 0x31, 0x15,
+	0x7b, 0x37, 0x99, 0x61, 0x68, 0xc3, 0x8f, 0xc2, 0x0b, 0x2c, 0xe8, 0x0c, 0x0c, 0x90, 0x00, 0x91,
+	0x0e, 0xd9, 0xa7, 0xe4, 0x42, 0x72, 0x65, 0x9f, 0x68, 0x13, 0xf2, 0x67, 0xb6, 0x37, 0x14, 0xf6,
+	0x29, 0x6f, 0xd5, 0xd2, 0x0c, 0x13, 0x43, 0x63, 0x41, 0xf6, 0x51, 0xe6, 0xbb, 0x9a, 0x89, 0x01,
+	0x12, 0x34, 0x7a, 0x1f, 0x0a, 0x3d, 0x4e, 0x52, 0xd3, 0x5e, 0xc0, 0x42, 0xd2, 0x21, 0x04, 0x39,
+	0xc7, 0x8e, 0x6c, 0xe9, 0xac, 0xfc, 0xdb, 0x1c, 0x82, 0xbe, 0x47, 0x22, 0xb1, 0x05, 0x93, 0x67,
+	0x43, 0x42, 0xa3, 0xf9, 0x9a, 0x48, 0xd9, 0x27, 0x33, 0x6e, 0x9f, 0x6f, 0x41, 0xde, 0xa6, 0x56,
+	0x70, 0x52, 0xcb, 0xce, 0x52, 0x7e, 0xce, 0xa6, 0xed, 0x13, 0xf3, 0x3e, 0xac, 0x8c, 0x1c, 0x4b,
+	0x07, 0x81, 0x4f, 0xd9, 0xe6, 0x82, 0x38, 0x46, 0xde, 0xa8, 0x9a, 0xbe, 0x11, 0x96, 0x58, 0xf3,
+	0xb7, 0x1a, 0x2c, 0x63, 0x62, 0x3b, 0xec, 0x8a, 0x2f, 0x25, 0xf3, 0xbc, 0x78, 0x48, 0xdd, 0x27,
+	0x3b, 0xf3, 0x3e, 0xb9, 0xf9, 0xf7, 0xf9, 0x08, 0xf4, 0x44, 0xa2, 0xf8, 0x3a, 0x39, 0x76, 0x8a,
+	0xbc, 0x0c, 0x9a, 0x34, 0x0f, 0xe6, 0x78, 0xf3, 0x2f, 0x19, 0xd0, 0x3f, 0x0d, 0xdd, 0x88, 0x8c,
+	0xde, 0x27, 0x25, 0x56, 0x61, 0x5c, 0xac, 0xd7, 0xbe, 0xad, 0x72, 0x81, 0x6c, 0xe2, 0x02, 0xe8,
+	0x1d, 0x58, 0x09, 0x3c, 0xc7, 0x0a, 0xc9, 0x99, 0x4b, 0xdd, 0xc0, 0x17, 0x31, 0x9b, 0xe3, 0x1b,
+	0x97, 0x03, 0xcf, 0xc1, 0x12, 0xce, 0x63, 0xf7, 0x13, 0x58, 0xb5, 0x87, 0x51, 0x2f, 0x08, 0x69,
+	0xcf, 0x1d, 0x58, 0x7d, 0x12, 0xd9, 0x9c, 0x5d, 0x9e, 0x5f, 0xd1, 0x50, 0x57, 0xac, 0xc7, 0x24,
+	0x87, 0x92, 0x02, 0x23, 0x7b, 0x02, 0x96, 0x0e, 0xe6, 0xc5, 0xf9, 0xc1, 0x5c, 0x7c, 0x89, 0x60,
+	0x6e, 0xc0, 0xca, 0x88, 0x1e, 0xa5, 0x15, 0x5e, 0x39, 0x4c, 0xcc, 0x1e, 0xe8, 0xea, 0xce, 0xdb,
+	0x81, 0x7f, 0xe2, 0xb9, 0xdd, 0x88, 0xe9, 0x88, 0x3c, 0x1f, 0x90, 0x6e, 0x44, 0x1c, 0x2b, 0x7e,
+	0xd7, 0x84, 0xe2, 0x97, 0x15, 0x02, 0xcb, 0xf7, 0x6d, 0x03, 0xf4, 0xee, 0x30, 0x0c, 0x89, 0x1f,
+	0x25, 0xa4, 0xc2, 0x0e, 0x55, 0x09, 0x97, 0x94, 0xe6, 0xef, 0x32, 0xb0, 0xb2, 0x43, 0x3c, 0x92,
+	0x36, 0xfd, 0x37, 0xe4, 0xca, 0xff, 0x33, 0x33, 0x7f, 0x0f, 0x96, 0x1c, 0x76, 0x49, 0x76, 0x68,
+	0x74, 0x31, 0x10, 0xee, 0x5c, 0xdd, 0xba, 0xa4, 0xd8, 0xec, 0x48, 0xe4, 0xf1, 0xc5, 0x80, 0xe0,
+	0x8a, 0x33, 0xb2, 0x32, 0x77, 0x01, 0x8d, 0xea, 0xe7, 0xb5, 0x4d, 0xfa, 0xf5, 0x12, 0x2c, 0x71,
+	0xa4, 0x4b, 0xe8, 0xc3, 0x21, 0x09, 0x2f, 0xd0, 0x5d, 0x28, 0x74, 0x3d, 0x7b, 0x48, 0x59, 0x78,
+	0xb2, 0x17, 0xfd, 0x8d, 0x14, 0x0f, 0x45, 0xb6, 0xb9, 0xcd, 0x69, 0xb0, 0xa4, 0x35, 0xfe, 0x5a,
+	0x81, 0x82, 0x00, 0xa1, 0x9b, 0x50, 0x66, 0x8a, 0xb7, 0xc8, 0x73, 0x97, 0x46, 0x54, 0xd8, 0x69,
+	0x7f, 0x01, 0x03, 0x03, 0x36, 0x38, 0x0c, 0x7d, 0x01, 0x4b, 0x9c, 0xa4, 0x1b, 0xf8, 0x11, 0xf1,
+	0x23, 0x2a, 0xdf, 0xfa, 0x3b, 0xf3, 0x8e, 0xe2, 0xa9, 0x64, 0xdf, 0xa6, 0xc7, 0xa2, 0x04, 0xd8,
+	0x96, 0x5b, 0xf7, 0x17, 0x70, 0x85, 0xf1, 0x52, 0x6b, 0x74, 0x6d, 0xd4, 0x49, 0x72, 0xf2, 0xf0,
+	0xc4, 0x4d, 0x1e, 0x40, 0x9e, 0xf6, 0xec, 0xd0, 0x91, 0x26, 0x7b, 0x67, 0xee, 0x91, 0x42, 0x6d,
+	0x4d, 0xbf, 0xc3, 0x76, 0xec, 0x2f, 0x60, 0xb1, 0x15, 0xed, 0x42, 0x21, 0xb4, 0x7d, 0x27, 0xe8,
+	0x73, 0x83, 0x95, 0xb7, 0xbe, 0x33, 0x97, 0x09, 0xe6, 0xa4, 0x1d, 0xe2, 0x91, 0x2e, 0x33, 0xdf,
+	0xfe, 0x02, 0x96, 0xbb, 0xd1, 0x7d, 0x28, 0xd8, 0xfe, 0x05, 0x7b, 0x44, 0x17, 0x39, 0x1f, 0x73,
+	0x2e, 0x9f, 0xba, 0x7f, 0xd1, 0x3e, 0x61, 0x42, 0xd8, 0xec, 0x03, 0xed, 0xc1, 0x62, 0x37, 0xe8,
+	0x0f, 0xec, 0x90, 0xc8, 0x27, 0xe0, 0xf6, 0x0b, 0xb5, 0xb7, 0xcd, 0xe9, 0x5d, 0xca, 0x85, 0x50,
+	0xbb, 0xd1, 0x27, 0xb0, 0xd8, 0xb7, 0xa3, 0x6e, 0x8f, 0x50, 0x59, 0x18, 0xbc, 0xf7, 0x42, 0x46,
+	0x87, 0x82, 0xfe, 0xc8, 0x8e, 0x22, 0x12, 0x72, 0x66, 0x92, 0x03, 0xba, 0x0f, 0x39, 0x1a, 0x84,
+	0x51, 0x0d, 0x38, 0xa7, 0x5b, 0x73, 0x39, 0xb5, 0x43, 0x87, 0x84, 0xae, 0x7f, 0xba, 0xbf, 0x80,
+	0xf9, 0x26, 0x74, 0x19, 0xf2, 0x9e, 0xdb, 0x77, 0xa3, 0x5a, 0xf9, 0x86, 0xb6, 0x91, 0x67, 0x57,
+	0xe5, 0x4b, 0x54, 0x83, 0x42, 0x70, 0x72, 0x42, 0x49, 0x54, 0xab, 0x48, 0x84, 0x5c, 0xb3, 0x3a,
+	0xd3, 0xf5, 0xcf, 0x48, 0x18, 0xf1, 0xa8, 0x2e, 0x62, 0xb9, 0x32, 0x8e, 0xe0, 0xf2, 0x74, 0x77,
+	0x49, 0x3d, 0x13, 0xda, 0xd8, 0x33, 0x61, 0x40, 0x31, 0xe5, 0x91, 0x25, 0x1c, 0xaf, 0x8d, 0x5b,
+	0xb0, 0x94, 0xf2, 0x06, 0x74, 0x49, 0x39, 0x12, 0x0b, 0x93, 0x92, 0x74, 0x0d, 0xe3, 0x6d, 0x58,
+	0x1e, 0xb3, 0x37, 0x93, 0xd1, 0x1f, 0xf6, 0x9f, 0xc8, 0xa0, 0xcc, 0x63, 0xb9, 0x32, 0x7e, 0x08,
+	0x79, 0x6e, 0x52, 0xf4, 0x21, 0x94, 0x6d, 0x8f, 0x29, 0xd2, 0x8e, 0xdc, 0x33, 0x15, 0x76, 0x6b,
+	0x53, 0x55, 0x87, 0x47, 0x29, 0x8d, 0x5f, 0x65, 0xa1, 0x9a, 0xb6, 0xeb, 0xdc, 0xeb, 0x1d, 0x41,
+	0x31, 0x18, 0x90, 0xd0, 0x8e, 0x82, 0x90, 0x5f, 0xaf, 0xba, 0x75, 0xf7, 0x15, 0x5c, 0x66, 0xb3,
+	0x2d, 0xf7, 0xe2, 0x98, 0x0b, 0xd3, 0x81, 0xa8, 0xd5, 0xc4, 0x9b, 0x2a, 0x16, 0xa8, 0x03, 0x25,
+	0x4a, 0xfa, 0xb6, 0x1f, 0xb9, 0x5d, 0xca, 0x23, 0xb0, 0xba, 0xf5, 0xc1, 0xab, 0x1c, 0xd4, 0x51,
+	0x9b, 0x71, 0xc2, 0xc7, 0xfc, 0x12, 0x8a, 0xed, 0xe4, 0x58, 0xbd, 0xd9, 0x7a, 0x5c, 0x3f, 0x68,
+	0xee, 0x58, 0xed, 0xa3, 0x06, 0xae, 0x1f, 0xb7, 0xb1, 0xbe, 0x80, 0x8a, 0x90, 0x3b, 0x68, 0x74,
+	0x3a, 0xba, 0x86, 0x56, 0x60, 0x89, 0x7d, 0x59, 0x6d, 0x6c, 0x35, 0x1e, 0x3e, 0xaa, 0x1f, 0xe8,
+	0x19, 0x54, 0x86, 0xc5, 0x3d, 0xdc, 0xa8, 0x1f, 0x37, 0xb0, 0x9e, 0x65, 0xfb, 0xe5, 0x22, 0x21,
+	0xc9, 0x99, 0xf7, 0xa1, 0x14, 0x9f, 0x8c, 0xd6, 0x60, 0x45, 0x1d, 0xd1, 0x69, 0x1c, 0xd6, 0x5b,
+	0xc7, 0xcd, 0xed, 0x8e, 0xbe, 0xc0, 0xd8, 0xb4, 0x1e, 0x1d, 0x36, 0x70, 0x73, 0x5b, 0xd7, 0x10,
+	0x40, 0xa1, 0x73, 0x8c, 0x9b, 0xad, 0x3d, 0x3d, 0x63, 0xfc, 0x4b, 0x03, 0x34, 0x19, 0x19, 0x73,
+	0xcd, 0xb1, 0x0f, 0xb9, 0x7e, 0xe0, 0x90, 0x97, 0x36, 0x45, 0x9a, 0xf5, 0xe6, 0x61, 0xe0, 0x10,
+	0xcc, 0x39, 0xa0, 0x1a, 0x2c, 0x0e, 0x04, 0x54, 0x1a, 0x42, 0x2d, 0xcd, 0x3d, 0xc8, 0x31, 0x3a,
+	0xa4, 0x43, 0x45, 0x5d, 0xe7, 0xb0, 0xbd, 0xd3, 0xd0, 0x17, 0x98, 0xf0, 0x47, 0xb8, 0xb1, 0xdb,
+	0xfc, 0x4c, 0xd7, 0x50, 0x05, 0x8a, 0xdb, 0xed, 0xd6, 0x71, 0xbd, 0xd9, 0xea, 0xe8, 0x19, 0xa6,
+	0xc7, 0xbd, 0x83, 0xf6, 0x03, 0x3d, 0x8b, 0x4a, 0x90, 0xc7, 0x8d, 0xbd, 0xc6, 0x67, 0x7a, 0xce,
+	0x60, 0xea, 0x97, 0xe1, 0x3a, 0xf7, 0x52, 0x6f, 0x02, 0x38, 0x84, 0x76, 0x89, 0xef, 0xb8, 0xfe,
+	0x29, 0xbf, 0x5a, 0x11, 0x8f, 0x40, 0x98, 0xa8, 0xfe, 0xb0, 0x4f, 0x42, 0xb7, 0x2b, 0x23, 0x56,
+	0x2d, 0x1f, 0x14, 0x20, 0xf7, 0xd4, 0xf5, 0x1d, 0xf3, 0x37, 0x1a, 0xa0, 0xc9, 0xfc, 0xc9, 0x0e,
+	0xed, 0x05, 0x34, 0x1a, 0x3d, 0x54, 0xad, 0x59, 0xed, 0x16, 0x05, 0x81, 0x27, 0x63, 0x96, 0x7f,
+	0x33, 0xd8, 0x90, 0x92, 0x50, 0x2a, 0x84, 0x7f, 0xa3, 0x2d, 0x58, 0x63, 0x4a, 0xb6, 0xce, 0x48,
+	0xc8, 0x12, 0xba, 0xeb, 0x9f, 0x04, 0xd6, 0x4f, 0x69, 0xe0, 0xcb, 0x64, 0xbf, 0xca, 0x90, 0x8f,
+	0x13, 0xdc, 0xc7, 0x34, 0xf0, 0xcd, 0xdf, 0x67, 0xe0, 0x12, 0x37, 0x85, 0xb2, 0xcb, 0xd4, 0x3a,
+	0x34, 0x3f, 0xb3, 0x3c, 0x2e, 0xcc, 0x2d, 0x8f, 0xd1, 0xdb, 0xa0, 0xbb, 0x7e, 0xd7, 0x1b, 0x3a,
+	0xc4, 0x8a, 0x75, 0xba, 0xc8, 0x1f, 0x94, 0x65, 0x09, 0xdf, 0x55, 0xaa, 0xbd, 0x06, 0xa5, 0xd0,
+	0x3e, 0xb7, 0x9e, 0x31, 0x61, 0xe2, 0xac, 0x5a, 0x0c, 0xed, 0x73, 0x91, 0xb7, 0x3f, 0x82, 0xca,
+	0xc0, 0x0e, 0x29, 0x71, 0x24, 0x85, 0x48, 0xa9, 0xd3, 0x9f, 0x91, 0xfd, 0x05, 0x5c, 0x16, 0xc4,
+	0x62, 0x2f, 0x82, 0xac, 0xed, 0x79, 0xc2, 0x22, 0xfb, 0x0b, 0x98, 0x2d, 0xd0, 0x5b, 0x50, 0xe9,
+	0xd9, 0x34, 0x91, 0x4a, 0xa5, 0xd2, 0x72, 0xcf, 0xa6, 0x4a, 0xa6, 0xd8, 0x68, 0x3f, 0xcf, 0x40,
+	0xad, 0x7e, 0x7a, 0x1a, 0x92, 0x53, 0x3b, 0x22, 0xe3, 0x9a, 0xda, 0x82, 0x7c, 0x22, 0xf4, 0x48,
+	0x41, 0x31, 0x4d, 0xad, 0x58, 0x90, 0xa2, 0x06, 0x14, 0x4f, 0x86, 0x3e, 0x7f, 0x40, 0x65, 0x80,
+	0xbc, 0x1d, 0x17, 0x57, 0x33, 0xce, 0xd9, 0xdc, 0x95, 0x1b, 0x70, 0xbc, 0x35, 0xe5, 0xaa, 0xd9,
+	0xb4, 0xab, 0x9a, 0x6d, 0x28, 0xaa, 0x1d, 0xa3, 0x2f, 0xca, 0xee, 0xa3, 0xd6, 0xf6, 0x71, 0xb3,
+	0xdd, 0xd2, 0x17, 0x98, 0xff, 0x6f, 0xb7, 0x1f, 0xb5, 0x8e, 0x75, 0x0d, 0x2d, 0x42, 0xb6, 0xf3,
+	0xe8, 0x50, 0xcf, 0xb0, 0x8f, 0xc3, 0x66, 0x4b, 0xcf, 0xf2, 0x8f, 0xfa, 0x67, 0x7a, 0x8e, 0x7d,
+	0xd4, 0x1f, 0xef, 0xe9, 0x79, 0xb3, 0x07, 0xeb, 0x53, 0x64, 0x93, 0xa5, 0xd9, 0x25, 0xc8, 0x77,
+	0x83, 0xa1, 0x1f, 0xc9, 0x41, 0x89, 0x58, 0xa0, 0xeb, 0x50, 0xe6, 0x6f, 0xa6, 0x25, 0x70, 0x19,
+	0x8e, 0x03, 0x0e, 0xda, 0xe6, 0x04, 0xa9, 0x17, 0x56, 0x93, 0x2f, 0xac, 0xe9, 0x00, 0xe2, 0xe8,
+	0xc7, 0x6c, 0xf5, 0x1f, 0xe9, 0x79, 0x4e, 0xd5, 0x6c, 0xfe, 0x42, 0x83, 0xd5, 0xd4, 0x31, 0xf2,
+	0x2a, 0x1f, 0x2a, 0x99, 0x44, 0xa6, 0xba, 0x19, 0x0f, 0x49, 0x26, 0x69, 0x37, 0xf9, 0x52, 0x8a,
+	0x6d, 0xdc, 0x81, 0x3c, 0x5f, 0x27, 0xb7, 0xd2, 0x46, 0xf3, 0x46, 0xac, 0xa2, 0xcc, 0x88, 0x8a,
+	0xcc, 0x1f, 0xc3, 0xda, 0xd8, 0x05, 0xa4, 0x18, 0x73, 0xbb, 0x01, 0xd5, 0x62, 0x8a, 0xa9, 0xc4,
+	0xec, 0x16, 0xf3, 0x0a, 0xac, 0x1d, 0xb8, 0x34, 0x6a, 0xa9, 0xc0, 0x55, 0xfa, 0x31, 0xef, 0xc1,
+	0xe5, 0x71, 0x84, 0x3c, 0x37, 0x15, 0xf8, 0x22, 0xf9, 0x27, 0x00, 0xf3, 0x97, 0x1a, 0x54, 0x3a,
+	0xee, 0x57, 0x24, 0x7e, 0xb8, 0xae, 0x01, 0x44, 0x41, 0x64, 0x7b, 0xac, 0xe5, 0xa1, 0xf2, 0x6a,
+	0x25, 0x0e, 0xc1, 0xc1, 0x39, 0x65, 0x1e, 0x60, 0x77, 0x59, 0x36, 0x17, 0x78, 0x31, 0x0e, 0x03,
+	0x01, 0xe2, 0x04, 0x1f, 0xc0, 0x15, 0xb1, 0x9f, 0x46, 0x41, 0x48, 0x1c, 0x8b, 0x31, 0xb5, 0x9e,
+	0x5c, 0x44, 0x44, 0xe4, 0xd6, 0x2c, 0xbe, 0xc4, 0xd1, 0x1d, 0x8e, 0xdd, 0xb1, 0x23, 0xfb, 0x01,
+	0xc3, 0x99, 0xd7, 0xa1, 0xcc, 0xeb, 0x14, 0xd7, 0x3f, 0xfd, 0x84, 0xa4, 0xe6, 0x2c, 0x15, 0x3e,
+	0x67, 0x61, 0x03, 0x1e, 0x9d, 0x91, 0x3f, 0xb1, 0x69, 0x22, 0xec, 0xf8, 0x48, 0x4b, 0x7b, 0xa9,
+	0x91, 0xd6, 0x06, 0xe4, 0xa8, 0xfb, 0x95, 0x9a, 0xd8, 0xc4, 0xed, 0xcb, 0xa8, 0x1a, 0x30, 0xa7,
+	0x40, 0xf7, 0xa0, 0x42, 0xa5, 0x54, 0x16, 0x93, 0x47, 0x0c, 0x43, 0x56, 0xe3, 0x1d, 0x89, 0xc4,
+	0xb8, 0x4c, 0x93, 0x85, 0xd9, 0x00, 0x63, 0x8f, 0x44, 0xe3, 0xe2, 0x2a, 0xc7, 0xff, 0x36, 0x2c,
+	0x07, 0xbe, 0x77, 0x61, 0x45, 0x4a, 0x3c, 0xd1, 0x75, 0x14, 0x71, 0x95, 0x81, 0x63, 0xa1, 0xa9,
+	0xd9, 0x81, 0xab, 0x53, 0xd9, 0x48, 0xcb, 0xde, 0x85, 0x62, 0xdc, 0xd1, 0x8d, 0x35, 0x50, 0x13,
+	0x7b, 0x62, 0x4a, 0xf3, 0xef, 0x1a, 0x54, 0x44, 0x17, 0x26, 0xfa, 0xc4, 0xd7, 0x98, 0x3f, 0xcd,
+	0xe8, 0x2a, 0x33, 0xaf, 0xd5, 0x55, 0x5e, 0x86, 0x82, 0x70, 0x1f, 0x55, 0x13, 0x8b, 0x15, 0x7a,
+	0x0b, 0x96, 0xb8, 0xef, 0x84, 0x24, 0xb2, 0x5d, 0x5f, 0xce, 0x2b, 0x8b, 0xb8, 0x22, 0x54, 0x20,
+	0x60, 0xe6, 0x9f, 0x35, 0xd0, 0xeb, 0x61, 0xb7, 0xe7, 0x9e, 0x91, 0xb8, 0xf1, 0x7d, 0xd9, 0x79,
+	0xcd, 0xff, 0xd1, 0x35, 0x9e, 0x41, 0x8d, 0x45, 0xef, 0xa8, 0x59, 0xa6, 0x27, 0x6e, 0x6d, 0xee,
+	0x00, 0x29, 0x33, 0x67, 0xc6, 0x30, 0x9e, 0x4e, 0x0e, 0x61, 0x7d, 0xca, 0x91, 0x71, 0x63, 0x5e,
+	0x54, 0xe3, 0x05, 0xf9, 0x6a, 0xc6, 0x51, 0x32, 0xba, 0x01, 0xc7, 0x54, 0xe6, 0x1f, 0x35, 0xb8,
+	0x92, 0x0c, 0xce, 0x24, 0xfa, 0x1b, 0xbd, 0x41, 0x6a, 0x32, 0x9d, 0x4b, 0x4f, 0xa6, 0xaf, 0x43,
+	0x59, 0xb8, 0xaa, 0xc5, 0x22, 0x8a, 0x97, 0x3b, 0x45, 0x0c, 0x02, 0xd4, 0xf6, 0xbd, 0x0b, 0xf3,
+	0x6b, 0x0d, 0x6a, 0x93, 0xe2, 0xbe, 0xda, 0xbc, 0xef, 0xbf, 0xea, 0x3f, 0xe6, 0xcf, 0xa0, 0xfa,
+	0x80, 0x55, 0xcc, 0xa2, 0x6b, 0x10, 0x71, 0x99, 0x3f, 0x0f, 0xdd, 0x88, 0x8c, 0x87, 0xe5, 0xf8,
+	0x88, 0x91, 0x35, 0x9e, 0x9c, 0x10, 0xdd, 0x81, 0x02, 0x9f, 0xba, 0xa8, 0xa7, 0x6d, 0x3d, 0x35,
+	0x99, 0x19, 0xdb, 0x23, 0x49, 0xe3, 0x9a, 0x68, 0x07, 0x2a, 0x5c, 0x00, 0x65, 0xb5, 0xbb, 0x50,
+	0x0a, 0x94, 0x2c, 0xd2, 0x09, 0x2e, 0x2b, 0x7e, 0x69, 0x49, 0x71, 0x42, 0x68, 0xd6, 0x61, 0x49,
+	0x72, 0x99, 0x32, 0xe3, 0xc9, 0xbe, 0xd4, 0x8c, 0xe7, 0x5d, 0x58, 0x4b, 0xf3, 0xdf, 0xb5, 0x5d,
+	0x6f, 0x18, 0xf2, 0x84, 0xeb, 0xfa, 0x0e, 0x79, 0x2e, 0x1b, 0x53, 0xb1, 0x30, 0xff, 0xa6, 0xc1,
+	0xea, 0xa7, 0x8c, 0x5e, 0x3c, 0xef, 0x2f, 0x19, 0x37, 0xb7, 0xa0, 0x6a, 0x7b, 0x9e, 0x15, 0x03,
+	0xa8, 0x2c, 0xfe, 0x97, 0x6c, 0xcf, 0x4b, 0x92, 0x28, 0x27, 0x3b, 0x89, 0x48, 0x68, 0x51, 0xc6,
+	0xd5, 0x97, 0xe3, 0xb8, 0x2c, 0x5e, 0xe2, 0xd0, 0x8e, 0x04, 0x32, 0x57, 0x3c, 0x09, 0x83, 0xbe,
+	0xe5, 0x07, 0xe7, 0x32, 0xbe, 0x17, 0xd9, 0xba, 0x15, 0x9c, 0xa3, 0xdb, 0xaa, 0xca, 0xc9, 0xcf,
+	0x29, 0x70, 0x65, 0x79, 0x63, 0xfe, 0x08, 0xca, 0xe2, 0x16, 0x8d, 0x33, 0xe2, 0x47, 0xaf, 0xf1,
+	0x32, 0x1b, 0x50, 0x8c, 0x25, 0x15, 0xb9, 0x3b, 0x5e, 0x9b, 0x3f, 0x81, 0x2a, 0xef, 0x5a, 0xbb,
+	0x91, 0x52, 0xd1, 0x6d, 0x58, 0x09, 0x49, 0xc4, 0x82, 0x2d, 0xf0, 0x2d, 0x4a, 0xba, 0x81, 0xef,
+	0x50, 0x59, 0xf0, 0xe9, 0x31, 0xa2, 0x23, 0xe0, 0x2c, 0xa6, 0xe8, 0x53, 0x77, 0x60, 0x9d, 0xd9,
+	0xdd, 0xe1, 0xb0, 0xaf, 0x7a, 0x25, 0x06, 0x7a, 0xcc, 0x21, 0xe6, 0x9f, 0x34, 0x58, 0x8e, 0x0f,
+	0x90, 0xd6, 0xbf, 0x0d, 0x2b, 0xc2, 0xcd, 0x92, 0x79, 0x65, 0x7c, 0x82, 0x44, 0xc4, 0xaf, 0x0f,
+	0x7a, 0x17, 0x90, 0x22, 0x8e, 0x7f, 0x46, 0x52, 0x25, 0x88, 0x62, 0x73, 0x1c, 0x23, 0x58, 0x1a,
+	0xe5, 0x75, 0x85, 0x15, 0x92, 0xae, 0x67, 0xbb, 0x7d, 0xe2, 0x48, 0xe3, 0x54, 0x39, 0x18, 0x2b,
+	0x68, 0x9c, 0xef, 0x73, 0x2f, 0xca, 0xf7, 0xe6, 0x43, 0x40, 0x47, 0x43, 0xcf, 0x1b, 0xf3, 0xa4,
+	0x49, 0x27, 0xd0, 0xa6, 0x39, 0xc1, 0x25, 0x35, 0x0e, 0xca, 0x08, 0xf7, 0xe4, 0x0b, 0x73, 0x00,
+	0xab, 0x29, 0x96, 0x49, 0xee, 0x1e, 0x7b, 0x61, 0x63, 0xe3, 0x8e, 0xe7, 0xb3, 0xe4, 0x95, 0x65,
+	0xc9, 0x84, 0xd7, 0x3b, 0x63, 0x36, 0xe6, 0x45, 0x90, 0x92, 0xc3, 0xfc, 0x98, 0x5d, 0x82, 0x8e,
+	0x87, 0xc3, 0x6b, 0x1d, 0x68, 0x36, 0x61, 0x35, 0xc5, 0x4b, 0x4a, 0x5f, 0x83, 0x45, 0x7b, 0x30,
+	0xf0, 0x5c, 0x59, 0x72, 0x65, 0xb1, 0x5a, 0x32, 0x0c, 0x73, 0x89, 0x01, 0x71, 0xa4, 0x6c, 0x6a,
+	0xf9, 0xce, 0x53, 0xa8, 0x8c, 0x0e, 0x88, 0xd1, 0x3a, 0xac, 0xa9, 0x1e, 0x66, 0xa7, 0x71, 0xd0,
+	0x60, 0x3d, 0x8c, 0x75, 0xfc, 0xf9, 0x11, 0x6b, 0xf6, 0xab, 0x00, 0x1c, 0xd4, 0xb0, 0xea, 0xad,
+	0xcf, 0x75, 0x0d, 0x2d, 0x43, 0x59, 0xae, 0x77, 0x9b, 0x07, 0x0d, 0x3d, 0x33, 0x42, 0xb0, 0xd3,
+	0x64, 0x13, 0x92, 0x84, 0xa0, 0xd5, 0x6e, 0x35, 0xf4, 0xdc, 0xd6, 0x3f, 0x4a, 0xa0, 0x3f, 0x54,
+	0xc6, 0xed, 0x90, 0xf0, 0xcc, 0xed, 0x12, 0xf4, 0x10, 0xaa, 0xe9, 0x1a, 0x19, 0x5d, 0x53, 0x2a,
+	0x98, 0x5a, 0x54, 0x1b, 0x6f, 0xce, 0x42, 0x0b, 0x35, 0x98, 0x0b, 0xe8, 0x08, 0x96, 0x52, 0xd5,
+	0x3e, 0x9a, 0xdb, 0xc5, 0x18, 0xd7, 0x66, 0x60, 0x15, 0xbf, 0xf7, 0x35, 0xf4, 0x05, 0xac, 0x4c,
+	0x74, 0x65, 0xe8, 0xc6, 0x8b, 0x9a, 0x49, 0xe3, 0xe6, 0x1c, 0x8a, 0x58, 0xda, 0x7d, 0x28, 0x8f,
+	0x34, 0x3d, 0xc8, 0x98, 0xda, 0x09, 0x09, 0x7e, 0x57, 0xe7, 0x74, 0x49, 0xe6, 0x02, 0x7a, 0x00,
+	0xa5, 0xf8, 0x67, 0x3f, 0x14, 0x3b, 0xd2, 0xf8, 0x0f, 0x90, 0xc6, 0xfa, 0x14, 0xcc, 0x28, 0x8f,
+	0x38, 0x95, 0xa1, 0x99, 0xd9, 0xcd, 0x58, 0x9f, 0x82, 0x89, 0x79, 0xfc, 0x00, 0x8a, 0x2a, 0x8d,
+	0xa3, 0x2b, 0x8a, 0x70, 0xec, 0x27, 0x45, 0xa3, 0x36, 0x89, 0x88, 0x19, 0x34, 0x00, 0x92, 0xe4,
+	0x88, 0x66, 0x27, 0x4c, 0xc3, 0x98, 0x86, 0x8a, 0xd9, 0xdc, 0x83, 0x3c, 0xcf, 0x59, 0xe8, 0x52,
+	0x2a, 0x45, 0xaa, 0xcd, 0x6b, 0x63, 0xd0, 0x78, 0xdf, 0x0e, 0x54, 0x46, 0x73, 0x17, 0x8a, 0xd5,
+	0x3e, 0x25, 0xa3, 0x19, 0xab, 0xc9, 0xcf, 0xfb, 0x71, 0x8e, 0xe0, 0x3e, 0xf3, 0x25, 0xac, 0x4e,
+	0xe9, 0x13, 0x90, 0x39, 0xa2, 0xfd, 0x19, 0xbd, 0x88, 0xf1, 0xd6, 0x5c, 0x9a, 0x58, 0xce, 0x2f,
+	0x60, 0x65, 0xa2, 0x5a, 0x4c, 0xbc, 0x72, 0x56, 0xed, 0x6a, 0xdc, 0x9c, 0x43, 0x11, 0xf3, 0xfe,
+	0x74, 0xf4, 0x27, 0x57, 0x81, 0x46, 0xd7, 0x27, 0x4d, 0x96, 0xaa, 0x29, 0x8d, 0x1b, 0xb3, 0x09,
+	0x62, 0xc6, 0xdf, 0x87, 0x45, 0x99, 0x8f, 0xd0, 0xe5, 0xc4, 0x9d, 0x47, 0x33, 0xa0, 0x71, 0x65,
+	0x02, 0x3e, 0x1a, 0x2c, 0x23, 0x0f, 0x77, 0x12, 0x2c, 0x93, 0x09, 0xc2, 0xb8, 0x3a, 0x15, 0x97,
+	0xe6, 0x44, 0x7b, 0x53, 0x38, 0xd1, 0xde, 0x6c, 0x4e, 0x13, 0xaf, 0xae, 0xb9, 0xf0, 0xa4, 0xc0,
+	0xff, 0x06, 0x73, 0xe7, 0xdf, 0x03, 0x00, 0x52, 0x5d, 0xcb, 0x09, 0x14, 0x23, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1940,11 +4017,20 @@ const _ = grpc.SupportPackageIsVersion4
 type QMetadataServiceClient interface {
 	ListNamespaces(ctx context.Context, in *ListNamespacesRequest, opts ...grpc.CallOption) (*ListNamespacesResponse, error)
 	QueryEntities(ctx context.Context, in *QueryEntitiesRequest, opts ...grpc.CallOption) (QMetadataService_QueryEntitiesClient, error)
+	AggregateEntities(ctx context.Context, in *AggregateEntitiesRequest, opts ...grpc.CallOption) (*AggregateEntitiesResponse, error)
+	CountValues(ctx context.Context, in *CountValuesRequest, opts ...grpc.CallOption) (*CountValuesResponse, error)
 	GetEntity(ctx context.Context, in *GetEntityRequest, opts ...grpc.CallOption) (*GetEntityResponse, error)
 	WriteFile(ctx context.Context, in *WriteFileRequest, opts ...grpc.CallOption) (*WriteFileResponse, error)
 	ReadFile(ctx context.Context, in *ReadFileRequest, opts ...grpc.CallOption) (*ReadFileResponse, error)
 	DeleteFile(ctx context.Context, in *DeleteFileRequest, opts ...grpc.CallOption) (*DeleteFileResponse, error)
+	Batch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error)
+	WatchChanges(ctx context.Context, in *WatchChangesRequest, opts ...grpc.CallOption) (QMetadataService_WatchChangesClient, error)
 	GetDatabaseMetadata(ctx context.Context, in *GetDatabaseMetadataRequest, opts ...grpc.CallOption) (*GetDatabaseMetadataResponse, error)
+	ListFileRevisions(ctx context.Context, in *ListFileRevisionsRequest, opts ...grpc.CallOption) (*ListFileRevisionsResponse, error)
+	ReadFileRevision(ctx context.Context, in *ReadFileRevisionRequest, opts ...grpc.CallOption) (*ReadFileRevisionResponse, error)
+	Compact(ctx context.Context, in *CompactRequest, opts ...grpc.CallOption) (*CompactResponse, error)
+	PullChanges(ctx context.Context, in *PullChangesRequest, opts ...grpc.CallOption) (*PullChangesResponse, error)
+	PushChanges(ctx context.Context, in *PushChangesRequest, opts ...grpc.CallOption) (*PushChangesResponse, error)
 }
 
 type qMetadataServiceClient struct {
@@ -1996,6 +4082,24 @@ func (x *qMetadataServiceQueryEntitiesClient) Recv() (*QueryEntitiesResponse, er
 	return m, nil
 }
 
+func (c *qMetadataServiceClient) AggregateEntities(ctx context.Context, in *AggregateEntitiesRequest, opts ...grpc.CallOption) (*AggregateEntitiesResponse, error) {
+	out := new(AggregateEntitiesResponse)
+	err := c.cc.Invoke(ctx, "/qmfspb.QMetadataService/AggregateEntities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qMetadataServiceClient) CountValues(ctx context.Context, in *CountValuesRequest, opts ...grpc.CallOption) (*CountValuesResponse, error) {
+	out := new(CountValuesResponse)
+	err := c.cc.Invoke(ctx, "/qmfspb.QMetadataService/CountValues", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *qMetadataServiceClient) GetEntity(ctx context.Context, in *GetEntityRequest, opts ...grpc.CallOption) (*GetEntityResponse, error) {
 	out := new(GetEntityResponse)
 	err := c.cc.Invoke(ctx, "/qmfspb.QMetadataService/GetEntity", in, out, opts...)
@@ -2032,6 +4136,47 @@ func (c *qMetadataServiceClient) DeleteFile(ctx context.Context, in *DeleteFileR
 	return out, nil
 }
 
+func (c *qMetadataServiceClient) Batch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error) {
+	out := new(BatchResponse)
+	err := c.cc.Invoke(ctx, "/qmfspb.QMetadataService/Batch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qMetadataServiceClient) WatchChanges(ctx context.Context, in *WatchChangesRequest, opts ...grpc.CallOption) (QMetadataService_WatchChangesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_QMetadataService_serviceDesc.Streams[1], "/qmfspb.QMetadataService/WatchChanges", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &qMetadataServiceWatchChangesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type QMetadataService_WatchChangesClient interface {
+	Recv() (*ChangeEvent, error)
+	grpc.ClientStream
+}
+
+type qMetadataServiceWatchChangesClient struct {
+	grpc.ClientStream
+}
+
+func (x *qMetadataServiceWatchChangesClient) Recv() (*ChangeEvent, error) {
+	m := new(ChangeEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *qMetadataServiceClient) GetDatabaseMetadata(ctx context.Context, in *GetDatabaseMetadataRequest, opts ...grpc.CallOption) (*GetDatabaseMetadataResponse, error) {
 	out := new(GetDatabaseMetadataResponse)
 	err := c.cc.Invoke(ctx, "/qmfspb.QMetadataService/GetDatabaseMetadata", in, out, opts...)
@@ -2041,15 +4186,69 @@ func (c *qMetadataServiceClient) GetDatabaseMetadata(ctx context.Context, in *Ge
 	return out, nil
 }
 
+func (c *qMetadataServiceClient) ListFileRevisions(ctx context.Context, in *ListFileRevisionsRequest, opts ...grpc.CallOption) (*ListFileRevisionsResponse, error) {
+	out := new(ListFileRevisionsResponse)
+	err := c.cc.Invoke(ctx, "/qmfspb.QMetadataService/ListFileRevisions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qMetadataServiceClient) ReadFileRevision(ctx context.Context, in *ReadFileRevisionRequest, opts ...grpc.CallOption) (*ReadFileRevisionResponse, error) {
+	out := new(ReadFileRevisionResponse)
+	err := c.cc.Invoke(ctx, "/qmfspb.QMetadataService/ReadFileRevision", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qMetadataServiceClient) Compact(ctx context.Context, in *CompactRequest, opts ...grpc.CallOption) (*CompactResponse, error) {
+	out := new(CompactResponse)
+	err := c.cc.Invoke(ctx, "/qmfspb.QMetadataService/Compact", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qMetadataServiceClient) PullChanges(ctx context.Context, in *PullChangesRequest, opts ...grpc.CallOption) (*PullChangesResponse, error) {
+	out := new(PullChangesResponse)
+	err := c.cc.Invoke(ctx, "/qmfspb.QMetadataService/PullChanges", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qMetadataServiceClient) PushChanges(ctx context.Context, in *PushChangesRequest, opts ...grpc.CallOption) (*PushChangesResponse, error) {
+	out := new(PushChangesResponse)
+	err := c.cc.Invoke(ctx, "/qmfspb.QMetadataService/PushChanges", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // QMetadataServiceServer is the server API for QMetadataService service.
 type QMetadataServiceServer interface {
 	ListNamespaces(context.Context, *ListNamespacesRequest) (*ListNamespacesResponse, error)
 	QueryEntities(*QueryEntitiesRequest, QMetadataService_QueryEntitiesServer) error
+	AggregateEntities(context.Context, *AggregateEntitiesRequest) (*AggregateEntitiesResponse, error)
+	CountValues(context.Context, *CountValuesRequest) (*CountValuesResponse, error)
 	GetEntity(context.Context, *GetEntityRequest) (*GetEntityResponse, error)
 	WriteFile(context.Context, *WriteFileRequest) (*WriteFileResponse, error)
 	ReadFile(context.Context, *ReadFileRequest) (*ReadFileResponse, error)
 	DeleteFile(context.Context, *DeleteFileRequest) (*DeleteFileResponse, error)
+	Batch(context.Context, *BatchRequest) (*BatchResponse, error)
+	WatchChanges(*WatchChangesRequest, QMetadataService_WatchChangesServer) error
 	GetDatabaseMetadata(context.Context, *GetDatabaseMetadataRequest) (*GetDatabaseMetadataResponse, error)
+	ListFileRevisions(context.Context, *ListFileRevisionsRequest) (*ListFileRevisionsResponse, error)
+	ReadFileRevision(context.Context, *ReadFileRevisionRequest) (*ReadFileRevisionResponse, error)
+	Compact(context.Context, *CompactRequest) (*CompactResponse, error)
+	PullChanges(context.Context, *PullChangesRequest) (*PullChangesResponse, error)
+	PushChanges(context.Context, *PushChangesRequest) (*PushChangesResponse, error)
 }
 
 func RegisterQMetadataServiceServer(s *grpc.Server, srv QMetadataServiceServer) {
@@ -2095,6 +4294,42 @@ func (x *qMetadataServiceQueryEntitiesServer) Send(m *QueryEntitiesResponse) err
 	return x.ServerStream.SendMsg(m)
 }
 
+func _QMetadataService_AggregateEntities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregateEntitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QMetadataServiceServer).AggregateEntities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/qmfspb.QMetadataService/AggregateEntities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QMetadataServiceServer).AggregateEntities(ctx, req.(*AggregateEntitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QMetadataService_CountValues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountValuesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QMetadataServiceServer).CountValues(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/qmfspb.QMetadataService/CountValues",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QMetadataServiceServer).CountValues(ctx, req.(*CountValuesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _QMetadataService_GetEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetEntityRequest)
 	if err := dec(in); err != nil {
@@ -2167,6 +4402,45 @@ func _QMetadataService_DeleteFile_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _QMetadataService_Batch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QMetadataServiceServer).Batch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/qmfspb.QMetadataService/Batch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QMetadataServiceServer).Batch(ctx, req.(*BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QMetadataService_WatchChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchChangesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QMetadataServiceServer).WatchChanges(m, &qMetadataServiceWatchChangesServer{stream})
+}
+
+type QMetadataService_WatchChangesServer interface {
+	Send(*ChangeEvent) error
+	grpc.ServerStream
+}
+
+type qMetadataServiceWatchChangesServer struct {
+	grpc.ServerStream
+}
+
+func (x *qMetadataServiceWatchChangesServer) Send(m *ChangeEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _QMetadataService_GetDatabaseMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetDatabaseMetadataRequest)
 	if err := dec(in); err != nil {
@@ -2185,6 +4459,96 @@ func _QMetadataService_GetDatabaseMetadata_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _QMetadataService_ListFileRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFileRevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QMetadataServiceServer).ListFileRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/qmfspb.QMetadataService/ListFileRevisions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QMetadataServiceServer).ListFileRevisions(ctx, req.(*ListFileRevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QMetadataService_ReadFileRevision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadFileRevisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QMetadataServiceServer).ReadFileRevision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/qmfspb.QMetadataService/ReadFileRevision",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QMetadataServiceServer).ReadFileRevision(ctx, req.(*ReadFileRevisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QMetadataService_Compact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompactRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QMetadataServiceServer).Compact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/qmfspb.QMetadataService/Compact",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QMetadataServiceServer).Compact(ctx, req.(*CompactRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QMetadataService_PullChanges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PullChangesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QMetadataServiceServer).PullChanges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/qmfspb.QMetadataService/PullChanges",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QMetadataServiceServer).PullChanges(ctx, req.(*PullChangesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QMetadataService_PushChanges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PushChangesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QMetadataServiceServer).PushChanges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/qmfspb.QMetadataService/PushChanges",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QMetadataServiceServer).PushChanges(ctx, req.(*PushChangesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _QMetadataService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "qmfspb.QMetadataService",
 	HandlerType: (*QMetadataServiceServer)(nil),
@@ -2193,6 +4557,14 @@ var _QMetadataService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ListNamespaces",
 			Handler:    _QMetadataService_ListNamespaces_Handler,
 		},
+		{
+			MethodName: "AggregateEntities",
+			Handler:    _QMetadataService_AggregateEntities_Handler,
+		},
+		{
+			MethodName: "CountValues",
+			Handler:    _QMetadataService_CountValues_Handler,
+		},
 		{
 			MethodName: "GetEntity",
 			Handler:    _QMetadataService_GetEntity_Handler,
@@ -2209,10 +4581,34 @@ var _QMetadataService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteFile",
 			Handler:    _QMetadataService_DeleteFile_Handler,
 		},
+		{
+			MethodName: "Batch",
+			Handler:    _QMetadataService_Batch_Handler,
+		},
 		{
 			MethodName: "GetDatabaseMetadata",
 			Handler:    _QMetadataService_GetDatabaseMetadata_Handler,
 		},
+		{
+			MethodName: "ListFileRevisions",
+			Handler:    _QMetadataService_ListFileRevisions_Handler,
+		},
+		{
+			MethodName: "ReadFileRevision",
+			Handler:    _QMetadataService_ReadFileRevision_Handler,
+		},
+		{
+			MethodName: "Compact",
+			Handler:    _QMetadataService_Compact_Handler,
+		},
+		{
+			MethodName: "PullChanges",
+			Handler:    _QMetadataService_PullChanges_Handler,
+		},
+		{
+			MethodName: "PushChanges",
+			Handler:    _QMetadataService_PushChanges_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -2220,6 +4616,11 @@ var _QMetadataService_serviceDesc = grpc.ServiceDesc{
 			Handler:       _QMetadataService_QueryEntities_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "WatchChanges",
+			Handler:       _QMetadataService_WatchChanges_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "qmfs.proto",
 }
@@ -3,6 +3,7 @@ package atomicfilefuse
 import (
 	"bytes"
 	"context"
+	"sort"
 	"sync"
 
 	"bazil.org/fuse"
@@ -309,6 +310,95 @@ type File struct {
 	GetAttr     func(ctx context.Context, a *fuse.Attr) (bool, error)
 	AtomicRead  func(ctx context.Context) ([]byte, string, bool, error)
 	AtomicWrite func(ctx context.Context, data []byte, revision string) (string, error)
+
+	// DirectIO bypasses the page cache, so that reads are not limited to
+	// the size reported by GetAttr. This suits files whose size is only
+	// known once their contents have been produced.
+	DirectIO bool
+
+	// Optional extended attribute support. SetXattr is called with a nil
+	// value when an attribute is removed.
+	GetXattrs func(ctx context.Context) (map[string][]byte, error)
+	SetXattr  func(ctx context.Context, name string, value []byte) error
+}
+
+var getxattrSec = sectiontrace.New("atomicfilefuse.Getxattr")
+
+func (f *File) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
+	if f.GetXattrs == nil {
+		return fuse.ErrNoXattr
+	}
+
+	return getxattrSec.Do(ctx, func(ctx context.Context) error {
+		xattrs, err := f.GetXattrs(ctx)
+		if err != nil {
+			return err
+		}
+
+		value, ok := xattrs[req.Name]
+		if !ok {
+			return fuse.ErrNoXattr
+		}
+
+		resp.Xattr = value
+		return nil
+	})
+}
+
+var listxattrSec = sectiontrace.New("atomicfilefuse.Listxattr")
+
+func (f *File) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) error {
+	if f.GetXattrs == nil {
+		return nil
+	}
+
+	return listxattrSec.Do(ctx, func(ctx context.Context) error {
+		xattrs, err := f.GetXattrs(ctx)
+		if err != nil {
+			return err
+		}
+
+		var names []string
+		for name := range xattrs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		resp.Append(names...)
+		return nil
+	})
+}
+
+var setxattrSec = sectiontrace.New("atomicfilefuse.Setxattr")
+
+func (f *File) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
+	logrus.WithFields(f.Fields).Infof("Setxattr(name=%q)", req.Name)
+
+	if f.SetXattr == nil {
+		return fuse.ENOTSUP
+	}
+
+	return setxattrSec.Do(ctx, func(ctx context.Context) error {
+		value := req.Xattr
+		if value == nil {
+			value = []byte{}
+		}
+		return f.SetXattr(ctx, req.Name, value)
+	})
+}
+
+var removexattrSec = sectiontrace.New("atomicfilefuse.Removexattr")
+
+func (f *File) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) error {
+	logrus.WithFields(f.Fields).Infof("Removexattr(name=%q)", req.Name)
+
+	if f.SetXattr == nil {
+		return fuse.ErrNoXattr
+	}
+
+	return removexattrSec.Do(ctx, func(ctx context.Context) error {
+		return f.SetXattr(ctx, req.Name, nil)
+	})
 }
 
 var attrSec = sectiontrace.New("atomicfilefuse.Attr")
@@ -356,6 +446,9 @@ func (f *File) open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenR
 		trueTruncate: req.Flags&fuse.OpenTruncate != 0,
 		file:         f,
 	}
+	if f.DirectIO {
+		resp.Flags |= fuse.OpenDirectIO
+	}
 	f.state.AddRef(rv)
 	return rv, nil
 }
@@ -20,6 +20,7 @@ type DynamicDir struct {
 	CacheSize int
 	List      func(context.Context, func(string, fuse.DirentType)) error
 	Get       func(context.Context, string) (fs.Node, fuse.DirentType, bool, error)
+	GetAttr   func(context.Context, *fuse.Attr) error
 	Delete    func(context.Context, string, bool) error
 	CreateDir func(context.Context, string) error
 
@@ -156,6 +157,9 @@ func (d *DynamicDir) Attr(ctx context.Context, a *fuse.Attr) error {
 
 	return attrSec.Do(ctx, func(ctx context.Context) error {
 		a.Mode = os.ModeDir | 0755
+		if d.GetAttr != nil {
+			return d.GetAttr(ctx, a)
+		}
 		return nil
 	})
 }
@@ -177,6 +181,18 @@ func (d *DynamicDir) getCache() (*lru.Cache, error) {
 	return d.nodecache, nil
 }
 
+// Invalidate drops any cached node for the named entry, so that the next
+// lookup calls Get again.
+func (d *DynamicDir) Invalidate(name string) {
+	d.cachemu.Lock()
+	cache := d.nodecache
+	d.cachemu.Unlock()
+
+	if cache != nil {
+		cache.Remove(name)
+	}
+}
+
 type cacheableEntry struct {
 	node     fs.Node
 	fusetype fuse.DirentType
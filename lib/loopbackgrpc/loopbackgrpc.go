@@ -10,6 +10,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 type Params struct {
@@ -18,6 +19,8 @@ type Params struct {
 	AddressGRPC        string
 	ServerCertPEM      []byte
 	ClientCertificates []tls.Certificate
+	// If set, connect over this Unix domain socket instead, without TLS.
+	SocketPath string
 }
 
 func Dial(ctx context.Context, params Params) (*grpc.ClientConn, error) {
@@ -27,6 +30,14 @@ func Dial(ctx context.Context, params Params) (*grpc.ClientConn, error) {
 		defer cancel()
 	}
 
+	if params.SocketPath != "" {
+		return grpc.DialContext(
+			ctx,
+			"unix:"+params.SocketPath,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+	}
+
 	addr := params.AddressGRPC
 	if params.Hostname != "" {
 		addr = fmt.Sprintf("%s:%s", params.Hostname, strings.Split(addr, ":")[1])
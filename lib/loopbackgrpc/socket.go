@@ -0,0 +1,91 @@
+package loopbackgrpc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// peerCheckingListener only accepts connections from processes running as
+// the same user as this one.
+type peerCheckingListener struct {
+	net.Listener
+}
+
+func (l *peerCheckingListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkPeer(conn); err != nil {
+			logrus.Warningf("Rejecting connection on %q: %v", l.Addr(), err)
+			conn.Close()
+			continue
+		}
+
+		return conn, nil
+	}
+}
+
+// ListenSocket listens on a Unix domain socket at path. The socket is only
+// accessible to the current user, both by its permissions and by checking
+// the credentials of each connecting peer.
+func ListenSocket(path string) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	// The socket is created with permissions from the umask, so restrict
+	// it while listening rather than leave it open until the chmod below.
+	// The umask is process-wide, but files created concurrently can only
+	// end up more restricted than intended, and only for this moment.
+	oldUmask := syscall.Umask(0077)
+	lis, err := net.Listen("unix", path)
+	syscall.Umask(oldUmask)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		lis.Close()
+		return nil, err
+	}
+
+	return &peerCheckingListener{lis}, nil
+}
+
+// removeStaleSocket removes what is left at path by a server that is no
+// longer running. Anything else at path, including the socket of a server
+// that is still running, is left alone and reported as an error.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("Refusing to replace %q: not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("Refusing to replace %q: socket is in use", path)
+	}
+
+	logrus.Infof("Removing stale socket %q", path)
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -20,6 +20,12 @@ func String(cb func(context.Context) (string, error)) *File {
 	}
 }
 
+func Bytes(cb func(context.Context) ([]byte, error)) *File {
+	return &File{
+		contents: cb,
+	}
+}
+
 type File struct {
 	contents func(ctx context.Context) ([]byte, error)
 }
@@ -0,0 +1,231 @@
+package qmfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"bazil.org/fuse"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+// ConflictPolicy decides what happens to a write made against a revision
+// of a file that has since been replaced by another writer.
+type ConflictPolicy int
+
+const (
+	// ConflictFail rejects the write with ESTALE.
+	ConflictFail ConflictPolicy = iota
+	// ConflictSaveSibling saves the rejected contents next to the file, as
+	// <file>.conflict-<row_guid> after the revision the write was based on
+	// (with a numbered suffix if that is taken), and reports the write as
+	// successful.
+	ConflictSaveSibling
+)
+
+var conflictPolicyNames = map[string]ConflictPolicy{
+	"fail": ConflictFail,
+	"save": ConflictSaveSibling,
+}
+
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	policy, ok := conflictPolicyNames[name]
+	if !ok {
+		return ConflictFail, fmt.Errorf("unknown conflict policy %q (want \"fail\" or \"save\")", name)
+	}
+	return policy, nil
+}
+
+const maxConflictRecords = 1000
+
+type conflictRecord struct {
+	Time             string `json:"time"`
+	Namespace        string `json:"namespace,omitempty"`
+	EntityID         string `json:"entity_id"`
+	Filename         string `json:"filename"`
+	ExpectedRevision string `json:"expected_revision"`
+	SavedAs          string `json:"saved_as,omitempty"`
+}
+
+// conflictLog keeps the most recent conflicts, for service/conflicts.
+type conflictLog struct {
+	mu      sync.Mutex
+	records [][]byte
+	// Number of records dropped from the front of records.
+	dropped int
+	changed chan struct{}
+}
+
+func (l *conflictLog) add(rec *conflictRecord) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		logrus.Errorf("Failed to record conflict: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, append(line, '\n'))
+	if excess := len(l.records) - maxConflictRecords; excess > 0 {
+		l.records = l.records[excess:]
+		l.dropped += excess
+	}
+
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+}
+
+// since returns the records after the first n ever added, the total number
+// of records added, and a channel closed on the next addition.
+func (l *conflictLog) since(n int) ([][]byte, int, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.changed == nil {
+		l.changed = make(chan struct{})
+	}
+
+	start := n - l.dropped
+	if start < 0 {
+		start = 0
+	}
+
+	return l.records[start:], l.dropped + len(l.records), l.changed
+}
+
+// follow writes all retained records, then new ones as they are added,
+// until the context is done or the reader goes away.
+func (l *conflictLog) follow(ctx context.Context, w io.Writer) error {
+	var n int
+
+	for {
+		records, total, changed := l.since(n)
+		for _, line := range records {
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+		}
+		n = total
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+	}
+}
+
+// isConflict reports whether err rejects a write based on a revision that
+// is no longer current.
+func isConflict(err error) bool {
+	if status.Code(err) != codes.FailedPrecondition {
+		return false
+	}
+	for _, detail := range status.Convert(err).Proto().GetDetails() {
+		if ptypes.Is(detail, &pb.RevisionConflict{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// conflictError reports a conflict detected here rather than by the server,
+// in the same form.
+func conflictError(expected, current string, format string, args ...interface{}) error {
+	st := status.Newf(codes.FailedPrecondition, "Conflict: "+format, args...)
+	withDetails, err := st.WithDetails(&pb.RevisionConflict{
+		ExpectedRowGuid: expected,
+		CurrentRowGuid:  current,
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+const maxConflictSiblings = 1000
+
+func conflictSiblingName(filename, rev string, n int) string {
+	if n <= 1 {
+		return fmt.Sprintf("%s.conflict-%s", filename, rev)
+	}
+	return fmt.Sprintf("%s.conflict-%s-%d", filename, rev, n)
+}
+
+// freeConflictSiblingName returns the first name for a sibling of filename
+// holding a write based on rev that is not already taken.
+func freeConflictSiblingName(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, filename, rev string) (string, error) {
+	for n := 1; n <= maxConflictSiblings; n++ {
+		sibling := conflictSiblingName(filename, rev, n)
+
+		entry, err := performReadOf(ctx, client, namespace, entityID, sibling)
+		if err != nil {
+			return "", err
+		}
+		if !entry.exists {
+			return sibling, nil
+		}
+	}
+	return "", fmt.Errorf("too many conflicting writes of %q based on revision %q", filename, rev)
+}
+
+// handleConflict applies the conflict policy to a write of data based on
+// revision rev, which failed because the file has changed since.
+func handleConflict(ctx context.Context, client pb.QMetadataServiceClient, opts *fsOptions, namespace, entityID, filename string, data []byte, rev string, writeErr error) (string, error) {
+	invalidateFileCacheFor(namespace, entityID, filename)
+
+	rec := &conflictRecord{
+		Time:             time.Now().UTC().Format(time.RFC3339Nano),
+		Namespace:        namespace,
+		EntityID:         entityID,
+		Filename:         filename,
+		ExpectedRevision: rev,
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"namespace": namespace,
+		"entity_id": entityID,
+		"filename":  filename,
+		"revision":  rev,
+	})
+
+	if opts.conflictPolicy != ConflictSaveSibling || rev == "" {
+		logger.Warningf("Rejecting conflicting write: %v", writeErr)
+		opts.conflicts.add(rec)
+		return "", fuse.ESTALE
+	}
+
+	// Every rejected write gets a sibling of its own, so that a further
+	// conflicting write through the same handle does not replace it.
+	sibling, err := freeConflictSiblingName(ctx, client, namespace, entityID, filename, rev)
+	if err != nil {
+		logger.Errorf("Failed to choose a name for conflicting write: %v", err)
+		opts.conflicts.add(rec)
+		return "", fuse.ESTALE
+	}
+
+	if _, err := writeFileOrDir(ctx, client, namespace, entityID, sibling, data, "", false); err != nil {
+		logger.Errorf("Failed to save conflicting write as %q: %v", sibling, err)
+		opts.conflicts.add(rec)
+		return "", fuse.ESTALE
+	}
+
+	logger.Warningf("Saved conflicting write as %q", sibling)
+	rec.SavedAs = sibling
+	opts.conflicts.add(rec)
+
+	// The handle stays based on the revision it read, so that a further
+	// write through it is detected as a conflict too.
+	return rev, nil
+}
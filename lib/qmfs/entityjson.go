@@ -0,0 +1,382 @@
+package qmfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"bazil.org/fuse"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+	"github.com/steinarvk/qmfs/lib/atomicfilefuse"
+	"github.com/steinarvk/qmfs/lib/qmfsquery"
+)
+
+const defaultEntityJSONFilename = ".entity.json"
+
+// relativeEntityPath returns the path relative to parentdir, if the path is
+// within it.
+func relativeEntityPath(parentdir, path string) (string, bool) {
+	if parentdir == "" {
+		return path, true
+	}
+	if !strings.HasPrefix(path, parentdir+"/") {
+		return "", false
+	}
+	return path[len(parentdir)+1:], true
+}
+
+// getEntitySubtree returns the headers of all files and directories within
+// parentdir, keyed by their full path.
+func getEntitySubtree(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, parentdir string) (map[string]*pb.EntityFileHeader, error) {
+	rv := map[string]*pb.EntityFileHeader{}
+
+	resp, err := client.GetEntity(ctx, &pb.GetEntityRequest{
+		Namespace: namespace,
+		EntityId:  entityID,
+	})
+	if status.Code(err) == codes.NotFound {
+		return rv, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for path, hdr := range resp.GetEntity().GetFiles() {
+		if _, ok := relativeEntityPath(parentdir, path); ok {
+			rv[path] = hdr
+		}
+	}
+
+	return rv, nil
+}
+
+// subtreeRevision identifies the state of a subtree, so that a write can
+// detect whether anything changed since the subtree was read.
+func subtreeRevision(headers map[string]*pb.EntityFileHeader) string {
+	var paths []string
+	for path := range headers {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(h, "%s\x00%s\n", path, headers[path].GetRowGuid())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// maxEntityJSONReadAttempts bounds how often rendering retries when the
+// subtree keeps changing between reading its headers and its contents.
+const maxEntityJSONReadAttempts = 3
+
+func renderEntityJSON(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, parentdir string) ([]byte, string, error) {
+	for attempt := 1; attempt <= maxEntityJSONReadAttempts; attempt++ {
+		data, rev, consistent, err := tryRenderEntityJSON(ctx, client, namespace, entityID, parentdir)
+		if err != nil {
+			return nil, "", err
+		}
+		if consistent {
+			return data, rev, nil
+		}
+		logrus.WithFields(logrus.Fields{
+			"namespace": namespace,
+			"entity_id": entityID,
+			"attempt":   attempt,
+		}).Warningf("Entity changed while rendering entity JSON; retrying")
+	}
+	return nil, "", status.Errorf(codes.Aborted, "entity %q kept changing while it was read", entityID)
+}
+
+// tryRenderEntityJSON renders the subtree from its headers and a separate
+// read of its contents, reporting whether the contents read were those of
+// the revisions in the headers.
+func tryRenderEntityJSON(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, parentdir string) ([]byte, string, bool, error) {
+	headers, err := getEntitySubtree(ctx, client, namespace, entityID, parentdir)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	var filenames []string
+	for path, hdr := range headers {
+		if !hdr.GetDirectory() {
+			filenames = append(filenames, path)
+		}
+	}
+
+	contents := map[string][]byte{}
+	consistent := true
+
+	if len(filenames) > 0 {
+		if err := queryFilesLister(client, &pb.QueryEntitiesRequest{
+			Namespace: namespace,
+			Kind: &pb.QueryEntitiesRequest_ParsedQuery{
+				ParsedQuery: &pb.EntitiesQuery{
+					Clause: []*pb.EntitiesQuery_Clause{
+						qmfsquery.EntityIDEquals(entityID),
+					},
+				},
+			},
+		})(ctx, filenames, func(resp *pb.QueryEntitiesResponse) error {
+			for _, file := range resp.GetFile() {
+				path := file.GetHeader().GetFilename()
+				if file.GetHeader().GetRowGuid() != headers[path].GetRowGuid() {
+					consistent = false
+				}
+				contents[path] = file.GetData()
+			}
+			return nil
+		}); err != nil {
+			return nil, "", false, err
+		}
+
+		if len(contents) != len(filenames) {
+			consistent = false
+		}
+	}
+
+	if !consistent {
+		return nil, "", false, nil
+	}
+
+	root := map[string]interface{}{}
+
+	for path, hdr := range headers {
+		rel, _ := relativeEntityPath(parentdir, path)
+		components := strings.Split(rel, "/")
+
+		node := root
+		for _, component := range components[:len(components)-1] {
+			child, ok := node[component].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				node[component] = child
+			}
+			node = child
+		}
+
+		leaf := components[len(components)-1]
+		if hdr.GetDirectory() {
+			if _, ok := node[leaf].(map[string]interface{}); !ok {
+				node[leaf] = map[string]interface{}{}
+			}
+		} else {
+			node[leaf] = string(contents[path])
+		}
+	}
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	return append(data, '\n'), subtreeRevision(headers), true, nil
+}
+
+type entityJSONEntry struct {
+	directory bool
+	data      []byte
+}
+
+func flattenEntityJSON(obj map[string]interface{}, parentdir string, opts *fsOptions, out map[string]*entityJSONEntry) error {
+	for key, value := range obj {
+		if !qmfsquery.ValidFilename(key) || key == historyDirName || key == opts.entityJSONFilename || opts.isFilenameBad(key) {
+			return status.Errorf(codes.InvalidArgument, "invalid filename: %q", key)
+		}
+
+		path := joinEntityPath(parentdir, key)
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			out[path] = &entityJSONEntry{directory: true}
+			if err := flattenEntityJSON(v, path, opts, out); err != nil {
+				return err
+			}
+
+		case string:
+			out[path] = &entityJSONEntry{data: []byte(v)}
+
+		case json.Number:
+			out[path] = &entityJSONEntry{data: []byte(v.String())}
+
+		case bool:
+			out[path] = &entityJSONEntry{data: []byte(fmt.Sprintf("%v", v))}
+
+		default:
+			return status.Errorf(codes.InvalidArgument, "unsupported value for %q: must be a string, number, boolean or object", path)
+		}
+	}
+
+	return nil
+}
+
+func writeEntityJSON(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, parentdir string, data []byte, rev string, opts *fsOptions) (string, error) {
+	var obj map[string]interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid JSON: %v", err)
+	}
+
+	desired := map[string]*entityJSONEntry{}
+	if err := flattenEntityJSON(obj, parentdir, opts, desired); err != nil {
+		return "", err
+	}
+
+	current, err := getEntitySubtree(ctx, client, namespace, entityID, parentdir)
+	if err != nil {
+		return "", err
+	}
+
+	if currentRev := subtreeRevision(current); rev != "" && rev != currentRev {
+		return "", conflictError(rev, currentRev, "entity %q changed since it was read", entityID)
+	}
+
+	authorship := &pb.AuthorshipMetadata{}
+	if qmfsVersioninfoJSON != "" {
+		authorship.QmfsVersioninfoJson = qmfsVersioninfoJSON
+	}
+
+	var ops []*pb.BatchOperation
+
+	// Deletions go deepest first, so that directories are emptied before
+	// they are removed.
+	var currentPaths []string
+	for path := range current {
+		currentPaths = append(currentPaths, path)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(currentPaths)))
+
+	for _, path := range currentPaths {
+		hdr := current[path]
+		if entry, ok := desired[path]; ok && entry.directory == hdr.GetDirectory() {
+			continue
+		}
+
+		deltype := pb.DeletionType_DELETE_FILE
+		if hdr.GetDirectory() {
+			deltype = pb.DeletionType_DELETE_DIR
+		}
+
+		ops = append(ops, &pb.BatchOperation{
+			Kind: &pb.BatchOperation_Delete{
+				Delete: &pb.DeleteFileRequest{
+					Namespace:          namespace,
+					EntityId:           entityID,
+					Filename:           path,
+					OldRevisionGuid:    hdr.GetRowGuid(),
+					AuthorshipMetadata: authorship,
+					DeletionType:       deltype,
+				},
+			},
+		})
+		delete(current, path)
+	}
+
+	// Writes go shallowest first, so that directories are created before
+	// their contents.
+	var desiredPaths []string
+	for path := range desired {
+		desiredPaths = append(desiredPaths, path)
+	}
+	sort.Strings(desiredPaths)
+
+	for _, path := range desiredPaths {
+		entry := desired[path]
+		hdr, exists := current[path]
+
+		if entry.directory && exists {
+			continue
+		}
+
+		ops = append(ops, &pb.BatchOperation{
+			Kind: &pb.BatchOperation_Write{
+				Write: &pb.WriteFileRequest{
+					Namespace:          namespace,
+					EntityId:           entityID,
+					Filename:           path,
+					Data:               entry.data,
+					OldRevisionGuid:    hdr.GetRowGuid(),
+					AuthorshipMetadata: authorship,
+					Directory:          entry.directory,
+				},
+			},
+		})
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"namespace":  namespace,
+		"entity_id":  entityID,
+		"subdir":     parentdir,
+		"operations": len(ops),
+	}).Infof("Writing entity JSON")
+
+	if len(ops) > 0 {
+		_, err := client.Batch(ctx, &pb.BatchRequest{
+			Operation: ops,
+		})
+
+		for _, op := range ops {
+			path := op.GetWrite().GetFilename()
+			if path == "" {
+				path = op.GetDelete().GetFilename()
+			}
+			invalidateFileCacheFor(namespace, entityID, path)
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	_, newRev, err := renderEntityJSON(ctx, client, namespace, entityID, parentdir)
+	return newRev, err
+}
+
+func getEntityJSONNode(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, parentdir string, opts *fsOptions) *atomicfilefuse.File {
+	// The size is only known once the subtree has been rendered, which is
+	// too costly for every stat; reads bypass the page cache instead, so
+	// that they are not cut short by the size reported here.
+	f := &atomicfilefuse.File{
+		Fields: map[string]interface{}{
+			"namespace": namespace,
+			"entity_id": entityID,
+			"filename":  joinEntityPath(parentdir, opts.entityJSONFilename),
+		},
+		DirectIO: true,
+	}
+
+	f.GetAttr = func(ctx context.Context, a *fuse.Attr) (bool, error) {
+		if a != nil {
+			a.Valid = 0
+			a.Mode = 0660
+		}
+		return true, nil
+	}
+	f.AtomicRead = func(ctx context.Context) ([]byte, string, bool, error) {
+		data, rev, err := renderEntityJSON(ctx, client, namespace, entityID, parentdir)
+		if err != nil {
+			return nil, "", false, err
+		}
+		return data, rev, true, nil
+	}
+	f.AtomicWrite = func(ctx context.Context, data []byte, rev string) (string, error) {
+		newRev, err := writeEntityJSON(ctx, client, namespace, entityID, parentdir, data, rev, opts)
+		if isConflict(err) {
+			logrus.Warningf("Rejecting conflicting write of entity JSON for %q: %v", entityID, err)
+			return "", fuse.ESTALE
+		}
+		return newRev, invalidWriteErrno(err)
+	}
+	return f
+}
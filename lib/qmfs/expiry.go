@@ -0,0 +1,87 @@
+package qmfs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"bazil.org/fuse"
+	"github.com/sirupsen/logrus"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+// Setting ttlXattr on a file (to a number of seconds, or a duration such as
+// "5m") makes it expire that long from now; removing it cancels the expiry.
+// Any ordinary write to the file also cancels it.
+const (
+	ttlXattr       = xattrPrefix + "ttl"
+	expiresAtXattr = xattrPrefix + "expires_at"
+)
+
+func parseTTL(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// setFileExpiry rewrites the current contents of a file with a new expiry,
+// or none if expiresAt is nil.
+func setFileExpiry(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, filename string, expiresAt *pb.Timestamp) error {
+	current, err := performReadOf(ctx, client, namespace, entityID, filename)
+	if err != nil {
+		return err
+	}
+	if !current.exists {
+		return fuse.ENOENT
+	}
+	if current.directory {
+		return fuse.Errno(syscall.EISDIR)
+	}
+
+	authorship := &pb.AuthorshipMetadata{}
+	if qmfsVersioninfoJSON != "" {
+		authorship.QmfsVersioninfoJson = qmfsVersioninfoJSON
+	}
+
+	_, err = client.WriteFile(ctx, &pb.WriteFileRequest{
+		Namespace:          namespace,
+		EntityId:           entityID,
+		Filename:           filename,
+		Data:               current.data,
+		OldRevisionGuid:    current.rowGUID,
+		AuthorshipMetadata: authorship,
+		ExpiresAt:          expiresAt,
+	})
+
+	invalidateFileCacheFor(namespace, entityID, filename)
+
+	if isConflict(err) {
+		logrus.Warningf("File %q in entity %q changed while setting its expiry: %v", filename, entityID, err)
+		return fuse.ESTALE
+	}
+
+	return invalidWriteErrno(err)
+}
+
+func setFileTTL(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, filename string, value []byte) error {
+	if value == nil {
+		return setFileExpiry(ctx, client, namespace, entityID, filename, nil)
+	}
+
+	ttl, err := parseTTL(string(value))
+	if err != nil || ttl <= 0 {
+		logrus.Warningf("Invalid TTL %q for %q in entity %q", value, filename, entityID)
+		return fuse.Errno(syscall.EINVAL)
+	}
+
+	return setFileExpiry(ctx, client, namespace, entityID, filename, &pb.Timestamp{
+		UnixNano: time.Now().Add(ttl).UnixNano(),
+	})
+}
@@ -0,0 +1,180 @@
+package qmfs
+
+import (
+	"context"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/sirupsen/logrus"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+	"github.com/steinarvk/qmfs/lib/dyndirfuse"
+)
+
+const (
+	// The same file may be reachable through several paths (shards, queries,
+	// links), each with its own node.
+	maxLiveNodesPerKey = 16
+
+	watchRetryInterval = time.Second
+)
+
+var (
+	// Nodes handed out for entity files and directories, so that the kernel
+	// can be told to drop its caches of them when the database changes.
+	// Directories are keyed by their own path within the entity.
+	liveNodesMu   sync.Mutex
+	liveFileNodes *lru.Cache
+	liveDirNodes  *lru.Cache
+)
+
+func init() {
+	fileNodes, err := lru.New(10000)
+	if err != nil {
+		logrus.Fatalf("Failed to create live file node cache: %v", err)
+	}
+	liveFileNodes = fileNodes
+
+	dirNodes, err := lru.New(10000)
+	if err != nil {
+		logrus.Fatalf("Failed to create live directory node cache: %v", err)
+	}
+	liveDirNodes = dirNodes
+}
+
+func registerLiveNode(cache *lru.Cache, key fileCacheKey, node fs.Node) {
+	liveNodesMu.Lock()
+	defer liveNodesMu.Unlock()
+
+	var nodes []fs.Node
+	if existing, ok := cache.Get(key); ok {
+		nodes = existing.([]fs.Node)
+	}
+
+	nodes = append(nodes, node)
+	if len(nodes) > maxLiveNodesPerKey {
+		nodes = nodes[len(nodes)-maxLiveNodesPerKey:]
+	}
+
+	cache.Add(key, nodes)
+}
+
+func liveNodesFor(cache *lru.Cache, key fileCacheKey) []fs.Node {
+	liveNodesMu.Lock()
+	defer liveNodesMu.Unlock()
+
+	if existing, ok := cache.Get(key); ok {
+		return existing.([]fs.Node)
+	}
+	return nil
+}
+
+func ignoreNotCached(err error) error {
+	if err == fuse.ErrNotCached {
+		return nil
+	}
+	return err
+}
+
+// invalidateForChange drops every cache, ours and the kernel's, that may hold
+// stale data after the file in the header changed.
+func invalidateForChange(srv *fs.Server, hdr *pb.EntityFileHeader) {
+	namespace := hdr.GetNamespace()
+	entityID := hdr.GetEntityId()
+	filename := hdr.GetFilename()
+
+	invalidateFileCacheFor(namespace, entityID, filename)
+
+	fileKey := fileCacheKey{namespace: namespace, entityID: entityID, filename: filename}
+
+	for _, node := range liveNodesFor(liveFileNodes, fileKey) {
+		if err := ignoreNotCached(srv.InvalidateNodeData(node)); err != nil {
+			logrus.Warningf("Failed to invalidate data of %q in entity %q: %v", filename, entityID, err)
+		}
+		if err := ignoreNotCached(srv.InvalidateNodeAttr(node)); err != nil {
+			logrus.Warningf("Failed to invalidate attributes of %q in entity %q: %v", filename, entityID, err)
+		}
+	}
+
+	parentdir := path.Dir(filename)
+	if parentdir == "." {
+		parentdir = ""
+	}
+	name := path.Base(filename)
+
+	parentKey := fileCacheKey{namespace: namespace, entityID: entityID, filename: parentdir}
+
+	for _, node := range liveNodesFor(liveDirNodes, parentKey) {
+		if dir, ok := node.(*dyndirfuse.DynamicDir); ok {
+			dir.Invalidate(name)
+		}
+		if err := ignoreNotCached(srv.InvalidateEntry(node, name)); err != nil {
+			logrus.Warningf("Failed to invalidate entry %q in entity %q: %v", filename, entityID, err)
+		}
+		if err := ignoreNotCached(srv.InvalidateNodeAttr(node)); err != nil {
+			logrus.Warningf("Failed to invalidate attributes of directory %q in entity %q: %v", parentdir, entityID, err)
+		}
+	}
+}
+
+// invalidateOnChanges follows the change feed until the context is done,
+// resuming where it left off if the feed is interrupted.
+func invalidateOnChanges(ctx context.Context, client pb.QMetadataServiceClient, srv *fs.Server) {
+	req := &pb.WatchChangesRequest{
+		AllNamespaces: true,
+		FromNow:       true,
+	}
+
+	for {
+		err := func() error {
+			stream, err := client.WatchChanges(ctx, req)
+			if err != nil {
+				return err
+			}
+
+			for {
+				event, err := stream.Recv()
+				if err != nil {
+					return err
+				}
+
+				invalidateForChange(srv, event.GetHeader())
+
+				req.FromNow = false
+				req.AfterSequence = event.GetSequence()
+			}
+		}()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != io.EOF {
+			logrus.Warningf("Change feed interrupted; retrying: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+// Serve serves the filesystem on the FUSE connection, keeping the kernel's
+// caches in sync with changes to the database made by any writer.
+func (f *Filesystem) Serve(ctx context.Context, conn *fuse.Conn) error {
+	srv := fs.New(conn, nil)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go invalidateOnChanges(ctx, f.client, srv)
+
+	return srv.Serve(f)
+}
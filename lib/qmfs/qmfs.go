@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -13,8 +14,11 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"bazil.org/fuse"
@@ -41,16 +45,43 @@ import (
 var qmfsVersioninfoJSON string
 
 type ServiceData struct {
-	Hostname             string
-	DatabasePath         string
-	AddressGRPC          string
-	AddressHTTP          string
-	ServerCertPEM        []byte
-	ClientCertificate    *tls.Certificate
+	Hostname     string
+	DatabasePath string
+	AddressGRPC  string
+	AddressHTTP  string
+	SocketPath   string
+	// The certificates are looked up on every read, since they may be
+	// rotated while mounted.
+	ServerCertPEM        func() ([]byte, error)
+	ClientCertificate    func() (*tls.Certificate, error)
 	ForbiddenFilenameREs []string
 }
 
-func newServiceTree(ctx context.Context, svcdata ServiceData, client pb.QMetadataServiceClient, goodbyeChan chan<- error) (fs.Node, error) {
+func clientCertificatePEM(getCert func() (*tls.Certificate, error)) ([]byte, []byte, error) {
+	cert, err := getCert()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var certBuf bytes.Buffer
+	if err := pem.Encode(&certBuf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}); err != nil {
+		return nil, nil, err
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var keyBuf bytes.Buffer
+	if err := pem.Encode(&keyBuf, &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}); err != nil {
+		return nil, nil, err
+	}
+
+	return certBuf.Bytes(), keyBuf.Bytes(), nil
+}
+
+func newServiceTree(ctx context.Context, svcdata ServiceData, client pb.QMetadataServiceClient, opts *fsOptions, goodbyeChan chan<- error) (fs.Node, error) {
 	tree := &fs.Tree{}
 
 	grpcAddr := svcdata.AddressGRPC
@@ -63,38 +94,28 @@ func newServiceTree(ctx context.Context, svcdata ServiceData, client pb.QMetadat
 	if grpcAddr != "" {
 		tree.Add("grpc", staticfuse.String(grpcAddr))
 	}
-	if len(svcdata.ServerCertPEM) > 0 {
-		tree.Add("server_cert.pem", staticfuse.Bytes(svcdata.ServerCertPEM))
+	if svcdata.SocketPath != "" {
+		tree.Add("grpc_socket", staticfuse.String(svcdata.SocketPath))
+	}
+	if svcdata.ServerCertPEM != nil {
+		tree.Add("server_cert.pem", ondemandfuse.Bytes(func(ctx context.Context) ([]byte, error) {
+			return svcdata.ServerCertPEM()
+		}))
 	}
 
 	if svcdata.ClientCertificate != nil {
-		certDER := svcdata.ClientCertificate.Certificate[0]
-		clientKey := svcdata.ClientCertificate.PrivateKey
-
-		var certBuf bytes.Buffer
-		if err := pem.Encode(&certBuf, &pem.Block{Type: "CERTIFICATE", Bytes: certDER}); err != nil {
-			return nil, err
-		}
-
-		leafCertPEM := certBuf.Bytes()
-
-		tree.Add("client_cert.pem", staticfuse.Bytes(leafCertPEM))
-
-		privBytes, err := x509.MarshalPKCS8PrivateKey(clientKey)
-		if err != nil {
-			return nil, err
-		}
-
-		var keyBuf bytes.Buffer
-		if err := pem.Encode(&keyBuf, &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}); err != nil {
-			return nil, err
-		}
-
-		clientKeyPEM := keyBuf.Bytes()
-
-		tree.Add("client_key.pem", staticfuse.Bytes(clientKeyPEM))
+		tree.Add("client_cert.pem", ondemandfuse.Bytes(func(ctx context.Context) ([]byte, error) {
+			certPEM, _, err := clientCertificatePEM(svcdata.ClientCertificate)
+			return certPEM, err
+		}))
+		tree.Add("client_key.pem", ondemandfuse.Bytes(func(ctx context.Context) ([]byte, error) {
+			_, keyPEM, err := clientCertificatePEM(svcdata.ClientCertificate)
+			return keyPEM, err
+		}))
 	}
 
+	tree.Add("conflicts", readstreamfuse.Stream(ctx, opts.conflicts.follow))
+
 	tree.Add("bad_filenames", staticfuse.Bytes(lines.AsBytes(svcdata.ForbiddenFilenameREs)))
 
 	startupTime := time.Now()
@@ -142,15 +163,34 @@ type Params struct {
 	ServiceData
 	Mountpoint   string
 	ShutdownChan chan<- error
+	// Name of the virtual JSON file in every entity directory; defaults to ".entity.json".
+	EntityJSONFilename string
+	// What to do with writes to files changed since they were read; defaults to ConflictFail.
+	ConflictPolicy ConflictPolicy
 }
 
 type Filesystem struct {
 	svc    ServiceData
 	client pb.QMetadataServiceClient
+	opts   *fsOptions
 	root   *fs.Tree
 }
 
-func newNamespaceListNode(client pb.QMetadataServiceClient, mountpoint string, shardKey []byte, contextBG context.Context, isFilenameBad func(string) bool) fs.Node {
+// fsOptions holds the settings of a Filesystem that its nodes depend on.
+type fsOptions struct {
+	isFilenameBad func(string) bool
+
+	// entityJSONFilename is the name of the virtual file present in every
+	// entity directory, exposing the whole directory as a single JSON object.
+	entityJSONFilename string
+
+	conflictPolicy ConflictPolicy
+	conflicts      *conflictLog
+
+	expectedRevisions *expectedRevisions
+}
+
+func newNamespaceListNode(client pb.QMetadataServiceClient, mountpoint string, shardKey []byte, contextBG context.Context, opts *fsOptions, asOf *pb.Timestamp) fs.Node {
 	return &dyndirfuse.DynamicDir{
 		CacheSize: 100,
 		Fields: map[string]interface{}{
@@ -177,7 +217,41 @@ func newNamespaceListNode(client pb.QMetadataServiceClient, mountpoint string, s
 			}
 
 			tree := &fs.Tree{}
-			if err := addRootNodesForNamespace(ctx, client, tree, contextBG, namespaceName, mountpoint, shardKey, isFilenameBad); err != nil {
+			if err := addRootNodesForNamespace(ctx, client, tree, contextBG, namespaceName, mountpoint, shardKey, opts, asOf); err != nil {
+				return nil, fuse.DT_Unknown, false, err
+			}
+			return tree, fuse.DT_Dir, true, nil
+		},
+	}
+}
+
+// newSnapshotListNode returns a directory in which every subdirectory
+// named by a Unix timestamp in nanoseconds is a read-only view of the
+// whole filesystem as it was at that moment.
+func newSnapshotListNode(client pb.QMetadataServiceClient, mountpoint string, shardKey []byte, contextBG context.Context, opts *fsOptions) fs.Node {
+	return &dyndirfuse.DynamicDir{
+		CacheSize: 100,
+		Fields: map[string]interface{}{
+			"dir": "snapshots",
+		},
+		List: func(ctx context.Context, cb func(string, fuse.DirentType)) error {
+			// Every point in time is a valid snapshot; none are listed.
+			return nil
+		},
+		Get: func(ctx context.Context, name string) (fs.Node, fuse.DirentType, bool, error) {
+			unixNano, err := strconv.ParseInt(name, 10, 64)
+			if err != nil || unixNano <= 0 {
+				return nil, fuse.DT_Unknown, false, fuse.ENOENT
+			}
+
+			asOf := &pb.Timestamp{
+				UnixNano: unixNano,
+			}
+			snapshotMountpoint := filepath.Join(mountpoint, "snapshot", name)
+
+			tree := &fs.Tree{}
+			tree.Add("namespace", newNamespaceListNode(client, snapshotMountpoint, shardKey, contextBG, opts, asOf))
+			if err := addRootNodesForNamespace(ctx, client, tree, contextBG, "", snapshotMountpoint, shardKey, opts, asOf); err != nil {
 				return nil, fuse.DT_Unknown, false, err
 			}
 			return tree, fuse.DT_Dir, true, nil
@@ -202,17 +276,34 @@ type fileCacheKey struct {
 }
 
 type fileAttribCacheEntry struct {
-	rowGUID   string
-	length    uint64
-	exists    bool
-	directory bool
+	rowGUID     string
+	length      uint64
+	exists      bool
+	directory   bool
+	lastChanged time.Time
 }
 
 type fileContentsCacheEntry struct {
-	rowGUID   string
-	data      []byte
-	exists    bool
-	directory bool
+	rowGUID     string
+	data        []byte
+	exists      bool
+	directory   bool
+	lastChanged time.Time
+}
+
+func timestampTime(ts *pb.Timestamp) time.Time {
+	return time.Unix(0, ts.GetUnixNano())
+}
+
+// latestChange returns the time of the most recent change among the headers.
+func latestChange(headers map[string]*pb.EntityFileHeader) time.Time {
+	var rv time.Time
+	for _, hdr := range headers {
+		if t := timestampTime(hdr.GetLastChanged()); t.After(rv) {
+			rv = t
+		}
+	}
+	return rv
 }
 
 func init() {
@@ -284,14 +375,16 @@ func performReadOf(ctx context.Context, client pb.QMetadataServiceClient, namesp
 	data := resp.GetFile().GetData()
 	rowGUID := resp.GetFile().GetHeader().GetRowGuid()
 	directory := resp.GetFile().GetHeader().GetDirectory()
+	lastChanged := timestampTime(resp.GetFile().GetHeader().GetLastChanged())
 
-	putIntoCacheAs(cacheKey, data, rowGUID, true, directory)
+	putIntoCacheAs(cacheKey, data, rowGUID, true, directory, lastChanged)
 
 	return &fileContentsCacheEntry{
-		rowGUID:   rowGUID,
-		data:      data,
-		exists:    true,
-		directory: directory,
+		rowGUID:     rowGUID,
+		data:        data,
+		exists:      true,
+		directory:   directory,
+		lastChanged: lastChanged,
 	}, nil
 }
 
@@ -316,10 +409,11 @@ func getFileAttribsOf(ctx context.Context, client pb.QMetadataServiceClient, nam
 		return &fileAttribCacheEntry{}, false, err
 	}
 	return &fileAttribCacheEntry{
-		exists:    contentsEntry.exists,
-		length:    uint64(len(contentsEntry.data)),
-		directory: contentsEntry.directory,
-		rowGUID:   contentsEntry.rowGUID,
+		exists:      contentsEntry.exists,
+		length:      uint64(len(contentsEntry.data)),
+		directory:   contentsEntry.directory,
+		rowGUID:     contentsEntry.rowGUID,
+		lastChanged: contentsEntry.lastChanged,
 	}, contentsEntry.exists, err
 }
 
@@ -342,34 +436,162 @@ func writeFileOrDir(ctx context.Context, client pb.QMetadataServiceClient, names
 
 	if err == nil {
 		rowGUID := resp.GetHeader().GetRowGuid()
+		lastChanged := timestampTime(resp.GetHeader().GetLastChanged())
 
 		cacheKey := fileCacheKey{namespace: namespace, entityID: entityID, filename: filename}
-		putIntoCacheAs(cacheKey, data, rowGUID, true, directory)
+		putIntoCacheAs(cacheKey, data, rowGUID, true, directory, lastChanged)
 	}
 
 	if err != nil {
-		return "", err
+		return "", invalidWriteErrno(err)
 	}
 
 	return resp.GetHeader().GetRowGuid(), nil
 }
 
-func putIntoCacheAs(cacheKey fileCacheKey, data []byte, rowGUID string, exists bool, directory bool) {
+// invalidWriteErrno reports writes rejected as invalid, for instance by the
+// schema of the namespace, as EINVAL rather than a generic I/O error.
+func invalidWriteErrno(err error) error {
+	if status.Code(err) == codes.InvalidArgument {
+		logrus.Warningf("Rejecting invalid write: %v", err)
+		return fuse.Errno(syscall.EINVAL)
+	}
+	return err
+}
+
+func putIntoCacheAs(cacheKey fileCacheKey, data []byte, rowGUID string, exists bool, directory bool, lastChanged time.Time) {
 	fileContentsCache.Add(cacheKey, &fileContentsCacheEntry{
-		data:      data,
-		rowGUID:   rowGUID,
-		exists:    exists,
-		directory: directory,
+		data:        data,
+		rowGUID:     rowGUID,
+		exists:      exists,
+		directory:   directory,
+		lastChanged: lastChanged,
 	})
 	fileAttribsCache.Add(cacheKey, &fileAttribCacheEntry{
-		rowGUID:   rowGUID,
-		length:    uint64(len(data)),
-		exists:    exists,
-		directory: directory,
+		rowGUID:     rowGUID,
+		length:      uint64(len(data)),
+		exists:      exists,
+		directory:   directory,
+		lastChanged: lastChanged,
 	})
 }
 
-func getFileNode(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, filename string) *atomicfilefuse.File {
+const (
+	xattrPrefix         = "user.qmfs."
+	expectRevisionXattr = xattrPrefix + "expect_revision"
+)
+
+// expectedRevisions holds the revisions the next write to a file is
+// conditional on, as set with the expect_revision extended attribute.
+// Bounded like the other caches, so entries for files that are never
+// written again are eventually dropped.
+type expectedRevisions struct {
+	mu    sync.Mutex
+	cache *lru.Cache
+}
+
+func newExpectedRevisions() (*expectedRevisions, error) {
+	cache, err := lru.New(10000)
+	if err != nil {
+		return nil, err
+	}
+	return &expectedRevisions{cache: cache}, nil
+}
+
+func (e *expectedRevisions) set(cacheKey fileCacheKey, rowGUID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if rowGUID == "" {
+		e.cache.Remove(cacheKey)
+	} else {
+		e.cache.Add(cacheKey, rowGUID)
+	}
+}
+
+func (e *expectedRevisions) get(cacheKey fileCacheKey, consume bool) (string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rowGUID, ok := e.cache.Get(cacheKey)
+	if !ok {
+		return "", false
+	}
+	if consume {
+		e.cache.Remove(cacheKey)
+	}
+	return rowGUID.(string), true
+}
+
+// readCurrentRevisionHeader returns the header and authorship of the current
+// revision of a file, without its contents. If the cached revision is gone
+// (for instance compacted away), the cache is refreshed and the lookup
+// retried once.
+func readCurrentRevisionHeader(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, filename string) (*pb.ReadFileRevisionResponse, error) {
+	for attempt := 0; ; attempt++ {
+		attribs, ok, err := getFileAttribsOf(ctx, client, namespace, entityID, filename)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fuse.ENOENT
+		}
+
+		resp, err := client.ReadFileRevision(ctx, &pb.ReadFileRevisionRequest{
+			Namespace:  namespace,
+			EntityId:   entityID,
+			Filename:   filename,
+			RowGuid:    attribs.rowGUID,
+			HeaderOnly: true,
+		})
+		if status.Code(err) == codes.NotFound && attempt == 0 {
+			invalidateFileCacheFor(namespace, entityID, filename)
+			continue
+		}
+		return resp, err
+	}
+}
+
+func getFileXattrs(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, filename string, opts *fsOptions) (map[string][]byte, error) {
+	resp, err := readCurrentRevisionHeader(ctx, client, namespace, entityID, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr := resp.GetFile().GetHeader()
+	lastChanged := timestampTime(hdr.GetLastChanged()).UTC()
+
+	rv := map[string][]byte{
+		xattrPrefix + "row_guid":     []byte(hdr.GetRowGuid()),
+		xattrPrefix + "sha256":       []byte(hex.EncodeToString(hdr.GetChecksums().GetSha256())),
+		xattrPrefix + "last_changed": []byte(lastChanged.Format(time.RFC3339Nano)),
+	}
+
+	author := resp.GetAuthorshipMetadata()
+	for name, value := range map[string]string{
+		"hostname":              author.GetHostname(),
+		"tool":                  author.GetTool(),
+		"user":                  author.GetUser(),
+		"qmfs_versioninfo_json": author.GetQmfsVersioninfoJson(),
+	} {
+		if value != "" {
+			rv[xattrPrefix+"author."+name] = []byte(value)
+		}
+	}
+
+	if expiresAt := hdr.GetExpiresAt(); expiresAt != nil {
+		rv[expiresAtXattr] = []byte(timestampTime(expiresAt).UTC().Format(time.RFC3339Nano))
+	}
+
+	cacheKey := fileCacheKey{namespace: namespace, entityID: entityID, filename: filename}
+	if expected, ok := opts.expectedRevisions.get(cacheKey, false); ok {
+		rv[expectRevisionXattr] = []byte(expected)
+	}
+
+	return rv, nil
+}
+
+func getFileNode(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, filename string, opts *fsOptions) *atomicfilefuse.File {
 	// TODO must keep all files with active handles in cache
 	f := &atomicfilefuse.File{
 		Fields: map[string]interface{}{
@@ -431,6 +653,8 @@ func getFileNode(ctx context.Context, client pb.QMetadataServiceClient, namespac
 				a.Mode |= os.ModeDir
 			}
 			a.Size = uint64(attribs.length)
+			a.Mtime = attribs.lastChanged
+			a.Ctime = attribs.lastChanged
 		}
 
 		return ok, nil
@@ -439,44 +663,312 @@ func getFileNode(ctx context.Context, client pb.QMetadataServiceClient, namespac
 		return getFileContents(ctx)
 	}
 	f.AtomicWrite = func(ctx context.Context, data []byte, rev string) (string, error) {
-		rev, err := writeFileOrDir(ctx, client, namespace, entityID, filename, data, rev, false)
-		return rev, err
+		if expected, ok := opts.expectedRevisions.get(cacheKey, true); ok {
+			rev = expected
+		}
+		newRev, err := writeFileOrDir(ctx, client, namespace, entityID, filename, data, rev, false)
+		if isConflict(err) {
+			return handleConflict(ctx, client, opts, namespace, entityID, filename, data, rev, err)
+		}
+		return newRev, err
+	}
+	f.GetXattrs = func(ctx context.Context) (map[string][]byte, error) {
+		return getFileXattrs(ctx, client, namespace, entityID, filename, opts)
+	}
+	f.SetXattr = func(ctx context.Context, name string, value []byte) error {
+		switch name {
+		case expectRevisionXattr:
+			opts.expectedRevisions.set(cacheKey, strings.TrimSpace(string(value)))
+			return nil
+
+		case ttlXattr:
+			return setFileTTL(ctx, client, namespace, entityID, filename, value)
+
+		default:
+			if value == nil {
+				return fuse.ErrNoXattr
+			}
+			return fuse.ENOTSUP
+		}
 	}
+
+	registerLiveNode(liveFileNodes, cacheKey, f)
+
 	return f
 }
 
-func getEntityRootNode(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID string, isFilenameBad func(string) bool) fs.Node {
-	return getEntityDirNode(ctx, client, namespace, entityID, "", isFilenameBad)
+// historyDirName is the name of the read-only directory present in every
+// entity directory, exposing old revisions of the files in that directory.
+const historyDirName = ".history"
+
+func revisionName(hdr *pb.EntityFileHeader) string {
+	return fmt.Sprintf("%d-%s", hdr.GetLastChanged().GetUnixNano(), hdr.GetRowGuid())
 }
 
-func getEntityDirNode(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, parentdir string, isFilenameBad func(string) bool) fs.Node {
-	cacheSize := 1000
-	if parentdir != "" {
-		cacheSize = 0
-	}
+func getFileRevisionsNode(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, path string) fs.Node {
+	listRevisions := func(ctx context.Context) ([]*pb.FileRevision, error) {
+		resp, err := client.ListFileRevisions(ctx, &pb.ListFileRevisionsRequest{
+			Namespace: namespace,
+			EntityId:  entityID,
+			Filename:  path,
+		})
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
 
-	isDirectChild := func(path string) string {
-		if parentdir == "" {
-			if strings.Contains(path, "/") {
-				return ""
+		var rv []*pb.FileRevision
+		for _, rev := range resp.GetRevision() {
+			hdr := rev.GetHeader()
+			if hdr.GetTombstone() || hdr.GetDirectory() {
+				continue
 			}
-			return path
+			rv = append(rv, rev)
 		}
-		prefix := parentdir + "/"
-		if !strings.HasPrefix(path, prefix) {
+		return rv, nil
+	}
+
+	return &dyndirfuse.DynamicDir{
+		Fields: map[string]interface{}{
+			"dir":       "file-revisions",
+			"namespace": namespace,
+			"entity_id": entityID,
+			"filename":  path,
+		},
+		List: func(ctx context.Context, cb func(string, fuse.DirentType)) error {
+			revs, err := listRevisions(ctx)
+			if err != nil {
+				return err
+			}
+			for _, rev := range revs {
+				cb(revisionName(rev.GetHeader()), fuse.DT_File)
+			}
+			return nil
+		},
+		Get: func(ctx context.Context, name string) (fs.Node, fuse.DirentType, bool, error) {
+			revs, err := listRevisions(ctx)
+			if err != nil {
+				return nil, fuse.DT_Unknown, false, err
+			}
+
+			for _, rev := range revs {
+				if revisionName(rev.GetHeader()) != name {
+					continue
+				}
+
+				rowGUID := rev.GetHeader().GetRowGuid()
+
+				return ondemandfuse.Bytes(func(ctx context.Context) ([]byte, error) {
+					resp, err := client.ReadFileRevision(ctx, &pb.ReadFileRevisionRequest{
+						Namespace: namespace,
+						EntityId:  entityID,
+						Filename:  path,
+						RowGuid:   rowGUID,
+					})
+					if err != nil {
+						logrus.WithFields(logrus.Fields{
+							"namespace": namespace,
+							"entity_id": entityID,
+							"filename":  path,
+							"row_guid":  rowGUID,
+						}).Warningf("ReadFileRevision: %v", err)
+						return nil, fuse.EIO
+					}
+					return resp.GetFile().GetData(), nil
+				}), fuse.DT_File, true, nil
+			}
+
+			return nil, fuse.DT_Unknown, false, fuse.ENOENT
+		},
+	}
+}
+
+func getHistoryDirNode(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, parentdir string, isDirectChild func(string) string, fullPath func(string) string) fs.Node {
+	return &dyndirfuse.DynamicDir{
+		Fields: map[string]interface{}{
+			"dir":       "entity-history",
+			"subdir":    parentdir,
+			"namespace": namespace,
+			"entity_id": entityID,
+		},
+		List: func(ctx context.Context, cb func(string, fuse.DirentType)) error {
+			resp, err := client.GetEntity(ctx, &pb.GetEntityRequest{
+				Namespace: namespace,
+				EntityId:  entityID,
+			})
+			if err != nil {
+				logrus.Errorf("Error on getting entity: %v", err)
+				return err
+			}
+
+			for path, hdr := range resp.GetEntity().GetFiles() {
+				if relname := isDirectChild(path); relname != "" && !hdr.GetDirectory() {
+					cb(relname, fuse.DT_Dir)
+				}
+			}
+
+			return nil
+		},
+		Get: func(ctx context.Context, filename string) (fs.Node, fuse.DirentType, bool, error) {
+			if !qmfsquery.ValidFilename(filename) {
+				return nil, fuse.DT_Unknown, false, fuse.ENOENT
+			}
+
+			// Deleted files have history too, so anything with revisions is
+			// accessible here even though only current files are listed.
+			path := fullPath(filename)
+
+			resp, err := client.ListFileRevisions(ctx, &pb.ListFileRevisionsRequest{
+				Namespace: namespace,
+				EntityId:  entityID,
+				Filename:  path,
+			})
+			if status.Code(err) == codes.NotFound {
+				return nil, fuse.DT_Unknown, false, fuse.ENOENT
+			}
+			if err != nil {
+				return nil, fuse.DT_Unknown, false, err
+			}
+
+			for _, rev := range resp.GetRevision() {
+				if !rev.GetHeader().GetTombstone() && !rev.GetHeader().GetDirectory() {
+					return getFileRevisionsNode(ctx, client, namespace, entityID, path), fuse.DT_Dir, true, nil
+				}
+			}
+
+			return nil, fuse.DT_Unknown, false, fuse.ENOENT
+		},
+	}
+}
+
+func directChildName(parentdir, path string) string {
+	if parentdir == "" {
+		if strings.Contains(path, "/") {
 			return ""
 		}
-		if strings.Contains(path[len(prefix):], "/") {
-			return ""
+		return path
+	}
+	prefix := parentdir + "/"
+	if !strings.HasPrefix(path, prefix) {
+		return ""
+	}
+	if strings.Contains(path[len(prefix):], "/") {
+		return ""
+	}
+	return path[len(prefix):]
+}
+
+func joinEntityPath(parentdir, childFilename string) string {
+	if parentdir == "" {
+		return childFilename
+	}
+	return parentdir + "/" + childFilename
+}
+
+func getSnapshotEntityDirNode(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, parentdir string, asOf *pb.Timestamp) fs.Node {
+	getFiles := func(ctx context.Context) (map[string]*pb.EntityFileHeader, error) {
+		resp, err := client.GetEntity(ctx, &pb.GetEntityRequest{
+			Namespace: namespace,
+			EntityId:  entityID,
+			AsOf:      asOf,
+		})
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		if err != nil {
+			logrus.Errorf("Error on getting entity: %v", err)
+			return nil, err
 		}
-		return path[len(prefix):]
+		return resp.GetEntity().GetFiles(), nil
+	}
+
+	return &dyndirfuse.DynamicDir{
+		Fields: map[string]interface{}{
+			"dir":       "snapshot-entity-files",
+			"subdir":    parentdir,
+			"namespace": namespace,
+			"entity_id": entityID,
+			"as_of":     asOf.GetUnixNano(),
+		},
+		List: func(ctx context.Context, cb func(string, fuse.DirentType)) error {
+			files, err := getFiles(ctx)
+			if err != nil {
+				return err
+			}
+
+			for path, hdr := range files {
+				if relname := directChildName(parentdir, path); relname != "" {
+					if hdr.GetDirectory() {
+						cb(relname, fuse.DT_Dir)
+					} else {
+						cb(relname, fuse.DT_File)
+					}
+				}
+			}
+
+			return nil
+		},
+		Get: func(ctx context.Context, filename string) (fs.Node, fuse.DirentType, bool, error) {
+			if !qmfsquery.ValidFilename(filename) {
+				return nil, fuse.DT_Unknown, false, fuse.ENOENT
+			}
+
+			files, err := getFiles(ctx)
+			if err != nil {
+				return nil, fuse.DT_Unknown, false, err
+			}
+
+			path := joinEntityPath(parentdir, filename)
+
+			hdr, ok := files[path]
+			if !ok {
+				return nil, fuse.DT_Unknown, false, fuse.ENOENT
+			}
+
+			if hdr.GetDirectory() {
+				return getSnapshotEntityDirNode(ctx, client, namespace, entityID, path, asOf), fuse.DT_Dir, true, nil
+			}
+
+			return ondemandfuse.Bytes(func(ctx context.Context) ([]byte, error) {
+				resp, err := client.ReadFile(ctx, &pb.ReadFileRequest{
+					Namespace: namespace,
+					EntityId:  entityID,
+					Filename:  path,
+					AsOf:      asOf,
+				})
+				if err != nil {
+					logrus.WithFields(logrus.Fields{
+						"namespace": namespace,
+						"entity_id": entityID,
+						"filename":  path,
+						"as_of":     asOf.GetUnixNano(),
+					}).Warningf("ReadFile: %v", err)
+					return nil, fuse.EIO
+				}
+				return resp.GetFile().GetData(), nil
+			}), fuse.DT_File, true, nil
+		},
+	}
+}
+
+func getEntityRootNode(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID string, opts *fsOptions) fs.Node {
+	return getEntityDirNode(ctx, client, namespace, entityID, "", opts)
+}
+
+func getEntityDirNode(ctx context.Context, client pb.QMetadataServiceClient, namespace, entityID, parentdir string, opts *fsOptions) fs.Node {
+	cacheSize := 1000
+	if parentdir != "" {
+		cacheSize = 0
+	}
+
+	isDirectChild := func(path string) string {
+		return directChildName(parentdir, path)
 	}
 
 	fullPath := func(childFilename string) string {
-		if parentdir == "" {
-			return childFilename
-		}
-		return parentdir + "/" + childFilename
+		return joinEntityPath(parentdir, childFilename)
 	}
 
 	isDir := func(ctx context.Context, path string) (bool, error) {
@@ -495,6 +987,16 @@ func getEntityDirNode(ctx context.Context, client pb.QMetadataServiceClient, nam
 			"namespace": namespace,
 			"entity_id": entityID,
 		},
+		GetAttr: func(ctx context.Context, a *fuse.Attr) error {
+			headers, err := getEntitySubtree(ctx, client, namespace, entityID, parentdir)
+			if err != nil {
+				return err
+			}
+
+			a.Mtime = latestChange(headers)
+			a.Ctime = a.Mtime
+			return nil
+		},
 		List: func(ctx context.Context, cb func(string, fuse.DirentType)) error {
 			resp, err := client.GetEntity(ctx, &pb.GetEntityRequest{
 				Namespace: namespace,
@@ -522,11 +1024,19 @@ func getEntityDirNode(ctx context.Context, client pb.QMetadataServiceClient, nam
 			return nil
 		},
 		Get: func(ctx context.Context, filename string) (fs.Node, fuse.DirentType, bool, error) {
+			if filename == historyDirName {
+				return getHistoryDirNode(ctx, client, namespace, entityID, parentdir, isDirectChild, fullPath), fuse.DT_Dir, true, nil
+			}
+
+			if filename == opts.entityJSONFilename {
+				return getEntityJSONNode(ctx, client, namespace, entityID, parentdir, opts), fuse.DT_File, true, nil
+			}
+
 			if !qmfsquery.ValidFilename(filename) {
 				return nil, fuse.DT_Unknown, false, fuse.ENOENT
 			}
 
-			if isFilenameBad(filename) {
+			if opts.isFilenameBad(filename) {
 				logrus.WithFields(logrus.Fields{
 					"filename": filename,
 				}).Warningf("Refusing to allow file")
@@ -542,10 +1052,10 @@ func getEntityDirNode(ctx context.Context, client pb.QMetadataServiceClient, nam
 			}
 
 			if dir {
-				return getEntityDirNode(ctx, client, namespace, entityID, path, isFilenameBad), fuse.DT_Dir, true, nil
+				return getEntityDirNode(ctx, client, namespace, entityID, path, opts), fuse.DT_Dir, true, nil
 			}
 
-			node := getFileNode(ctx, client, namespace, entityID, path)
+			node := getFileNode(ctx, client, namespace, entityID, path, opts)
 			ft := fuse.DT_File
 
 			ok, err := node.GetAttr(ctx, nil)
@@ -556,7 +1066,7 @@ func getEntityDirNode(ctx context.Context, client pb.QMetadataServiceClient, nam
 			return node, ft, ok, nil
 		},
 		CreateDir: func(ctx context.Context, filename string) error {
-			if !qmfsquery.ValidFilename(filename) {
+			if !qmfsquery.ValidFilename(filename) || filename == historyDirName || filename == opts.entityJSONFilename {
 				return fuse.EIO
 			}
 			path := fullPath(filename)
@@ -568,6 +1078,10 @@ func getEntityDirNode(ctx context.Context, client pb.QMetadataServiceClient, nam
 				return fuse.ENOENT
 			}
 
+			if filename == historyDirName || filename == opts.entityJSONFilename {
+				return fuse.EPERM
+			}
+
 			path := fullPath(filename)
 
 			deltype := pb.DeletionType_DELETE_FILE
@@ -590,6 +1104,9 @@ func getEntityDirNode(ctx context.Context, client pb.QMetadataServiceClient, nam
 			return err
 		},
 	}
+
+	registerLiveNode(liveDirNodes, fileCacheKey{namespace: namespace, entityID: entityID, filename: parentdir}, f)
+
 	return f
 }
 
@@ -600,6 +1117,63 @@ type entitiesQueryer struct {
 	checkEntityExists func(context.Context, string) (bool, error)
 	getNode           func(context.Context, string) (fs.Node, bool, error)
 	getShards         func(context.Context, []string) (map[string][]string, error)
+	aggregate         func(context.Context, pb.AggregateEntitiesRequest_Function, string) (*pb.AggregateEntitiesResponse, error)
+	countValues       func(context.Context, string) (*pb.CountValuesResponse, error)
+	refine            func(filename, value string) (fs.Node, error)
+	listWithFiles     func(context.Context, []string, func(*pb.QueryEntitiesResponse) error) error
+	lastChanged       func(context.Context) (time.Time, error)
+}
+
+// timestampedTree is a static directory reporting when its contents last changed.
+type timestampedTree struct {
+	*fs.Tree
+	lastChanged func(context.Context) (time.Time, error)
+}
+
+func (t *timestampedTree) Attr(ctx context.Context, a *fuse.Attr) error {
+	if err := t.Tree.Attr(ctx, a); err != nil {
+		return err
+	}
+
+	lastChanged, err := t.lastChanged(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.Mtime = lastChanged
+	a.Ctime = lastChanged
+	return nil
+}
+
+func queryValueCounter(client pb.QMetadataServiceClient, req *pb.QueryEntitiesRequest) func(context.Context, string) (*pb.CountValuesResponse, error) {
+	return func(ctx context.Context, filename string) (*pb.CountValuesResponse, error) {
+		return client.CountValues(ctx, &pb.CountValuesRequest{
+			Query:    req,
+			Filename: filename,
+		})
+	}
+}
+
+// validValueDirName is true for values that can be shown as a directory name.
+func validValueDirName(value string) bool {
+	return value != "" && value != "." && value != ".." && !strings.ContainsAny(value, "/\x00")
+}
+
+var aggregateForms = map[string]pb.AggregateEntitiesRequest_Function{
+	"sum": pb.AggregateEntitiesRequest_SUM,
+	"min": pb.AggregateEntitiesRequest_MIN,
+	"max": pb.AggregateEntitiesRequest_MAX,
+	"avg": pb.AggregateEntitiesRequest_AVG,
+}
+
+func queryAggregator(client pb.QMetadataServiceClient, req *pb.QueryEntitiesRequest) func(context.Context, pb.AggregateEntitiesRequest_Function, string) (*pb.AggregateEntitiesResponse, error) {
+	return func(ctx context.Context, function pb.AggregateEntitiesRequest_Function, filename string) (*pb.AggregateEntitiesResponse, error) {
+		return client.AggregateEntities(ctx, &pb.AggregateEntitiesRequest{
+			Query:    req,
+			Function: function,
+			Filename: filename,
+		})
+	}
 }
 
 func moreFields(ms ...map[string]interface{}) map[string]interface{} {
@@ -822,6 +1396,108 @@ func mkEntitiesListNode(ctx context.Context, client pb.QMetadataServiceClient, m
 	formSelector.Add("all", legacyAll)
 	formSelector.Add("shard", sharded)
 
+	if q.listWithFiles != nil {
+		for form := range tabularForms {
+			formSelector.Add(form, mkTabularNode(ctx, fields, form, q))
+		}
+	}
+
+	if q.countValues != nil {
+		formSelector.Add("values", &dyndirfuse.DynamicDir{
+			Fields: moreFields(fields, map[string]interface{}{"resultset": "values"}),
+			List: func(ctx context.Context, cb func(string, fuse.DirentType)) error {
+				return nil
+			},
+			Get: func(ctx context.Context, filename string) (fs.Node, fuse.DirentType, bool, error) {
+				if !qmfsquery.ValidFilename(filename) {
+					return nil, fuse.DT_Unknown, false, nil
+				}
+				return ondemandfuse.String(func(ctx context.Context) (string, error) {
+					resp, err := q.countValues(ctx, filename)
+					if err != nil {
+						return "", err
+					}
+					var buf bytes.Buffer
+					for _, value := range resp.GetValue() {
+						fmt.Fprintf(&buf, "%d\t%s\n", value.GetCount(), value.GetValue())
+					}
+					return buf.String(), nil
+				}), fuse.DT_File, true, nil
+			},
+		})
+	}
+
+	if q.countValues != nil && q.refine != nil {
+		formSelector.Add("by", &dyndirfuse.DynamicDir{
+			Fields: moreFields(fields, map[string]interface{}{"resultset": "by"}),
+			List: func(ctx context.Context, cb func(string, fuse.DirentType)) error {
+				return nil
+			},
+			Get: func(ctx context.Context, filename string) (fs.Node, fuse.DirentType, bool, error) {
+				if !qmfsquery.ValidFilename(filename) {
+					return nil, fuse.DT_Unknown, false, nil
+				}
+				return &dyndirfuse.DynamicDir{
+					Fields: moreFields(fields, map[string]interface{}{"resultset": "by", "filename": filename}),
+					List: func(ctx context.Context, cb func(string, fuse.DirentType)) error {
+						resp, err := q.countValues(ctx, filename)
+						if err != nil {
+							return err
+						}
+						for _, value := range resp.GetValue() {
+							if validValueDirName(value.GetValue()) {
+								cb(value.GetValue(), fuse.DT_Dir)
+							}
+						}
+						return nil
+					},
+					Get: func(ctx context.Context, value string) (fs.Node, fuse.DirentType, bool, error) {
+						node, err := q.refine(filename, value)
+						if err != nil {
+							return nil, fuse.DT_Unknown, false, err
+						}
+						return node, fuse.DT_Dir, true, nil
+					},
+				}, fuse.DT_Dir, true, nil
+			},
+		})
+	}
+
+	if q.aggregate != nil {
+		formSelector.Add("count", ondemandfuse.String(func(ctx context.Context) (string, error) {
+			resp, err := q.aggregate(ctx, pb.AggregateEntitiesRequest_COUNT, "")
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d", resp.GetCount()), nil
+		}))
+
+		for form, function := range aggregateForms {
+			function := function
+			formSelector.Add(form, &dyndirfuse.DynamicDir{
+				Fields: moreFields(fields, map[string]interface{}{"resultset": form}),
+				List: func(ctx context.Context, cb func(string, fuse.DirentType)) error {
+					return nil
+				},
+				Get: func(ctx context.Context, filename string) (fs.Node, fuse.DirentType, bool, error) {
+					if !qmfsquery.ValidFilename(filename) {
+						return nil, fuse.DT_Unknown, false, nil
+					}
+					return ondemandfuse.String(func(ctx context.Context) (string, error) {
+						resp, err := q.aggregate(ctx, function, filename)
+						if err != nil {
+							return "", err
+						}
+						if resp.GetValueCount() == 0 {
+							return "", nil
+						}
+						return strconv.FormatFloat(resp.GetValue(), 'g', -1, 64), nil
+					}), fuse.DT_File, true, nil
+				},
+			})
+		}
+	}
+
 	if isRoot {
 		linkAccessor := &dyndirfuse.DynamicDir{
 			Fields:    moreFields(fields, map[string]interface{}{"resultset": "link"}),
@@ -839,12 +1515,181 @@ func mkEntitiesListNode(ctx context.Context, client pb.QMetadataServiceClient, m
 		formSelector.Add("link", linkAccessor)
 	}
 
+	if q.lastChanged != nil {
+		return &timestampedTree{Tree: formSelector, lastChanged: q.lastChanged}, nil
+	}
+
 	return formSelector, nil
 }
 
-func addRootNodesForNamespace(shortLivedCtx context.Context, client pb.QMetadataServiceClient, tree *fs.Tree, contextBG context.Context, ns, mountpoint string, shardKey []byte, isFilenameBad func(string) bool) error {
+func addRootNodesForNamespace(shortLivedCtx context.Context, client pb.QMetadataServiceClient, tree *fs.Tree, contextBG context.Context, ns, mountpoint string, shardKey []byte, opts *fsOptions, asOf *pb.Timestamp) error {
 	var nextQueryID int64 = 1
 
+	queryCtxBG := contextBG
+
+	var newQueryNode func(querystring string, parsed *pb.EntitiesQuery) (fs.Node, error)
+	newQueryNode = func(querystring string, parsed *pb.EntitiesQuery) (fs.Node, error) {
+		queryReq := &pb.QueryEntitiesRequest{
+			Namespace: ns,
+			AsOf:      asOf,
+			Kind: &pb.QueryEntitiesRequest_ParsedQuery{
+				ParsedQuery: parsed,
+			},
+		}
+
+		queryID := atomic.AddInt64(&nextQueryID, 1)
+		logrus.WithFields(logrus.Fields{
+			"namespace":   ns,
+			"querystring": querystring,
+			"query_id":    queryID,
+		}).Infof("Received query")
+
+		return mkEntitiesListNode(queryCtxBG, client, mountpoint, shardKey, ns, map[string]interface{}{
+			"dir":         "query/instance",
+			"querystring": querystring,
+			"namespace":   ns,
+			"query_id":    queryID,
+		}, &entitiesQueryer{
+			listAll: func(ctx context.Context, shards []string, report func(string) error) error {
+				cloneIntf := proto.Clone(parsed)
+				clone := cloneIntf.(*pb.EntitiesQuery)
+				clone.Clause = append(clone.Clause, qmfsquery.EntityIDShards(shards))
+
+				stream, err := client.QueryEntities(ctx, queryReq)
+				if err != nil {
+					return err
+				}
+
+				for {
+					resp, err := stream.Recv()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						return err
+					}
+
+					queryResultCache.Add(queryCacheKey{
+						namespace: ns,
+						queryID:   queryID,
+						entityID:  resp.EntityId,
+					}, true)
+
+					if err := report(resp.EntityId); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			checkEntityExists: func(ctx context.Context, entityID string) (bool, error) {
+				qck := queryCacheKey{
+					namespace: ns,
+					queryID:   queryID,
+					entityID:  entityID,
+				}
+
+				result, ok := queryResultCache.Get(qck)
+
+				var verifiedExists bool
+
+				if ok && result != nil {
+					verifiedExists = result.(bool)
+				}
+
+				if verifiedExists {
+					return true, nil
+				}
+
+				logrus.WithFields(logrus.Fields{
+					"namespace":   ns,
+					"querystring": querystring,
+					"query_id":    queryID,
+					"entity_id":   entityID,
+				}).Warningf("Not clear whether entity matches query -- verifying")
+
+				if qmfsquery.HasLimit(parsed) {
+					// Narrowing down to the entity would defeat the limit, so
+					// look for it among the full results instead.
+					stream, err := client.QueryEntities(ctx, queryReq)
+					if err != nil {
+						return false, err
+					}
+
+					for {
+						resp, err := stream.Recv()
+						if err == io.EOF {
+							return false, nil
+						}
+						if err != nil {
+							return false, err
+						}
+						if resp.EntityId == entityID {
+							return true, nil
+						}
+					}
+				}
+
+				cloneIntf := proto.Clone(parsed)
+				clone := cloneIntf.(*pb.EntitiesQuery)
+				clone.Clause = append(clone.Clause, qmfsquery.EntityIDEquals(entityID))
+
+				verifyStream, err := client.QueryEntities(ctx, &pb.QueryEntitiesRequest{
+					Namespace: ns,
+					AsOf:      asOf,
+					Kind: &pb.QueryEntitiesRequest_ParsedQuery{
+						ParsedQuery: clone,
+					},
+				})
+				if err != nil {
+					return false, err
+				}
+
+				var rowcount int64
+
+				for {
+					_, err := verifyStream.Recv()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						return false, err
+					}
+					rowcount++
+
+					if rowcount > 1 {
+						logrus.WithFields(logrus.Fields{
+							"namespace":   ns,
+							"querystring": querystring,
+							"query_id":    queryID,
+							"entity_id":   entityID,
+						}).Errorf("Verification query returned more than one entry")
+						err := status.Errorf(codes.Internal, "verification query returned more than one entry")
+						return false, err
+					}
+				}
+
+				verifiedExists = rowcount > 0
+				return verifiedExists, nil
+			},
+			aggregate:     queryAggregator(client, queryReq),
+			countValues:   queryValueCounter(client, queryReq),
+			listWithFiles: queryFilesLister(client, queryReq),
+			refine: func(filename, value string) (fs.Node, error) {
+				clone := proto.Clone(parsed).(*pb.EntitiesQuery)
+				clone.Clause = append(clone.Clause, qmfsquery.FileContentsEquals(filename, value))
+				return newQueryNode(querystring+","+filename+"="+value, clone)
+			},
+		}, false)
+	}
+
+	allEntitiesReq := &pb.QueryEntitiesRequest{
+		Namespace: ns,
+		AsOf:      asOf,
+		Kind: &pb.QueryEntitiesRequest_All{
+			All: true,
+		},
+	}
+
 	listAllEntities, err := mkEntitiesListNode(contextBG, client, mountpoint, shardKey, ns, map[string]interface{}{
 		"dir":       "entities",
 		"namespace": ns,
@@ -853,12 +1698,27 @@ func addRootNodesForNamespace(shortLivedCtx context.Context, client pb.QMetadata
 			if !qmfsquery.ValidFilename(entityID) {
 				return nil, false, fmt.Errorf("invalid filename")
 			}
-			node := getEntityRootNode(ctx, client, ns, entityID, isFilenameBad)
+			if asOf != nil {
+				_, err := client.GetEntity(ctx, &pb.GetEntityRequest{
+					Namespace: ns,
+					EntityId:  entityID,
+					AsOf:      asOf,
+				})
+				if status.Code(err) == codes.NotFound {
+					return nil, false, nil
+				}
+				if err != nil {
+					return nil, false, err
+				}
+				return getSnapshotEntityDirNode(ctx, client, ns, entityID, "", asOf), true, nil
+			}
+			node := getEntityRootNode(ctx, client, ns, entityID, opts)
 			return node, true, nil
 		},
 		listAll: func(ctx context.Context, shards []string, report func(string) error) error {
 			req := &pb.QueryEntitiesRequest{
 				Namespace: ns,
+				AsOf:      asOf,
 			}
 			if len(shards) == 0 {
 				req.Kind = &pb.QueryEntitiesRequest_All{
@@ -894,6 +1754,30 @@ func addRootNodesForNamespace(shortLivedCtx context.Context, client pb.QMetadata
 
 			return nil
 		},
+		aggregate:     queryAggregator(client, allEntitiesReq),
+		countValues:   queryValueCounter(client, allEntitiesReq),
+		listWithFiles: queryFilesLister(client, allEntitiesReq),
+		lastChanged: func(ctx context.Context) (time.Time, error) {
+			resp, err := client.GetDatabaseMetadata(ctx, &pb.GetDatabaseMetadataRequest{
+				OnlyTimestamps: true,
+			})
+			if err != nil {
+				return time.Time{}, err
+			}
+
+			lastChanged := timestampTime(resp.GetMetadata().GetLastChanged())
+			if asOf != nil && lastChanged.After(timestampTime(asOf)) {
+				lastChanged = timestampTime(asOf)
+			}
+			return lastChanged, nil
+		},
+		refine: func(filename, value string) (fs.Node, error) {
+			return newQueryNode(filename+"="+value, &pb.EntitiesQuery{
+				Clause: []*pb.EntitiesQuery_Clause{
+					qmfsquery.FileContentsEquals(filename, value),
+				},
+			})
+		},
 	}, true)
 	if err != nil {
 		return err
@@ -901,8 +1785,6 @@ func addRootNodesForNamespace(shortLivedCtx context.Context, client pb.QMetadata
 
 	tree.Add("entities", listAllEntities)
 
-	queryCtxBG := contextBG
-
 	tree.Add("query", &dyndirfuse.DynamicDir{
 		Fields: map[string]interface{}{
 			"dir":       "query",
@@ -920,130 +1802,12 @@ func addRootNodesForNamespace(shortLivedCtx context.Context, client pb.QMetadata
 				return nil, fuse.DT_Unknown, false, status.Errorf(codes.InvalidArgument, "invalid query: %q", err)
 			}
 
-			queryReq := &pb.QueryEntitiesRequest{
-				Namespace: ns,
-				Kind: &pb.QueryEntitiesRequest_ParsedQuery{
-					ParsedQuery: parsed,
-				},
-			}
-
-			queryID := atomic.AddInt64(&nextQueryID, 1)
-			logrus.WithFields(logrus.Fields{
-				"namespace":   ns,
-				"querystring": querystring,
-				"query_id":    queryID,
-			}).Infof("Received query")
-
-			listQueryEntities, err := mkEntitiesListNode(queryCtxBG, client, mountpoint, shardKey, ns, map[string]interface{}{
-				"dir":         "query/instance",
-				"querystring": querystring,
-				"namespace":   ns,
-				"query_id":    queryID,
-			}, &entitiesQueryer{
-				listAll: func(ctx context.Context, shards []string, report func(string) error) error {
-					cloneIntf := proto.Clone(parsed)
-					clone := cloneIntf.(*pb.EntitiesQuery)
-					clone.Clause = append(clone.Clause, qmfsquery.EntityIDShards(shards))
-
-					stream, err := client.QueryEntities(ctx, queryReq)
-					if err != nil {
-						return err
-					}
-
-					for {
-						resp, err := stream.Recv()
-						if err == io.EOF {
-							break
-						}
-						if err != nil {
-							return err
-						}
-
-						queryResultCache.Add(queryCacheKey{
-							namespace: ns,
-							queryID:   queryID,
-							entityID:  resp.EntityId,
-						}, true)
-
-						if err := report(resp.EntityId); err != nil {
-							return err
-						}
-					}
-					return nil
-				},
-				checkEntityExists: func(ctx context.Context, entityID string) (bool, error) {
-					qck := queryCacheKey{
-						namespace: ns,
-						queryID:   queryID,
-						entityID:  entityID,
-					}
-
-					result, ok := queryResultCache.Get(qck)
-
-					var verifiedExists bool
-
-					if ok && result != nil {
-						verifiedExists = result.(bool)
-					}
-
-					if verifiedExists {
-						return true, nil
-					}
-
-					logrus.WithFields(logrus.Fields{
-						"namespace":   ns,
-						"querystring": querystring,
-						"query_id":    queryID,
-						"entity_id":   entityID,
-					}).Warningf("Not clear whether entity matches query -- verifying")
-
-					cloneIntf := proto.Clone(parsed)
-					clone := cloneIntf.(*pb.EntitiesQuery)
-					clone.Clause = append(clone.Clause, qmfsquery.EntityIDEquals(entityID))
-
-					verifyStream, err := client.QueryEntities(ctx, &pb.QueryEntitiesRequest{
-						Namespace: ns,
-						Kind: &pb.QueryEntitiesRequest_ParsedQuery{
-							ParsedQuery: clone,
-						},
-					})
-					if err != nil {
-						return false, err
-					}
-
-					var rowcount int64
-
-					for {
-						_, err := verifyStream.Recv()
-						if err == io.EOF {
-							break
-						}
-						if err != nil {
-							return false, err
-						}
-						rowcount++
-
-						if rowcount > 1 {
-							logrus.WithFields(logrus.Fields{
-								"namespace":   ns,
-								"querystring": querystring,
-								"query_id":    queryID,
-								"entity_id":   entityID,
-							}).Errorf("Verification query returned more than one entry")
-							err := status.Errorf(codes.Internal, "verification query returned more than one entry")
-							return false, err
-						}
-					}
-
-					verifiedExists = rowcount > 0
-					return verifiedExists, nil
-				},
-			}, false)
+			node, err := newQueryNode(querystring, parsed)
 			if err != nil {
 				return nil, fuse.DT_Unknown, false, err
 			}
 
-			return listQueryEntities, fuse.DT_Dir, true, nil
+			return node, fuse.DT_Dir, true, nil
 		},
 	})
 	return nil
@@ -1084,7 +1848,27 @@ func New(ctx context.Context, client pb.QMetadataServiceClient, params Params) (
 	}
 	qmfsVersioninfoJSON = string(versioninfoJSON)
 
-	svcTree, err := newServiceTree(ctx, params.ServiceData, client, params.ShutdownChan)
+	expected, err := newExpectedRevisions()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &fsOptions{
+		isFilenameBad:      isFilenameBad,
+		entityJSONFilename: defaultEntityJSONFilename,
+		expectedRevisions:  expected,
+		conflictPolicy:     params.ConflictPolicy,
+		conflicts:          &conflictLog{},
+	}
+
+	if params.EntityJSONFilename != "" {
+		if !qmfsquery.ValidFilename(params.EntityJSONFilename) {
+			return nil, fmt.Errorf("bad entity JSON filename %q", params.EntityJSONFilename)
+		}
+		opts.entityJSONFilename = params.EntityJSONFilename
+	}
+
+	svcTree, err := newServiceTree(ctx, params.ServiceData, client, opts, params.ShutdownChan)
 	if err != nil {
 		return nil, err
 	}
@@ -1092,15 +1876,18 @@ func New(ctx context.Context, client pb.QMetadataServiceClient, params Params) (
 	tree := &fs.Tree{}
 	tree.Add("service", svcTree)
 
-	tree.Add("namespace", newNamespaceListNode(client, params.Mountpoint, shardKey, ctx, isFilenameBad))
+	tree.Add("namespace", newNamespaceListNode(client, params.Mountpoint, shardKey, ctx, opts, nil))
+
+	tree.Add("snapshot", newSnapshotListNode(client, params.Mountpoint, shardKey, ctx, opts))
 
-	if err := addRootNodesForNamespace(ctx, client, tree, ctx, "", params.Mountpoint, shardKey, isFilenameBad); err != nil {
+	if err := addRootNodesForNamespace(ctx, client, tree, ctx, "", params.Mountpoint, shardKey, opts, nil); err != nil {
 		return nil, err
 	}
 
 	return &Filesystem{
 		client: client,
 		svc:    params.ServiceData,
+		opts:   opts,
 		root:   tree,
 	}, nil
 }
@@ -0,0 +1,225 @@
+package qmfs
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+	"github.com/steinarvk/qmfs/lib/dyndirfuse"
+	"github.com/steinarvk/qmfs/lib/qmfsquery"
+	"github.com/steinarvk/qmfs/lib/readstreamfuse"
+)
+
+// A tabularWriter writes one row per entity: the entity ID followed by the
+// trimmed contents of each column file, or nil where the file is missing.
+type tabularWriter interface {
+	WriteRow(entityID string, values []*string) error
+	Close() error
+}
+
+var tabularForms = map[string]func(io.Writer, []string) (tabularWriter, error){
+	"csv": func(w io.Writer, columns []string) (tabularWriter, error) {
+		return newDelimitedWriter(w, columns, ',')
+	},
+	"tsv": func(w io.Writer, columns []string) (tabularWriter, error) {
+		return newDelimitedWriter(w, columns, '\t')
+	},
+	"json": func(w io.Writer, columns []string) (tabularWriter, error) {
+		return &jsonWriter{w: w, columns: columns, array: true}, nil
+	},
+	"ndjson": func(w io.Writer, columns []string) (tabularWriter, error) {
+		return &jsonWriter{w: w, columns: columns}, nil
+	},
+}
+
+type delimitedWriter struct {
+	w *csv.Writer
+}
+
+func newDelimitedWriter(w io.Writer, columns []string, comma rune) (*delimitedWriter, error) {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	if err := cw.Write(append([]string{"entity_id"}, columns...)); err != nil {
+		return nil, err
+	}
+
+	return &delimitedWriter{w: cw}, nil
+}
+
+func (d *delimitedWriter) WriteRow(entityID string, values []*string) error {
+	record := []string{entityID}
+	for _, value := range values {
+		if value == nil {
+			record = append(record, "")
+		} else {
+			record = append(record, *value)
+		}
+	}
+
+	if err := d.w.Write(record); err != nil {
+		return err
+	}
+
+	// Flush every row, so that readers see results as they arrive.
+	d.w.Flush()
+	return d.w.Error()
+}
+
+func (d *delimitedWriter) Close() error {
+	d.w.Flush()
+	return d.w.Error()
+}
+
+type jsonWriter struct {
+	w       io.Writer
+	columns []string
+	array   bool
+	started bool
+}
+
+// marshalRow renders a row as a JSON object, keeping the columns in order.
+func (j *jsonWriter) marshalRow(entityID string, values []*string) ([]byte, error) {
+	keys := append([]string{"entity_id"}, j.columns...)
+	vals := append([]*string{&entityID}, values...)
+
+	var parts []string
+	for i, key := range keys {
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(vals[i])
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, string(k)+":"+string(v))
+	}
+
+	return []byte("{" + strings.Join(parts, ",") + "}"), nil
+}
+
+func (j *jsonWriter) WriteRow(entityID string, values []*string) error {
+	row, err := j.marshalRow(entityID, values)
+	if err != nil {
+		return err
+	}
+
+	prefix := ""
+	if j.array {
+		prefix = ",\n"
+		if !j.started {
+			prefix = "[\n"
+		}
+	}
+	j.started = true
+
+	suffix := ""
+	if !j.array {
+		suffix = "\n"
+	}
+
+	_, err = io.WriteString(j.w, prefix+string(row)+suffix)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if !j.array {
+		return nil
+	}
+
+	s := "\n]\n"
+	if !j.started {
+		s = "[]\n"
+	}
+
+	_, err := io.WriteString(j.w, s)
+	return err
+}
+
+func queryFilesLister(client pb.QMetadataServiceClient, req *pb.QueryEntitiesRequest) func(context.Context, []string, func(*pb.QueryEntitiesResponse) error) error {
+	return func(ctx context.Context, filenames []string, report func(*pb.QueryEntitiesResponse) error) error {
+		clone := proto.Clone(req).(*pb.QueryEntitiesRequest)
+		clone.IncludeFilename = filenames
+
+		stream, err := client.QueryEntities(ctx, clone)
+		if err != nil {
+			return err
+		}
+
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := report(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseColumns parses a comma-separated list of filenames.
+func parseColumns(spec string) ([]string, bool) {
+	columns := strings.Split(spec, ",")
+	for _, column := range columns {
+		if !qmfsquery.ValidFilename(column) {
+			return nil, false
+		}
+	}
+	return columns, true
+}
+
+func mkTabularNode(ctx context.Context, fields map[string]interface{}, form string, q *entitiesQueryer) fs.Node {
+	newWriter := tabularForms[form]
+
+	return &dyndirfuse.DynamicDir{
+		Fields: moreFields(fields, map[string]interface{}{"resultset": form}),
+		List: func(ctx context.Context, cb func(string, fuse.DirentType)) error {
+			return nil
+		},
+		Get: func(_ context.Context, spec string) (fs.Node, fuse.DirentType, bool, error) {
+			columns, ok := parseColumns(spec)
+			if !ok {
+				return nil, fuse.DT_Unknown, false, nil
+			}
+
+			return readstreamfuse.Stream(ctx, func(ctx context.Context, w io.Writer) error {
+				tw, err := newWriter(w, columns)
+				if err != nil {
+					return err
+				}
+
+				if err := q.listWithFiles(ctx, columns, func(resp *pb.QueryEntitiesResponse) error {
+					contents := map[string]string{}
+					for _, file := range resp.GetFile() {
+						contents[file.GetHeader().GetFilename()] = strings.TrimSpace(string(file.GetData()))
+					}
+
+					values := make([]*string, len(columns))
+					for i, column := range columns {
+						if value, ok := contents[column]; ok {
+							values[i] = &value
+						}
+					}
+
+					return tw.WriteRow(resp.GetEntityId(), values)
+				}); err != nil {
+					return err
+				}
+
+				return tw.Close()
+			}), fuse.DT_File, true, nil
+		},
+	}
+}
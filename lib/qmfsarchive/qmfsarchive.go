@@ -0,0 +1,107 @@
+// Package qmfsarchive reads and writes export archives: a header identifying
+// the format, followed by a stream of length-delimited ArchivedRevision
+// messages.
+package qmfsarchive
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+const (
+	magic = "qmfs-archive-v1\n"
+
+	maxRecordSize = 1 << 30
+)
+
+type Writer struct {
+	w       *bufio.Writer
+	started bool
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+func (w *Writer) Write(rec *pb.ArchivedRevision) error {
+	if !w.started {
+		if _, err := w.w.WriteString(magic); err != nil {
+			return err
+		}
+		w.started = true
+	}
+
+	data, err := proto.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(data)))
+
+	if _, err := w.w.Write(lenBuf[:n]); err != nil {
+		return err
+	}
+	_, err = w.w.Write(data)
+	return err
+}
+
+// Close writes out anything buffered. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if !w.started {
+		if _, err := w.w.WriteString(magic); err != nil {
+			return err
+		}
+		w.started = true
+	}
+	return w.w.Flush()
+}
+
+type Reader struct {
+	r       *bufio.Reader
+	started bool
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next revision in the archive, or io.EOF at the end.
+func (r *Reader) Read() (*pb.ArchivedRevision, error) {
+	if !r.started {
+		header := make([]byte, len(magic))
+		if _, err := io.ReadFull(r.r, header); err != nil || string(header) != magic {
+			return nil, fmt.Errorf("not a qmfs archive")
+		}
+		r.started = true
+	}
+
+	size, err := binary.ReadUvarint(r.r)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("corrupt archive: %v", err)
+	}
+	if size > maxRecordSize {
+		return nil, fmt.Errorf("corrupt archive: record of %d bytes", size)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, fmt.Errorf("corrupt archive: %v", err)
+	}
+
+	rec := &pb.ArchivedRevision{}
+	if err := proto.Unmarshal(data, rec); err != nil {
+		return nil, fmt.Errorf("corrupt archive: %v", err)
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,208 @@
+package qmfsdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/steinarvk/orclib/lib/sqlitedb"
+	"github.com/steinarvk/qmfs/lib/qmfsquery"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+var aggregateFunctions = map[pb.AggregateEntitiesRequest_Function]string{
+	pb.AggregateEntitiesRequest_SUM: "SUM",
+	pb.AggregateEntitiesRequest_MIN: "MIN",
+	pb.AggregateEntitiesRequest_MAX: "MAX",
+	pb.AggregateEntitiesRequest_AVG: "AVG",
+}
+
+// entitiesQueryFromRequest expresses any kind of entities query as an EntitiesQuery.
+func entitiesQueryFromRequest(req *pb.QueryEntitiesRequest) (*pb.EntitiesQuery, error) {
+	switch value := req.Kind.(type) {
+	case *pb.QueryEntitiesRequest_All:
+		return &pb.EntitiesQuery{}, nil
+
+	case *pb.QueryEntitiesRequest_HasFilename:
+		if value.HasFilename == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "HasFilename query with empty filename")
+		}
+		return &pb.EntitiesQuery{
+			Clause: []*pb.EntitiesQuery_Clause{
+				{Kind: &pb.EntitiesQuery_Clause_FileExists{FileExists: value.HasFilename}},
+			},
+		}, nil
+
+	case *pb.QueryEntitiesRequest_ParsedQuery:
+		return value.ParsedQuery, nil
+
+	case *pb.QueryEntitiesRequest_RawQuery:
+		parsed, err := qmfsquery.Parse(value.RawQuery)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid query %q: %v", value.RawQuery, err)
+		}
+		return parsed, nil
+
+	case nil:
+		return nil, status.Errorf(codes.InvalidArgument, "no query")
+
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unsupported query kind %v", req.Kind)
+	}
+}
+
+// compileMatchesSubquery returns SQL selecting the entity_id of each entity
+// matching the query, suitable for use as a subquery, along with its arguments.
+func (d *Database) compileMatchesSubquery(ctx context.Context, queryReq *pb.QueryEntitiesRequest) (string, map[string]interface{}, error) {
+	query, err := entitiesQueryFromRequest(queryReq)
+	if err != nil {
+		return "", nil, err
+	}
+
+	innerSQL, argmap, checkfunc, err := d.compileEntitiesQuery(queryReq.GetAsOf() != nil, query)
+	if err != nil {
+		return "", nil, err
+	}
+	if checkfunc != nil {
+		return "", nil, status.Errorf(codes.InvalidArgument, "regex[] is not supported in aggregates")
+	}
+
+	argmap["namespace"] = queryReq.GetNamespace()
+	if queryReq.GetAsOf() != nil {
+		argmap["as_of_unix_nano"] = queryReq.GetAsOf().GetUnixNano()
+	}
+
+	return innerSQL, argmap, nil
+}
+
+var aggregateEntitiesTransactor = sqlitedb.Transactor("AggregateEntities")
+
+func (d *Database) AggregateEntities(ctx context.Context, req *pb.AggregateEntitiesRequest) (*pb.AggregateEntitiesResponse, error) {
+	queryReq := req.GetQuery()
+
+	innerSQL, argmap, err := d.compileMatchesSubquery(ctx, queryReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var fullSQL string
+
+	if req.GetFunction() == pb.AggregateEntitiesRequest_COUNT {
+		fullSQL = "SELECT COUNT(*) AS count, 0 AS value_count, NULL AS value\nFROM (" + innerSQL + ") AS matches"
+	} else {
+		function, ok := aggregateFunctions[req.GetFunction()]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid aggregate function (%v)", req.GetFunction())
+		}
+
+		if !qmfsquery.ValidPath(req.GetFilename()) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid filename: %q", req.GetFilename())
+		}
+		if err := d.checkContentsAsOf(queryReq.GetAsOf() != nil, "aggregating contents"); err != nil {
+			return nil, err
+		}
+		argmap["aggregate_filename"] = req.GetFilename()
+
+		textExpr := "COALESCE(CAST(agg.trimmed_data AS TEXT), '')"
+		numericExpr := "CASE WHEN " + numericTextCondition(textExpr) + " THEN CAST(" + textExpr + " AS REAL) END"
+
+		fullSQL = fmt.Sprintf(`SELECT COUNT(*) AS count, COUNT(%[2]s) AS value_count, %[1]s(%[2]s) AS value
+FROM (%[3]s) AS matches
+LEFT JOIN items AS agg
+ON agg.namespace = :namespace
+AND agg.entity_id = matches.entity_id
+AND agg.filename = :aggregate_filename
+AND %[4]s`, function, numericExpr, innerSQL, liveRowCondition("agg", queryReq.GetAsOf() != nil))
+	}
+
+	logrus.Infof("Final aggregate SQL: %s", fullSQL)
+
+	prepq := d.db.PrepareQuery(&err, "qmfsdb-aggregate-entities-query", fullSQL)
+	if err != nil {
+		logrus.Infof("SQL error (query was: %s): %v", fullSQL, err)
+		return nil, err
+	}
+
+	var row struct {
+		Count      int64
+		ValueCount int64
+		Value      *float64
+	}
+
+	var rv pb.AggregateEntitiesResponse
+
+	if err := aggregateEntitiesTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		return prepq.Query(ctx, tx, argmap, &row, func() (bool, error) {
+			rv.Count = row.Count
+			rv.ValueCount = row.ValueCount
+			if row.Value != nil {
+				rv.Value = *row.Value
+			}
+			return false, nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return &rv, nil
+}
+
+var countValuesTransactor = sqlitedb.Transactor("CountValues")
+
+func (d *Database) CountValues(ctx context.Context, req *pb.CountValuesRequest) (*pb.CountValuesResponse, error) {
+	queryReq := req.GetQuery()
+
+	if !qmfsquery.ValidPath(req.GetFilename()) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid filename: %q", req.GetFilename())
+	}
+
+	innerSQL, argmap, err := d.compileMatchesSubquery(ctx, queryReq)
+	if err != nil {
+		return nil, err
+	}
+	argmap["values_filename"] = req.GetFilename()
+
+	fullSQL := fmt.Sprintf(`SELECT CAST(COALESCE(val.trimmed_data, X'') AS TEXT) AS value, COUNT(*) AS count
+FROM (%s) AS matches
+JOIN items AS val
+ON val.namespace = :namespace
+AND val.entity_id = matches.entity_id
+AND val.filename = :values_filename
+AND val.directory = 0
+AND %s
+GROUP BY value
+ORDER BY count DESC, value`, innerSQL, liveRowCondition("val", queryReq.GetAsOf() != nil))
+
+	logrus.Infof("Final values SQL: %s", fullSQL)
+
+	prepq := d.db.PrepareQuery(&err, "qmfsdb-count-values-query", fullSQL)
+	if err != nil {
+		logrus.Infof("SQL error (query was: %s): %v", fullSQL, err)
+		return nil, err
+	}
+
+	var row struct {
+		Value string
+		Count int64
+	}
+
+	var rv pb.CountValuesResponse
+
+	if err := countValuesTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		return prepq.Query(ctx, tx, argmap, &row, func() (bool, error) {
+			rv.Value = append(rv.Value, &pb.CountValuesResponse_Value{
+				Value: row.Value,
+				Count: row.Count,
+			})
+			return true, nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return &rv, nil
+}
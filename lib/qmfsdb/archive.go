@@ -0,0 +1,372 @@
+package qmfsdb
+
+import (
+	"context"
+	"database/sql"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"github.com/steinarvk/orclib/lib/sqlitedb"
+	"github.com/steinarvk/qmfs/lib/qmfsquery"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+const importBatchSize = 1000
+
+type ExportOptions struct {
+	Namespace     string
+	AllNamespaces bool
+
+	// IncludeHistory exports inactive revisions and deletions as well as
+	// the current contents.
+	IncludeHistory    bool
+	IncludeAuthorship bool
+}
+
+var exportTransactor = sqlitedb.Transactor("Export")
+
+func archivedRevision(row *fullRevisionData, includeAuthorship bool) (*pb.ArchivedRevision, error) {
+	hdr := makeRevisionHeader(row.Namespace, row.EntityID, row.Filename, row.RowGUID, row.TimestampUnixNano, row.Sha256Hash, row.TrimmedSha256Hash, row.DataLength, row.TrimmedDataLength, row.Tombstone, row.Directory)
+	hdr.ExpiresAt = expiresAtTimestamp(row.ExpiresAtUnixNano)
+
+	data := append(row.WhitespacePrefix, append(row.TrimmedData, row.WhitespaceSuffix...)...)
+
+	rec := &pb.ArchivedRevision{
+		File: &pb.EntityFile{
+			Header: hdr,
+			Data:   data,
+		},
+		Active:       row.Active,
+		DataRetained: row.Tombstone || int64(len(data)) == hdr.GetChecksums().GetLength(),
+	}
+
+	if includeAuthorship {
+		authorship, err := deserializeAuthorshipMetadata(row.AuthorshipMetadata)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Error deserializing authorship metadata: %v", err)
+		}
+		rec.AuthorshipMetadata = authorship
+	}
+
+	return rec, nil
+}
+
+// Export reports revisions in the order they were written, so that
+// importing them replays the same history.
+func (d *Database) Export(ctx context.Context, opts ExportOptions, report func(*pb.ArchivedRevision) error) error {
+	var row fullRevisionData
+
+	return exportTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		return d.queryExportRevisions.Query(ctx, tx, map[string]interface{}{
+			"namespace":       opts.Namespace,
+			"all_namespaces":  opts.AllNamespaces,
+			"include_history": opts.IncludeHistory,
+		}, &row, func() (bool, error) {
+			rec, err := archivedRevision(&row, opts.IncludeAuthorship)
+			if err != nil {
+				return false, err
+			}
+
+			if err := report(rec); err != nil {
+				return false, err
+			}
+			return true, nil
+		})
+	})
+}
+
+type ImportOptions struct {
+	// PreserveRevisions keeps the row GUIDs and timestamps of the imported
+	// revisions, instead of recording them as new writes. Revisions already
+	// present are skipped, so importing the same archive twice is harmless.
+	// A revision only becomes current if it was current when exported and
+	// is newer than the current revision here; otherwise it is kept as
+	// history. Revisions of unknown files older than the last compaction
+	// are skipped, as they may have been deleted here.
+	PreserveRevisions bool
+}
+
+type ImportResult struct {
+	Imported int64
+	Skipped  int64
+}
+
+func (d *Database) prepareImport(rec *pb.ArchivedRevision, preserve bool) (*pendingWrite, error) {
+	hdr := rec.GetFile().GetHeader()
+
+	if !qmfsquery.ValidPath(hdr.GetFilename()) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid filename: %q", hdr.GetFilename())
+	}
+
+	w, err := d.prepareWriteOrDelete(hdr.GetNamespace(), hdr.GetEntityId(), hdr.GetFilename(), "", hdr.GetTombstone(), rec.GetFile().GetData(), rec.GetAuthorshipMetadata(), hdr.GetDirectory(), pb.DeletionType_DELETE_ANY)
+	if err != nil {
+		return nil, err
+	}
+
+	if hdr.GetExpiresAt() != nil && !hdr.GetTombstone() {
+		w.setExpiresAt(hdr.GetExpiresAt().GetUnixNano())
+	}
+
+	if !preserve {
+		return w, nil
+	}
+
+	if hdr.GetRowGuid() == "" || hdr.GetLastChanged() == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "revision of %q in entity %q lacks a row GUID or timestamp", hdr.GetFilename(), hdr.GetEntityId())
+	}
+
+	w.fields["row_guid"] = hdr.GetRowGuid()
+	w.fields["timestamp_unix_nano"] = hdr.GetLastChanged().GetUnixNano()
+	w.header.RowGuid = hdr.GetRowGuid()
+	w.header.LastChanged = hdr.GetLastChanged()
+
+	if !hdr.GetTombstone() && !rec.GetDataRetained() {
+		// Only the checksums of the contents survive.
+		checksums := hdr.GetChecksums()
+		w.fields["data_length"] = checksums.GetLength()
+		w.fields["sha256_hash"] = checksums.GetSha256()
+		w.fields["trimmed_data_length"] = checksums.GetTrimmedLength()
+		w.fields["trimmed_sha256_hash"] = checksums.GetTrimmedSha256()
+		w.fields["whitespace_prefix"] = nil
+		w.fields["trimmed_data"] = nil
+		w.fields["whitespace_suffix"] = nil
+		w.header.Checksums = checksums
+	}
+
+	return w, nil
+}
+
+var importTransactor = sqlitedb.Transactor("Import")
+
+func (d *Database) rowExists(ctx context.Context, tx *sql.Tx, rowGUID string) (bool, error) {
+	var found bool
+
+	var row struct {
+		RowGUID string
+	}
+	if err := d.queryRowExists.Query(ctx, tx, map[string]interface{}{
+		"row_guid": rowGUID,
+	}, &row, func() (bool, error) {
+		found = true
+		return false, nil
+	}); err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+// supersedes reports whether a revision with the given timestamp and row
+// GUID wins over another revision of the same file. The latest write wins,
+// with ties broken by GUID, so that every database picks the same winner.
+func supersedes(timestampUnixNano int64, rowGUID string, otherTimestampUnixNano int64, otherRowGUID string) bool {
+	if timestampUnixNano != otherTimestampUnixNano {
+		return timestampUnixNano > otherTimestampUnixNano
+	}
+	return rowGUID > otherRowGUID
+}
+
+type currentRevisionRow struct {
+	RowGUID           string
+	TimestampUnixNano int64
+}
+
+func (d *Database) currentRevision(ctx context.Context, tx *sql.Tx, namespace, entityID, filename string) (*currentRevisionRow, error) {
+	var rv *currentRevisionRow
+
+	var row currentRevisionRow
+	if err := d.queryCurrentRevision.Query(ctx, tx, map[string]interface{}{
+		"namespace": namespace,
+		"entity_id": entityID,
+		"filename":  filename,
+	}, &row, func() (bool, error) {
+		current := row
+		rv = &current
+		return false, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// mergeRevision adds a revision written elsewhere, keeping its row GUID and
+// timestamp. If it was not current where it came from, lacks its contents,
+// or loses to the current revision of the file, it is recorded as history
+// only; otherwise it must be allowed by the schema of its namespace.
+// Revisions of files without any revisions here are skipped if older than
+// the compaction horizon, since the file may have been deleted and
+// compacted.
+func (d *Database) mergeRevision(ctx context.Context, tx *sql.Tx, rec *pb.ArchivedRevision) (bool, error) {
+	hdr := rec.GetFile().GetHeader()
+
+	w, err := d.prepareImport(rec, true)
+	if err != nil {
+		return false, err
+	}
+
+	exists, err := d.rowExists(ctx, tx, hdr.GetRowGuid())
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	current, err := d.currentRevision(ctx, tx, w.namespace, w.entityID, w.filename)
+	if err != nil {
+		return false, err
+	}
+
+	if current == nil {
+		// A file deleted here may have been compacted away without a
+		// trace, so older revisions of it must not bring it back.
+		horizon, err := d.compactionHorizon(ctx, tx)
+		if err != nil {
+			return false, err
+		}
+		if hdr.GetLastChanged().GetUnixNano() < horizon {
+			logrus.WithFields(logrus.Fields{
+				"namespace": w.namespace,
+				"entity_id": w.entityID,
+				"filename":  w.filename,
+				"row_guid":  hdr.GetRowGuid(),
+			}).Warningf("Skipping merged revision of unknown file older than the compaction horizon")
+			return false, nil
+		}
+	}
+
+	superseded := current != nil && !supersedes(hdr.GetLastChanged().GetUnixNano(), hdr.GetRowGuid(), current.TimestampUnixNano, current.RowGUID)
+	if superseded {
+		logrus.WithFields(logrus.Fields{
+			"namespace": w.namespace,
+			"entity_id": w.entityID,
+			"filename":  w.filename,
+			"row_guid":  hdr.GetRowGuid(),
+			"current":   current.RowGUID,
+		}).Infof("Merged revision superseded by current revision; keeping as history")
+	}
+
+	// Without its contents a revision can only serve as history.
+	dataMissing := !hdr.GetTombstone() && !rec.GetDataRetained()
+
+	if superseded || dataMissing || !rec.GetActive() {
+		w.fields["active"] = false
+		if !d.opts.KeepRevisionData {
+			w.fields["whitespace_prefix"] = nil
+			w.fields["trimmed_data"] = nil
+			w.fields["whitespace_suffix"] = nil
+		}
+
+		return true, d.stmtInsertNewRow.Exec(ctx, tx, w.fields)
+	}
+
+	if err := d.checkSchema(ctx, tx, w); err != nil {
+		return false, err
+	}
+
+	if err := d.stmtMarkOldRowsInactive.Exec(ctx, tx, map[string]interface{}{
+		"namespace": w.namespace,
+		"entity_id": w.entityID,
+		"filename":  w.filename,
+	}); err != nil {
+		return false, err
+	}
+
+	return true, d.stmtInsertNewRow.Exec(ctx, tx, w.fields)
+}
+
+// Import replays revisions read from next, until it returns io.EOF, as
+// writes and deletions applied in batches.
+func (d *Database) Import(ctx context.Context, opts ImportOptions, next func() (*pb.ArchivedRevision, error)) (*ImportResult, error) {
+	var rv ImportResult
+
+	for done := false; !done; {
+		var batch []*pb.ArchivedRevision
+
+		for len(batch) < importBatchSize {
+			rec, err := next()
+			if err == io.EOF {
+				done = true
+				break
+			}
+			if err != nil {
+				return &rv, err
+			}
+			batch = append(batch, rec)
+		}
+
+		var imported, skipped int64
+
+		if err := importTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+			imported, skipped = 0, 0
+
+			for _, rec := range batch {
+				hdr := rec.GetFile().GetHeader()
+
+				if !opts.PreserveRevisions && !hdr.GetTombstone() && !rec.GetDataRetained() {
+					// Without the contents, this can only be imported as
+					// history.
+					skipped++
+					continue
+				}
+
+				if opts.PreserveRevisions {
+					// These only become current if they win over the
+					// current revision.
+					merged, err := d.mergeRevision(ctx, tx, rec)
+					if err != nil {
+						return err
+					}
+					if merged {
+						imported++
+					} else {
+						skipped++
+					}
+					continue
+				}
+
+				w, err := d.prepareImport(rec, false)
+				if err != nil {
+					return err
+				}
+
+				if err := d.checkSchema(ctx, tx, w); err != nil {
+					return err
+				}
+
+				err = d.applyWriteOrDelete(ctx, tx, w)
+				if status.Code(err) == codes.NotFound {
+					// Deletion of a file that is not present.
+					skipped++
+					continue
+				}
+				if err != nil {
+					return err
+				}
+
+				if w.actuallyChanging {
+					imported++
+				} else {
+					skipped++
+				}
+			}
+
+			return nil
+		}); err != nil {
+			return &rv, err
+		}
+
+		rv.Imported += imported
+		rv.Skipped += skipped
+
+		if imported > 0 {
+			d.onChange()
+		}
+	}
+
+	return &rv, nil
+}
@@ -0,0 +1,88 @@
+package qmfsdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+func (d *Database) prepareBatchOperation(op *pb.BatchOperation) (*pendingWrite, error) {
+	switch value := op.Kind.(type) {
+	case *pb.BatchOperation_Write:
+		return d.prepareWriteFile(value.Write)
+
+	case *pb.BatchOperation_Delete:
+		return d.prepareDeleteFile(value.Delete)
+
+	case nil:
+		return nil, status.Errorf(codes.InvalidArgument, "no operation")
+
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unsupported operation kind %v", op.Kind)
+	}
+}
+
+// batchOperationError identifies the operation that failed with a detail,
+// leaving the code, message and other details of err as they are.
+func batchOperationError(i int, err error) error {
+	logrus.WithFields(logrus.Fields{
+		"operation": i,
+	}).Warningf("Batch operation failed: %v", err)
+
+	withDetails, detailErr := status.Convert(err).WithDetails(&pb.BatchOperationFailure{
+		Index: int32(i),
+	})
+	if detailErr != nil {
+		logrus.Errorf("Failed to attach batch operation details: %v", detailErr)
+		return err
+	}
+	return withDetails.Err()
+}
+
+func (d *Database) Batch(ctx context.Context, req *pb.BatchRequest) (*pb.BatchResponse, error) {
+	var writes []*pendingWrite
+
+	for i, op := range req.GetOperation() {
+		w, err := d.prepareBatchOperation(op)
+		if err != nil {
+			return nil, batchOperationError(i, err)
+		}
+		writes = append(writes, w)
+	}
+
+	if err := writeFileTx(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		for i, w := range writes {
+			if err := d.checkSchema(ctx, tx, w); err != nil {
+				return batchOperationError(i, err)
+			}
+			if err := d.applyWriteOrDelete(ctx, tx, w); err != nil {
+				return batchOperationError(i, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var rv pb.BatchResponse
+	var actuallyChanging bool
+
+	for _, w := range writes {
+		w.logDone()
+		actuallyChanging = actuallyChanging || w.actuallyChanging
+		rv.Header = append(rv.Header, w.header)
+	}
+
+	if actuallyChanging {
+		d.onChange()
+	}
+
+	logrus.Infof("Batch of %d operations done", len(writes))
+
+	return &rv, nil
+}
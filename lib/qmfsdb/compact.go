@@ -0,0 +1,162 @@
+package qmfsdb
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/steinarvk/orclib/lib/sqlitedb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+// An inactive row can be removed once a newer row for the same file was
+// written before the cutoff: at no point inside the retention window was it
+// the current revision.
+const supersededRevisionCondition = `
+active=0
+AND EXISTS (
+	SELECT 1 FROM items AS later
+	WHERE later.namespace = items.namespace
+	AND   later.entity_id = items.entity_id
+	AND   later.filename = items.filename
+	AND   later.timestamp_unix_nano > items.timestamp_unix_nano
+	AND   later.timestamp_unix_nano < :cutoff_unix_nano
+)`
+
+// A tombstone can be removed once it is older than the cutoff, no other
+// revisions of the file remain to give it meaning, and it has been pushed to
+// every peer synced with.
+const expiredTombstoneCondition = `
+tombstone=1
+AND timestamp_unix_nano < :cutoff_unix_nano
+AND items.sequence <= (SELECT COALESCE(MIN(pushed_sequence), items.sequence) FROM sync_peers)
+AND NOT EXISTS (
+	SELECT 1 FROM items AS other
+	WHERE other.namespace = items.namespace
+	AND   other.entity_id = items.entity_id
+	AND   other.filename = items.filename
+	AND   other.row_guid != items.row_guid
+)`
+
+var compactTransactor = sqlitedb.Transactor("Compact")
+
+func (d *Database) databaseFileSize() (int64, error) {
+	info, err := os.Stat(d.filename)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (d *Database) vacuum(ctx context.Context) error {
+	// VACUUM cannot run inside a transaction, so it gets a connection of its own.
+	conn, err := sql.Open("sqlite3", d.filename)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "VACUUM")
+	return err
+}
+
+// compactionHorizon returns the latest cutoff compaction was run with, or
+// zero if the database was never compacted.
+func (d *Database) compactionHorizon(ctx context.Context, tx *sql.Tx) (int64, error) {
+	var rv int64
+
+	var row struct {
+		HorizonUnixNano int64
+	}
+	if err := d.queryCompactionHorizon.Query(ctx, tx, nil, &row, func() (bool, error) {
+		rv = row.HorizonUnixNano
+		return false, nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return rv, nil
+}
+
+func (d *Database) Compact(ctx context.Context, req *pb.CompactRequest) (*pb.CompactResponse, error) {
+	if req.GetRetentionSeconds() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "negative retention: %d", req.GetRetentionSeconds())
+	}
+
+	cutoff := time.Now().Add(-time.Duration(req.GetRetentionSeconds()) * time.Second)
+
+	sizeBefore, err := d.databaseFileSize()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error checking database size: %v", err)
+	}
+
+	var rv pb.CompactResponse
+
+	args := map[string]interface{}{
+		"cutoff_unix_nano": cutoff.UnixNano(),
+	}
+
+	if err := compactTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		var row struct {
+			Count int64
+		}
+
+		if err := d.queryCountSupersededRevisions.Query(ctx, tx, args, &row, func() (bool, error) {
+			rv.DeletedRevisions = row.Count
+			return false, nil
+		}); err != nil {
+			return err
+		}
+
+		if err := d.stmtDeleteSupersededRevisions.Exec(ctx, tx, args); err != nil {
+			return err
+		}
+
+		if err := d.queryCountExpiredTombstones.Query(ctx, tx, args, &row, func() (bool, error) {
+			rv.DeletedTombstones = row.Count
+			return false, nil
+		}); err != nil {
+			return err
+		}
+
+		if err := d.stmtDeleteExpiredTombstones.Exec(ctx, tx, args); err != nil {
+			return err
+		}
+
+		return d.stmtRaiseCompactionHorizon.Exec(ctx, tx, args)
+	}); err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"cutoff":             cutoff,
+		"deleted_revisions":  rv.DeletedRevisions,
+		"deleted_tombstones": rv.DeletedTombstones,
+	}).Infof("Compact: deleted rows")
+
+	if !req.GetSkipVacuum() {
+		if err := d.vacuum(ctx); err != nil {
+			return nil, status.Errorf(codes.Internal, "Error running VACUUM: %v", err)
+		}
+
+		sizeAfter, err := d.databaseFileSize()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Error checking database size: %v", err)
+		}
+
+		rv.BytesReclaimed = sizeBefore - sizeAfter
+	}
+
+	metadata, err := d.GetDatabaseMetadata(ctx, &pb.GetDatabaseMetadataRequest{})
+	if err != nil {
+		return nil, err
+	}
+	rv.Size = metadata.GetMetadata().GetSize()
+
+	return &rv, nil
+}
@@ -0,0 +1,152 @@
+package qmfsdb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/steinarvk/orclib/lib/sqlitedb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+const (
+	DefaultExpiryInterval = time.Second
+
+	expiryBatchSize = 1000
+	expiryTool      = "qmfs-expiry"
+)
+
+func (w *pendingWrite) setExpiresAt(unixNano int64) {
+	w.expiresAt = &unixNano
+	w.fields["expires_at_unix_nano"] = unixNano
+	w.header.ExpiresAt = &pb.Timestamp{UnixNano: unixNano}
+}
+
+func expiresAtTimestamp(unixNano *int64) *pb.Timestamp {
+	if unixNano == nil {
+		return nil
+	}
+	return &pb.Timestamp{UnixNano: *unixNano}
+}
+
+func sameExpiry(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+type expiredFile struct {
+	Namespace string
+	EntityID  string
+	Filename  string
+	RowGUID   string
+}
+
+var expiryTransactor = sqlitedb.Transactor("Expiry")
+
+func (d *Database) expiredFiles(ctx context.Context, now time.Time) ([]expiredFile, error) {
+	var rv []expiredFile
+
+	var row expiredFile
+	err := expiryTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		rv = nil
+		return d.queryExpiredFiles.Query(ctx, tx, map[string]interface{}{
+			"now_unix_nano": now.UnixNano(),
+			"limit":         expiryBatchSize,
+		}, &row, func() (bool, error) {
+			rv = append(rv, row)
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// deleteExpiredFiles deletes files whose expiry has passed, as ordinary
+// deletions conditional on the expiring revision still being current.
+func (d *Database) deleteExpiredFiles(ctx context.Context) (int, error) {
+	expired, err := d.expiredFiles(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	authorship := &pb.AuthorshipMetadata{
+		Tool: expiryTool,
+	}
+
+	var deleted int
+
+	for _, f := range expired {
+		w, err := d.prepareWriteOrDelete(f.Namespace, f.EntityID, f.Filename, f.RowGUID, true, nil, authorship, false, pb.DeletionType_DELETE_FILE)
+		if err != nil {
+			return deleted, err
+		}
+
+		_, err = d.writeOrDeleteFile(ctx, w)
+		switch status.Code(err) {
+		case codes.OK:
+			deleted++
+
+		case codes.FailedPrecondition, codes.NotFound:
+			// Rewritten or deleted in the meantime.
+
+		default:
+			return deleted, err
+		}
+	}
+
+	return deleted, nil
+}
+
+func (d *Database) runExpiry(interval time.Duration) {
+	defer close(d.expiryDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stopExpiry:
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := d.deleteExpiredFiles(context.Background())
+		if err != nil {
+			logrus.Errorf("Error deleting expired files: %v", err)
+		}
+		if deleted > 0 {
+			logrus.Infof("Deleted %d expired files", deleted)
+		}
+	}
+}
+
+func (d *Database) startExpiry() {
+	interval := d.opts.ExpiryInterval
+	if interval <= 0 {
+		interval = DefaultExpiryInterval
+	}
+
+	d.stopExpiry = make(chan struct{})
+	d.expiryDone = make(chan struct{})
+
+	go d.runExpiry(interval)
+}
+
+func (d *Database) stopExpiryAndWait() {
+	if d.stopExpiry == nil {
+		return
+	}
+
+	close(d.stopExpiry)
+	<-d.expiryDone
+	d.stopExpiry = nil
+}
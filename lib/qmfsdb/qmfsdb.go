@@ -7,7 +7,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
@@ -60,37 +63,159 @@ var (
 			CREATE INDEX idx_nef_active_tombstone ON items (namespace, entity_id, filename, active, tombstone);
 			CREATE INDEX idx_nef_shards_active_tombstone ON items (namespace, entity_id_shard1, entity_id_shard2, entity_id, filename, active, tombstone);
 			`,
+			// Change feeds and sync use the sequence as a cursor, so unlike
+			// rowid it must never be reused, even after rows are deleted.
+			`
+			CREATE TABLE items_with_sequence (
+				sequence INTEGER PRIMARY KEY AUTOINCREMENT,
+				row_guid TEXT NOT NULL UNIQUE,
+				namespace TEXT NOT NULL,
+				tombstone BOOLEAN NOT NULL CHECK (tombstone=0 OR tombstone=1),
+				active BOOLEAN NOT NULL CHECK (active=0 OR active=1),
+				directory BOOLEAN NOT NULL CHECK (directory=0 OR directory=1),
+				timestamp_unix_nano INTEGER NOT NULL,
+				entity_id TEXT NOT NULL,
+				entity_id_shard1 TEXT NOT NULL,
+				entity_id_shard2 TEXT NOT NULL,
+				filename TEXT NOT NULL,
+				sha256_hash BLOB NULL,
+				trimmed_sha256_hash BLOB NULL,
+				data_length INTEGER NULL,
+				trimmed_data_length INTEGER NULL,
+				whitespace_prefix BLOB NULL,
+				trimmed_data BLOB NULL,
+				whitespace_suffix BLOB NULL,
+				authorship_metadata BLOB NULL
+			);
+
+			INSERT INTO items_with_sequence
+				(sequence, row_guid, namespace, tombstone, active, directory,
+				 timestamp_unix_nano, entity_id, entity_id_shard1, entity_id_shard2,
+				 filename, sha256_hash, trimmed_sha256_hash, data_length,
+				 trimmed_data_length, whitespace_prefix, trimmed_data,
+				 whitespace_suffix, authorship_metadata)
+			SELECT
+				rowid, row_guid, namespace, tombstone, active, directory,
+				timestamp_unix_nano, entity_id, entity_id_shard1, entity_id_shard2,
+				filename, sha256_hash, trimmed_sha256_hash, data_length,
+				trimmed_data_length, whitespace_prefix, trimmed_data,
+				whitespace_suffix, authorship_metadata
+			FROM items
+			ORDER BY rowid;
+
+			DROP TABLE items;
+
+			ALTER TABLE items_with_sequence RENAME TO items;
+
+			CREATE UNIQUE INDEX idx_row_guid ON items (row_guid);
+
+			CREATE INDEX idx_nef_active_tombstone ON items (namespace, entity_id, filename, active, tombstone);
+			CREATE INDEX idx_nef_shards_active_tombstone ON items (namespace, entity_id_shard1, entity_id_shard2, entity_id, filename, active, tombstone);
+			`,
+			`
+			CREATE TABLE sync_peers (
+				peer TEXT NOT NULL PRIMARY KEY,
+				pulled_sequence INTEGER NOT NULL,
+				pushed_sequence INTEGER NOT NULL
+			);
+			`,
+			// Compaction forgets deleted files, so a revision older than its
+			// latest cutoff can no longer be told apart from a deleted one.
+			`
+			CREATE TABLE compaction_horizon (
+				id INTEGER NOT NULL PRIMARY KEY CHECK (id = 0),
+				horizon_unix_nano INTEGER NOT NULL
+			);
+			`,
+			`
+			ALTER TABLE items ADD COLUMN expires_at_unix_nano INTEGER NULL;
+
+			CREATE INDEX idx_active_tombstone_expires_at ON items (active, tombstone, expires_at_unix_nano);
+			`,
 		),
 	}
 )
 
 const (
 	channelSize = 1000
+
+	entityFilesBatchSize = 100
 )
 
 type Options struct {
 	ChangeHook func()
+
+	// KeepRevisionData retains the contents of old revisions of files,
+	// instead of discarding it when a file is overwritten or deleted.
+	KeepRevisionData bool
+
+	// DeleteExpiredFiles runs a background reaper deleting files whose
+	// expiry has passed. Only long-running servers should enable it, so
+	// that one-off commands do not modify the database as a side effect.
+	DeleteExpiredFiles bool
+
+	// ExpiryInterval is how often files written with an expiry are checked
+	// for deletion; defaults to DefaultExpiryInterval.
+	ExpiryInterval time.Duration
 }
 
 type Database struct {
 	db *sqlitedb.Database
 
+	filename    string
 	shardingKey []byte
 	opts        Options
 
+	changeMu sync.Mutex
+	changeCh chan struct{}
+
+	stopExpiry chan struct{}
+	expiryDone chan struct{}
+
 	stmtInsertNewRow        *sqlitedb.PreparedExec
 	stmtMarkOldRowsInactive *sqlitedb.PreparedExec
 	stmtSetShardingKey      *sqlitedb.PreparedExec
 
-	queryListEntityFiles    *sqlitedb.PreparedQuery
-	queryGlobalLastChanged  *sqlitedb.PreparedQuery
-	queryGlobalMetadata     *sqlitedb.PreparedQuery
-	queryEntityFileHeaders  *sqlitedb.PreparedQuery
-	queryAllEntities        *sqlitedb.PreparedQuery
-	queryEntitiesByFilename *sqlitedb.PreparedQuery
-	queryReadFile           *sqlitedb.PreparedQuery
-	queryListNamespaces     *sqlitedb.PreparedQuery
-	queryGetShardingKey     *sqlitedb.PreparedQuery
+	stmtDeleteSupersededRevisions *sqlitedb.PreparedExec
+	stmtDeleteExpiredTombstones   *sqlitedb.PreparedExec
+
+	queryListEntityFiles        *sqlitedb.PreparedQuery
+	queryGlobalLastChanged      *sqlitedb.PreparedQuery
+	queryGlobalMetadata         *sqlitedb.PreparedQuery
+	queryEntityFileHeaders      *sqlitedb.PreparedQuery
+	queryAllEntities            *sqlitedb.PreparedQuery
+	queryEntitiesByFilename     *sqlitedb.PreparedQuery
+	queryReadFile               *sqlitedb.PreparedQuery
+	queryListNamespaces         *sqlitedb.PreparedQuery
+	queryGetShardingKey         *sqlitedb.PreparedQuery
+	queryListFileRevisions      *sqlitedb.PreparedQuery
+	queryReadFileRevision       *sqlitedb.PreparedQuery
+	queryReadFileRevisionHeader *sqlitedb.PreparedQuery
+
+	queryReadFileAsOf           *sqlitedb.PreparedQuery
+	queryEntityFileHeadersAsOf  *sqlitedb.PreparedQuery
+	queryAllEntitiesAsOf        *sqlitedb.PreparedQuery
+	queryEntitiesByFilenameAsOf *sqlitedb.PreparedQuery
+
+	queryCountSupersededRevisions *sqlitedb.PreparedQuery
+	queryCountExpiredTombstones   *sqlitedb.PreparedQuery
+	queryCompactionHorizon        *sqlitedb.PreparedQuery
+	stmtRaiseCompactionHorizon    *sqlitedb.PreparedExec
+
+	queryChangesAfter   *sqlitedb.PreparedQuery
+	queryLatestSequence *sqlitedb.PreparedQuery
+
+	queryExportRevisions *sqlitedb.PreparedQuery
+	queryRowExists       *sqlitedb.PreparedQuery
+	queryCurrentRevision *sqlitedb.PreparedQuery
+
+	querySyncRevisions    *sqlitedb.PreparedQuery
+	querySyncWatermarks   *sqlitedb.PreparedQuery
+	stmtSetSyncWatermarks *sqlitedb.PreparedExec
+
+	queryEntityExists *sqlitedb.PreparedQuery
+
+	queryExpiredFiles *sqlitedb.PreparedQuery
 }
 
 type MaybeString struct {
@@ -135,23 +260,44 @@ func (d *Database) QueryEntities(req *pb.QueryEntitiesRequest, stream pb.QMetada
 		"namespace": req.GetNamespace(),
 	}
 
+	asOf := req.GetAsOf() != nil
+	if asOf {
+		argmap["as_of_unix_nano"] = req.GetAsOf().GetUnixNano()
+	}
+
 	var prepq *sqlitedb.PreparedQuery
 
-	var checkfunc func(context.Context, string) (bool, error)
+	var check *entityCheck
 
-	switch value := req.Kind.(type) {
+	kind := req.Kind
+	if raw, ok := kind.(*pb.QueryEntitiesRequest_RawQuery); ok {
+		// Same syntax as the query/ directory.
+		parsed, err := qmfsquery.Parse(raw.RawQuery)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid query %q: %v", raw.RawQuery, err)
+		}
+		kind = &pb.QueryEntitiesRequest_ParsedQuery{ParsedQuery: parsed}
+	}
+
+	switch value := kind.(type) {
 	case *pb.QueryEntitiesRequest_All:
 		prepq = d.queryAllEntities
+		if asOf {
+			prepq = d.queryAllEntitiesAsOf
+		}
 
 	case *pb.QueryEntitiesRequest_HasFilename:
 		prepq = d.queryEntitiesByFilename
+		if asOf {
+			prepq = d.queryEntitiesByFilenameAsOf
+		}
 		if value.HasFilename == "" {
 			return status.Errorf(codes.InvalidArgument, "HasFilename query with empty filename")
 		}
 		argmap["filename"] = value.HasFilename
 
 	case *pb.QueryEntitiesRequest_ParsedQuery:
-		dynq, dynargmap, dyncheckfunc, err := d.prepareDynamicEntitiesQuery(ctx, req.GetNamespace(), value.ParsedQuery)
+		dynq, dynargmap, dyncheck, err := d.prepareDynamicEntitiesQuery(asOf, value.ParsedQuery)
 		if err != nil {
 			return err
 		}
@@ -161,13 +307,11 @@ func (d *Database) QueryEntities(req *pb.QueryEntitiesRequest, stream pb.QMetada
 		}
 
 		prepq = dynq
-		checkfunc = dyncheckfunc
+		check = dyncheck
 
 	case nil:
 		return status.Errorf(codes.InvalidArgument, "no query")
 
-	case *pb.QueryEntitiesRequest_RawQuery:
-
 	default:
 		return status.Errorf(codes.Unimplemented, "unsupported query kind %v", req.Kind)
 	}
@@ -178,20 +322,33 @@ func (d *Database) QueryEntities(req *pb.QueryEntitiesRequest, stream pb.QMetada
 
 	var row rowType
 
-	if err := queryEntitiesTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
-		return prepq.Query(ctx, tx, argmap, &row, func() (bool, error) {
+	// Candidates needing a check or included files are collected first,
+	// and their files then read in batches within the same transaction.
+	filenames := append([]string(nil), req.GetIncludeFilename()...)
+	if check != nil {
+		filenames = append(filenames, check.filenames...)
+	}
+
+	var filesq *sqlitedb.PreparedQuery
+	if len(filenames) > 0 {
+		var err error
+		filesq, err = d.prepareEntityFilesQuery(asOf, len(filenames))
+		if err != nil {
+			return err
+		}
+	}
+
+	return queryEntitiesTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		var candidates []string
+
+		if err := prepq.Query(ctx, tx, argmap, &row, func() (bool, error) {
 			if err := ctx.Err(); err != nil {
 				return false, err
 			}
 
-			if checkfunc != nil {
-				ok, err := checkfunc(ctx, row.EntityID)
-				if err != nil {
-					return false, err
-				}
-				if !ok {
-					return true, nil
-				}
+			if filesq != nil {
+				candidates = append(candidates, row.EntityID)
+				return true, nil
 			}
 
 			if err := stream.Send(&pb.QueryEntitiesResponse{
@@ -201,12 +358,117 @@ func (d *Database) QueryEntities(req *pb.QueryEntitiesRequest, stream pb.QMetada
 			}
 
 			return true, nil
-		})
+		}); err != nil {
+			return err
+		}
+
+		for len(candidates) > 0 {
+			batch := candidates
+			if len(batch) > entityFilesBatchSize {
+				batch = batch[:entityFilesBatchSize]
+			}
+			candidates = candidates[len(batch):]
+
+			files, err := readEntityFiles(ctx, tx, filesq, argmap, batch, filenames)
+			if err != nil {
+				return err
+			}
+
+			for _, entityID := range batch {
+				if check != nil && !check.matches(files[entityID]) {
+					continue
+				}
+
+				resp := &pb.QueryEntitiesResponse{
+					EntityId: entityID,
+				}
+
+				for _, filename := range req.GetIncludeFilename() {
+					if file, ok := files[entityID][filename]; ok {
+						resp.File = append(resp.File, file)
+					}
+				}
+
+				if err := stream.Send(resp); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// prepareEntityFilesQuery prepares a query reading the given number of
+// files from each of up to entityFilesBatchSize entities at once.
+func (d *Database) prepareEntityFilesQuery(asOf bool, numFilenames int) (*sqlitedb.PreparedQuery, error) {
+	var entityVars, filenameVars []string
+	for i := 0; i < entityFilesBatchSize; i++ {
+		entityVars = append(entityVars, fmt.Sprintf(":entity_id%d", i))
+	}
+	for i := 0; i < numFilenames; i++ {
+		filenameVars = append(filenameVars, fmt.Sprintf(":filename%d", i))
+	}
+
+	var err error
+	prepared := d.db.PrepareQuery(&err, "qmfsdb-entity-files-query", `
+SELECT namespace, entity_id, filename, row_guid, timestamp_unix_nano,
+       sha256_hash, data_length, trimmed_sha256_hash, trimmed_data_length,
+			 whitespace_prefix, trimmed_data, whitespace_suffix,
+			 directory, expires_at_unix_nano
+FROM items AS cur
+WHERE `+liveRowCondition("cur", asOf)+`
+AND   namespace = :namespace
+AND   entity_id IN (`+strings.Join(entityVars, ", ")+`)
+AND   filename IN (`+strings.Join(filenameVars, ", ")+`)
+`)
+	if err != nil {
+		return nil, err
+	}
+
+	return prepared, nil
+}
+
+// readEntityFiles reads the given files of a batch of entities, by entity
+// ID and filename. Files whose contents were not retained are left out.
+func readEntityFiles(ctx context.Context, tx *sql.Tx, prepq *sqlitedb.PreparedQuery, baseArgs map[string]interface{}, entityIDs, filenames []string) (map[string]map[string]*pb.EntityFile, error) {
+	args := map[string]interface{}{
+		"namespace": baseArgs["namespace"],
+	}
+	if asOf, ok := baseArgs["as_of_unix_nano"]; ok {
+		args["as_of_unix_nano"] = asOf
+	}
+	for i := 0; i < entityFilesBatchSize; i++ {
+		// Entity IDs are never empty, so unused slots match nothing.
+		entityID := ""
+		if i < len(entityIDs) {
+			entityID = entityIDs[i]
+		}
+		args[fmt.Sprintf("entity_id%d", i)] = entityID
+	}
+	for i, filename := range filenames {
+		args[fmt.Sprintf("filename%d", i)] = filename
+	}
+
+	rv := map[string]map[string]*pb.EntityFile{}
+
+	var row fullFileData
+	if err := prepq.Query(ctx, tx, args, &row, func() (bool, error) {
+		file := row.entityFile()
+		if file == nil {
+			return true, nil
+		}
+
+		if rv[row.EntityID] == nil {
+			rv[row.EntityID] = map[string]*pb.EntityFile{}
+		}
+		rv[row.EntityID][row.Filename] = file
+		return true, nil
 	}); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return rv, nil
 }
 
 type entityFileHeader struct {
@@ -231,12 +493,19 @@ func (d *Database) GetEntity(ctx context.Context, req *pb.GetEntityRequest) (*pb
 
 	var rv []*pb.EntityFileHeader
 
+	prepq := d.queryEntityFileHeaders
+	argmap := map[string]interface{}{
+		"namespace": req.GetNamespace(),
+		"entity_id": entityID,
+	}
+	if req.GetAsOf() != nil {
+		prepq = d.queryEntityFileHeadersAsOf
+		argmap["as_of_unix_nano"] = req.GetAsOf().GetUnixNano()
+	}
+
 	var row entityFileHeader
 	err := getEntityTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
-		return d.queryEntityFileHeaders.Query(ctx, tx, map[string]interface{}{
-			"namespace": req.GetNamespace(),
-			"entity_id": entityID,
-		}, &row, func() (bool, error) {
+		return prepq.Query(ctx, tx, argmap, &row, func() (bool, error) {
 			rv = append(rv, &pb.EntityFileHeader{
 				EntityId: row.EntityID,
 				Filename: row.Filename,
@@ -305,7 +574,26 @@ func serializeAuthorshipMetadata(md *pb.AuthorshipMetadata) ([]byte, error) {
 
 var writeFileTx = sqlitedb.Transactor("qmfsdb.WriteOrDeleteFile")
 
-func (d *Database) writeOrDeleteFile(ctx context.Context, namespace, entityID, filename, oldRevisionGUID string, tombstone bool, data []byte, authorship *pb.AuthorshipMetadata, directory bool, replaceType pb.DeletionType) (*pb.EntityFileHeader, error) {
+// pendingWrite is a validated write or deletion of a single file, ready to
+// be applied within a transaction.
+type pendingWrite struct {
+	namespace       string
+	entityID        string
+	filename        string
+	oldRevisionGUID string
+	tombstone       bool
+	data            []byte
+	directory       bool
+	replaceType     pb.DeletionType
+	expiresAt       *int64
+
+	header *pb.EntityFileHeader
+	fields map[string]interface{}
+
+	actuallyChanging bool
+}
+
+func (d *Database) prepareWriteOrDelete(namespace, entityID, filename, oldRevisionGUID string, tombstone bool, data []byte, authorship *pb.AuthorshipMetadata, directory bool, replaceType pb.DeletionType) (*pendingWrite, error) {
 	if len(data) > 0 && tombstone {
 		return nil, status.Errorf(codes.Internal, "Cannot both delete and write file")
 	}
@@ -373,116 +661,160 @@ func (d *Database) writeOrDeleteFile(ctx context.Context, namespace, entityID, f
 		fields["trimmed_sha256_hash"] = nil
 	}
 
-	actuallyChanging := true
+	fields["entity_id_shard1"] = entityIDShards[0]
+	fields["entity_id_shard2"] = entityIDShards[1]
 
-	if err := writeFileTx(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
-		var previousContents fullFileData
-		var hadPreviousContents bool
-
-		if err := d.queryReadFile.Query(ctx, tx, map[string]interface{}{
-			"namespace": namespace,
-			"entity_id": entityID,
-			"filename":  filename,
-		}, &previousContents, func() (bool, error) {
-			hadPreviousContents = true
-			return false, nil
-		}); err != nil {
-			return err
-		}
+	fields["row_guid"] = rowGUID
+	fields["tombstone"] = tombstone
+	fields["active"] = true
+	fields["timestamp_unix_nano"] = t.UnixNano()
 
-		if hadPreviousContents {
-			// Check the file we're overwriting or deleting.
-			switch replaceType {
-			case pb.DeletionType_DELETE_NONE:
-				return status.Errorf(codes.FailedPrecondition, "file %q already exists", filename)
+	fields["namespace"] = namespace
+	fields["entity_id"] = entityID
+	fields["filename"] = filename
 
-			case pb.DeletionType_DELETE_FILE:
-				if previousContents.Directory {
-					return status.Errorf(codes.FailedPrecondition, "file %q is a directory", filename)
-				}
+	fields["directory"] = directory
 
-			case pb.DeletionType_DELETE_DIR:
-				if !previousContents.Directory {
-					return status.Errorf(codes.FailedPrecondition, "file %q is not a directory", filename)
-				}
-			}
-		}
+	prefix, trimmed, suffix := partitionData(data)
 
-		if oldRevisionGUID != "" && oldRevisionGUID != previousContents.RowGUID {
-			return status.Errorf(codes.FailedPrecondition, "Conflict: modification of %q but last revision was %q", oldRevisionGUID, previousContents.RowGUID)
-		}
+	fields["whitespace_prefix"] = prefix
+	fields["trimmed_data"] = trimmed
+	fields["whitespace_suffix"] = suffix
 
-		if tombstone && !hadPreviousContents {
-			return status.Errorf(codes.NotFound, "File not found")
-		} else if !tombstone && hadPreviousContents {
-			if hasDataEqualTo(&previousContents, data) {
-				actuallyChanging = false
+	fields["authorship_metadata"] = authorshipBytes
 
-				returnedHeader.LastChanged = &pb.Timestamp{
-					UnixNano: previousContents.TimestampUnixNano,
-				}
-				returnedHeader.RowGuid = previousContents.RowGUID
-				return nil
+	fields["expires_at_unix_nano"] = nil
+
+	return &pendingWrite{
+		namespace:        namespace,
+		entityID:         entityID,
+		filename:         filename,
+		oldRevisionGUID:  oldRevisionGUID,
+		tombstone:        tombstone,
+		data:             data,
+		directory:        directory,
+		replaceType:      replaceType,
+		header:           returnedHeader,
+		fields:           fields,
+		actuallyChanging: true,
+	}, nil
+}
+
+func (d *Database) applyWriteOrDelete(ctx context.Context, tx *sql.Tx, w *pendingWrite) error {
+	w.actuallyChanging = true
+
+	var previousContents fullFileData
+	var hadPreviousContents bool
+
+	if err := d.queryReadFile.Query(ctx, tx, map[string]interface{}{
+		"namespace": w.namespace,
+		"entity_id": w.entityID,
+		"filename":  w.filename,
+	}, &previousContents, func() (bool, error) {
+		hadPreviousContents = true
+		return false, nil
+	}); err != nil {
+		return err
+	}
+
+	if hadPreviousContents {
+		// Check the file we're overwriting or deleting.
+		switch w.replaceType {
+		case pb.DeletionType_DELETE_NONE:
+			return status.Errorf(codes.FailedPrecondition, "file %q already exists", w.filename)
+
+		case pb.DeletionType_DELETE_FILE:
+			if previousContents.Directory {
+				return status.Errorf(codes.FailedPrecondition, "file %q is a directory", w.filename)
 			}
-		}
 
-		if err := d.stmtMarkOldRowsInactive.Exec(ctx, tx, map[string]interface{}{
-			"namespace": namespace,
-			"entity_id": entityID,
-			"filename":  filename,
-		}); err != nil {
-			return err
+		case pb.DeletionType_DELETE_DIR:
+			if !previousContents.Directory {
+				return status.Errorf(codes.FailedPrecondition, "file %q is not a directory", w.filename)
+			}
 		}
+	}
 
-		fields["entity_id_shard1"] = entityIDShards[0]
-		fields["entity_id_shard2"] = entityIDShards[1]
+	if w.oldRevisionGUID != "" && w.oldRevisionGUID != previousContents.RowGUID {
+		return revisionConflictError(w.oldRevisionGUID, previousContents.RowGUID)
+	}
 
-		fields["row_guid"] = rowGUID
-		fields["tombstone"] = tombstone
-		fields["active"] = true
-		fields["timestamp_unix_nano"] = t.UnixNano()
+	if w.tombstone && !hadPreviousContents {
+		return status.Errorf(codes.NotFound, "File not found")
+	} else if !w.tombstone && hadPreviousContents {
+		if hasDataEqualTo(&previousContents, w.data) && sameExpiry(previousContents.ExpiresAtUnixNano, w.expiresAt) {
+			w.actuallyChanging = false
 
-		fields["namespace"] = namespace
-		fields["entity_id"] = entityID
-		fields["filename"] = filename
+			w.header.LastChanged = &pb.Timestamp{
+				UnixNano: previousContents.TimestampUnixNano,
+			}
+			w.header.RowGuid = previousContents.RowGUID
+			return nil
+		}
+	}
 
-		fields["directory"] = directory
+	if err := d.stmtMarkOldRowsInactive.Exec(ctx, tx, map[string]interface{}{
+		"namespace": w.namespace,
+		"entity_id": w.entityID,
+		"filename":  w.filename,
+	}); err != nil {
+		return err
+	}
+
+	if err := d.stmtInsertNewRow.Exec(ctx, tx, w.fields); err != nil {
+		return err
+	}
 
-		prefix, trimmed, suffix := partitionData(data)
+	return nil
+}
 
-		fields["whitespace_prefix"] = prefix
-		fields["trimmed_data"] = trimmed
-		fields["whitespace_suffix"] = suffix
+func (w *pendingWrite) logDone() {
+	logrus.WithFields(logrus.Fields{
+		"namespace": w.namespace,
+		"entity_id": w.entityID,
+		"filename":  w.filename,
+		"changed":   w.actuallyChanging,
+		"tombstone": w.header.Tombstone,
+		"dir":       w.header.Directory,
+		"row_guid":  w.header.RowGuid,
+	}).Infof("writeOrDeleteFile done")
+}
 
-		fields["authorship_metadata"] = authorshipBytes
+// revisionConflictError reports a write based on a revision that is no
+// longer current, with a RevisionConflict detail for callers to check for.
+func revisionConflictError(expected, current string) error {
+	st := status.Newf(codes.FailedPrecondition, "Conflict: modification of %q but last revision was %q", expected, current)
+	withDetails, err := st.WithDetails(&pb.RevisionConflict{
+		ExpectedRowGuid: expected,
+		CurrentRowGuid:  current,
+	})
+	if err != nil {
+		logrus.Errorf("Failed to attach conflict details: %v", err)
+		return st.Err()
+	}
+	return withDetails.Err()
+}
 
-		if err := d.stmtInsertNewRow.Exec(ctx, tx, fields); err != nil {
+func (d *Database) writeOrDeleteFile(ctx context.Context, w *pendingWrite) (*pb.EntityFileHeader, error) {
+	if err := writeFileTx(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		if err := d.checkSchema(ctx, tx, w); err != nil {
 			return err
 		}
-
-		return nil
+		return d.applyWriteOrDelete(ctx, tx, w)
 	}); err != nil {
 		return nil, err
 	}
 
-	if actuallyChanging {
+	if w.actuallyChanging {
 		d.onChange()
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"namespace": namespace,
-		"entity_id": entityID,
-		"filename":  filename,
-		"changed":   actuallyChanging,
-		"tombstone": returnedHeader.Tombstone,
-		"dir":       returnedHeader.Directory,
-		"row_guid":  returnedHeader.RowGuid,
-	}).Infof("writeOrDeleteFile done")
+	w.logDone()
 
-	return returnedHeader, nil
+	return w.header, nil
 }
 
-func (d *Database) WriteFile(ctx context.Context, req *pb.WriteFileRequest) (*pb.WriteFileResponse, error) {
+func (d *Database) prepareWriteFile(req *pb.WriteFileRequest) (*pendingWrite, error) {
 	if !qmfsquery.ValidPath(req.GetFilename()) {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid filename: %q", req.GetFilename())
 	}
@@ -496,7 +828,28 @@ func (d *Database) WriteFile(ctx context.Context, req *pb.WriteFileRequest) (*pb
 		replaceType = pb.DeletionType_DELETE_NONE
 	}
 
-	header, err := d.writeOrDeleteFile(ctx, req.GetNamespace(), req.GetEntityId(), req.GetFilename(), req.GetOldRevisionGuid(), false, req.GetData(), req.GetAuthorshipMetadata(), req.GetDirectory(), replaceType)
+	w, err := d.prepareWriteOrDelete(req.GetNamespace(), req.GetEntityId(), req.GetFilename(), req.GetOldRevisionGuid(), false, req.GetData(), req.GetAuthorshipMetadata(), req.GetDirectory(), replaceType)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetExpiresAt() != nil {
+		if req.GetDirectory() {
+			return nil, status.Errorf(codes.InvalidArgument, "directories cannot expire")
+		}
+		w.setExpiresAt(req.GetExpiresAt().GetUnixNano())
+	}
+
+	return w, nil
+}
+
+func (d *Database) WriteFile(ctx context.Context, req *pb.WriteFileRequest) (*pb.WriteFileResponse, error) {
+	w, err := d.prepareWriteFile(req)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := d.writeOrDeleteFile(ctx, w)
 	if err != nil {
 		return nil, err
 	}
@@ -508,7 +861,7 @@ func (d *Database) WriteFile(ctx context.Context, req *pb.WriteFileRequest) (*pb
 	}, nil
 }
 
-func (d *Database) DeleteFile(ctx context.Context, req *pb.DeleteFileRequest) (*pb.DeleteFileResponse, error) {
+func (d *Database) prepareDeleteFile(req *pb.DeleteFileRequest) (*pendingWrite, error) {
 	switch req.GetDeletionType() {
 	case pb.DeletionType_DELETE_ANY:
 	case pb.DeletionType_DELETE_FILE:
@@ -519,7 +872,16 @@ func (d *Database) DeleteFile(ctx context.Context, req *pb.DeleteFileRequest) (*
 		return nil, status.Errorf(codes.InvalidArgument, "invalid deletion_type (%v)", req.GetDeletionType())
 	}
 
-	header, err := d.writeOrDeleteFile(ctx, req.GetNamespace(), req.GetEntityId(), req.GetFilename(), req.GetOldRevisionGuid(), true, nil, req.GetAuthorshipMetadata(), false, req.GetDeletionType())
+	return d.prepareWriteOrDelete(req.GetNamespace(), req.GetEntityId(), req.GetFilename(), req.GetOldRevisionGuid(), true, nil, req.GetAuthorshipMetadata(), false, req.GetDeletionType())
+}
+
+func (d *Database) DeleteFile(ctx context.Context, req *pb.DeleteFileRequest) (*pb.DeleteFileResponse, error) {
+	w, err := d.prepareDeleteFile(req)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := d.writeOrDeleteFile(ctx, w)
 	if err != nil {
 		return nil, err
 	}
@@ -532,7 +894,17 @@ func (d *Database) DeleteFile(ctx context.Context, req *pb.DeleteFileRequest) (*
 func (d *Database) prepareStatements() error {
 	var err error
 
-	d.stmtMarkOldRowsInactive = d.db.PrepareExec(&err, "qmfsdb-mark-old-rows-inactive", `
+	if d.opts.KeepRevisionData {
+		d.stmtMarkOldRowsInactive = d.db.PrepareExec(&err, "qmfsdb-mark-old-rows-inactive-keeping-data", `
+UPDATE items
+SET    active = 0
+WHERE  entity_id = :entity_id
+AND    filename = :filename
+AND    namespace = :namespace
+;
+`)
+	} else {
+		d.stmtMarkOldRowsInactive = d.db.PrepareExec(&err, "qmfsdb-mark-old-rows-inactive", `
 UPDATE items
 SET    active = 0, trimmed_data = NULL, whitespace_prefix = NULL, whitespace_suffix = NULL
 WHERE  entity_id = :entity_id
@@ -540,6 +912,7 @@ AND    filename = :filename
 AND    namespace = :namespace
 ;
 `)
+	}
 
 	d.stmtInsertNewRow = d.db.PrepareExec(&err, "qmfsdb-insert-row", `
 INSERT INTO items
@@ -547,13 +920,13 @@ INSERT INTO items
 	 sha256_hash, trimmed_sha256_hash, data_length, trimmed_data_length,
 	 authorship_metadata, namespace, directory,
 	 whitespace_prefix, trimmed_data, whitespace_suffix,
-   entity_id_shard1, entity_id_shard2)
+   entity_id_shard1, entity_id_shard2, expires_at_unix_nano)
 VALUES
 	(:row_guid, :tombstone, :active, :timestamp_unix_nano, :entity_id, :filename,
 	:sha256_hash, :trimmed_sha256_hash, :data_length, :trimmed_data_length,
 	:authorship_metadata, :namespace, :directory,
 	:whitespace_prefix, :trimmed_data, :whitespace_suffix,
-  :entity_id_shard1, :entity_id_shard2)
+  :entity_id_shard1, :entity_id_shard2, :expires_at_unix_nano)
 ;
 `)
 
@@ -620,13 +993,53 @@ ORDER BY entity_id, filename
 SELECT namespace, entity_id, filename, row_guid, timestamp_unix_nano,
        sha256_hash, data_length, trimmed_sha256_hash, trimmed_data_length,
 			 whitespace_prefix, trimmed_data, whitespace_suffix,
-			 directory
+			 directory, expires_at_unix_nano
 FROM items
 WHERE active=1
 AND   tombstone=0
 AND   namespace = :namespace
 AND   entity_id = :entity_id
 AND   filename = :filename
+`)
+
+	d.queryReadFileAsOf = d.db.PrepareQuery(&err, "qmfsdb-query-read-file-as-of", `
+SELECT namespace, entity_id, filename, row_guid, timestamp_unix_nano,
+       sha256_hash, data_length, trimmed_sha256_hash, trimmed_data_length,
+			 whitespace_prefix, trimmed_data, whitespace_suffix,
+			 directory, expires_at_unix_nano
+FROM items AS cur
+WHERE `+liveRowCondition("cur", true)+`
+AND   namespace = :namespace
+AND   entity_id = :entity_id
+AND   filename = :filename
+`)
+
+	d.queryEntityFileHeadersAsOf = d.db.PrepareQuery(&err, "qmfsdb-query-entity-file-headers-as-of", `
+SELECT entity_id, filename, row_guid, timestamp_unix_nano,
+       sha256_hash, data_length, trimmed_sha256_hash, trimmed_data_length,
+			 directory
+FROM items AS cur
+WHERE `+liveRowCondition("cur", true)+`
+AND   namespace = :namespace
+AND   entity_id = :entity_id
+ORDER BY entity_id, filename
+`)
+
+	d.queryAllEntitiesAsOf = d.db.PrepareQuery(&err, "qmfsdb-query-all-entities-as-of", `
+SELECT DISTINCT entity_id
+FROM items AS cur
+WHERE `+liveRowCondition("cur", true)+`
+AND   namespace = :namespace
+ORDER BY entity_id
+`)
+
+	d.queryEntitiesByFilenameAsOf = d.db.PrepareQuery(&err, "qmfsdb-query-all-entities-with-filename-as-of", `
+SELECT DISTINCT entity_id
+FROM items AS cur
+WHERE `+liveRowCondition("cur", true)+`
+AND   namespace = :namespace
+AND   filename = :filename
+ORDER BY entity_id
 `)
 
 	d.queryListNamespaces = d.db.PrepareQuery(&err, "qmfsdb-query-list-namespaces", `
@@ -639,12 +1052,176 @@ WHERE active=1 AND tombstone=0
 SELECT sharding_key_bytes
 FROM sharding_key
 LIMIT 1
+`)
+
+	d.queryListFileRevisions = d.db.PrepareQuery(&err, "qmfsdb-query-list-file-revisions", `
+SELECT namespace, entity_id, filename, row_guid, timestamp_unix_nano,
+       sha256_hash, data_length, trimmed_sha256_hash, trimmed_data_length,
+			 tombstone, active, directory, authorship_metadata,
+			 (whitespace_prefix IS NOT NULL OR trimmed_data IS NOT NULL OR whitespace_suffix IS NOT NULL) AS has_data
+FROM items
+WHERE namespace = :namespace
+AND   entity_id = :entity_id
+AND   filename = :filename
+ORDER BY timestamp_unix_nano, row_guid
+`)
+
+	d.queryReadFileRevision = d.db.PrepareQuery(&err, "qmfsdb-query-read-file-revision", `
+SELECT namespace, entity_id, filename, row_guid, timestamp_unix_nano,
+       sha256_hash, data_length, trimmed_sha256_hash, trimmed_data_length,
+			 whitespace_prefix, trimmed_data, whitespace_suffix,
+			 tombstone, active, directory, authorship_metadata,
+			 expires_at_unix_nano
+FROM items
+WHERE namespace = :namespace
+AND   entity_id = :entity_id
+AND   filename = :filename
+AND   row_guid = :row_guid
+`)
+
+	d.queryReadFileRevisionHeader = d.db.PrepareQuery(&err, "qmfsdb-query-read-file-revision-header", `
+SELECT namespace, entity_id, filename, row_guid, timestamp_unix_nano,
+       sha256_hash, data_length, trimmed_sha256_hash, trimmed_data_length,
+			 NULL AS whitespace_prefix, NULL AS trimmed_data, NULL AS whitespace_suffix,
+			 tombstone, active, directory, authorship_metadata,
+			 expires_at_unix_nano
+FROM items
+WHERE namespace = :namespace
+AND   entity_id = :entity_id
+AND   filename = :filename
+AND   row_guid = :row_guid
+`)
+
+	d.queryChangesAfter = d.db.PrepareQuery(&err, "qmfsdb-query-changes-after", `
+SELECT sequence, namespace, entity_id, filename, row_guid, timestamp_unix_nano,
+       sha256_hash, data_length, trimmed_sha256_hash, trimmed_data_length,
+			 tombstone, directory
+FROM items
+WHERE sequence > :after_sequence
+AND   (:all_namespaces = 1 OR namespace = :namespace)
+ORDER BY sequence
+LIMIT :limit
+`)
+
+	d.queryLatestSequence = d.db.PrepareQuery(&err, "qmfsdb-query-latest-sequence", `
+SELECT MAX(sequence) AS sequence
+FROM items
+`)
+
+	d.queryExportRevisions = d.db.PrepareQuery(&err, "qmfsdb-query-export-revisions", `
+SELECT namespace, entity_id, filename, row_guid, timestamp_unix_nano,
+       sha256_hash, data_length, trimmed_sha256_hash, trimmed_data_length,
+			 whitespace_prefix, trimmed_data, whitespace_suffix,
+			 tombstone, active, directory, authorship_metadata,
+			 expires_at_unix_nano
+FROM items
+WHERE (:all_namespaces = 1 OR namespace = :namespace)
+AND   (:include_history = 1 OR (active = 1 AND tombstone = 0))
+ORDER BY sequence
+`)
+
+	d.queryRowExists = d.db.PrepareQuery(&err, "qmfsdb-query-row-exists", `
+SELECT row_guid
+FROM items
+WHERE row_guid = :row_guid
+`)
+
+	d.queryCurrentRevision = d.db.PrepareQuery(&err, "qmfsdb-query-current-revision", `
+SELECT row_guid, timestamp_unix_nano
+FROM items
+WHERE active = 1
+AND   namespace = :namespace
+AND   entity_id = :entity_id
+AND   filename = :filename
+`)
+
+	d.querySyncRevisions = d.db.PrepareQuery(&err, "qmfsdb-query-sync-revisions", `
+SELECT sequence, namespace, entity_id, filename, row_guid, timestamp_unix_nano,
+       sha256_hash, data_length, trimmed_sha256_hash, trimmed_data_length,
+			 whitespace_prefix, trimmed_data, whitespace_suffix,
+			 tombstone, active, directory, authorship_metadata,
+			 expires_at_unix_nano
+FROM items
+WHERE sequence > :after_sequence
+ORDER BY sequence
+LIMIT :limit
+`)
+
+	d.querySyncWatermarks = d.db.PrepareQuery(&err, "qmfsdb-query-sync-watermarks", `
+SELECT pulled_sequence, pushed_sequence
+FROM sync_peers
+WHERE peer = :peer
+`)
+
+	d.stmtSetSyncWatermarks = d.db.PrepareExec(&err, "qmfsdb-set-sync-watermarks", `
+INSERT OR REPLACE INTO sync_peers
+  (peer, pulled_sequence, pushed_sequence)
+VALUES
+  (:peer, :pulled_sequence, :pushed_sequence)
+`)
+
+	d.queryEntityExists = d.db.PrepareQuery(&err, "qmfsdb-query-entity-exists", `
+SELECT entity_id
+FROM items
+WHERE active = 1
+AND   tombstone = 0
+AND   namespace = :namespace
+AND   entity_id = :entity_id
+LIMIT 1
+`)
+
+	d.queryExpiredFiles = d.db.PrepareQuery(&err, "qmfsdb-query-expired-files", `
+SELECT namespace, entity_id, filename, row_guid
+FROM items
+WHERE active = 1
+AND   tombstone = 0
+AND   expires_at_unix_nano IS NOT NULL
+AND   expires_at_unix_nano <= :now_unix_nano
+LIMIT :limit
+`)
+
+	d.queryCountSupersededRevisions = d.db.PrepareQuery(&err, "qmfsdb-query-count-superseded-revisions", `
+SELECT COUNT(1) AS count
+FROM items
+WHERE `+supersededRevisionCondition+`
+`)
+
+	d.stmtDeleteSupersededRevisions = d.db.PrepareExec(&err, "qmfsdb-delete-superseded-revisions", `
+DELETE FROM items
+WHERE `+supersededRevisionCondition+`
+;
+`)
+
+	d.queryCountExpiredTombstones = d.db.PrepareQuery(&err, "qmfsdb-query-count-expired-tombstones", `
+SELECT COUNT(1) AS count
+FROM items
+WHERE `+expiredTombstoneCondition+`
+`)
+
+	d.stmtDeleteExpiredTombstones = d.db.PrepareExec(&err, "qmfsdb-delete-expired-tombstones", `
+DELETE FROM items
+WHERE `+expiredTombstoneCondition+`
+;
+`)
+
+	d.queryCompactionHorizon = d.db.PrepareQuery(&err, "qmfsdb-query-compaction-horizon", `
+SELECT horizon_unix_nano
+FROM compaction_horizon
+WHERE id = 0
+`)
+
+	d.stmtRaiseCompactionHorizon = d.db.PrepareExec(&err, "qmfsdb-raise-compaction-horizon", `
+INSERT OR REPLACE INTO compaction_horizon
+  (id, horizon_unix_nano)
+SELECT 0, MAX(:cutoff_unix_nano, COALESCE((SELECT horizon_unix_nano FROM compaction_horizon WHERE id = 0), 0))
 `)
 
 	return err
 }
 
 func (d *Database) Close() error {
+	d.stopExpiryAndWait()
+
 	return d.db.Close()
 }
 
@@ -687,6 +1264,8 @@ func (d *Database) onStartup(ctx context.Context) error {
 }
 
 func (d *Database) onChange() {
+	d.notifyWatchers()
+
 	if d.opts.ChangeHook != nil {
 		d.opts.ChangeHook()
 	}
@@ -703,8 +1282,9 @@ func Open(ctx context.Context, localDBFilename string, opts *Options) (*Database
 	}
 
 	rv := &Database{
-		db:   db,
-		opts: *opts,
+		db:       db,
+		filename: localDBFilename,
+		opts:     *opts,
 	}
 
 	if err := rv.prepareStatements(); err != nil {
@@ -716,6 +1296,10 @@ func Open(ctx context.Context, localDBFilename string, opts *Options) (*Database
 		return nil, err
 	}
 
+	if rv.opts.DeleteExpiredFiles {
+		rv.startExpiry()
+	}
+
 	return rv, nil
 }
 
@@ -733,6 +1317,7 @@ type fullFileData struct {
 	TrimmedData       []byte
 	WhitespaceSuffix  []byte
 	Directory         bool
+	ExpiresAtUnixNano *int64
 }
 
 func hasDataEqualTo(f *fullFileData, data []byte) bool {
@@ -818,13 +1403,20 @@ func (d *Database) ReadFile(ctx context.Context, req *pb.ReadFileRequest) (*pb.R
 
 	success := false
 
+	prepq := d.queryReadFile
+	argmap := map[string]interface{}{
+		"namespace": namespace,
+		"entity_id": entityID,
+		"filename":  filename,
+	}
+	if req.GetAsOf() != nil {
+		prepq = d.queryReadFileAsOf
+		argmap["as_of_unix_nano"] = req.GetAsOf().GetUnixNano()
+	}
+
 	var row fullFileData
 	err := readFileTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
-		return d.queryReadFile.Query(ctx, tx, map[string]interface{}{
-			"namespace": namespace,
-			"entity_id": entityID,
-			"filename":  filename,
-		}, &row, func() (bool, error) {
+		return prepq.Query(ctx, tx, argmap, &row, func() (bool, error) {
 			success = true
 			return false, nil
 		})
@@ -837,8 +1429,25 @@ func (d *Database) ReadFile(ctx context.Context, req *pb.ReadFileRequest) (*pb.R
 		return nil, status.Errorf(codes.NotFound, "File not found: entity_id=%q filename=%q", entityID, filename)
 	}
 
+	file := row.entityFile()
+	if file == nil {
+		return nil, status.Errorf(codes.NotFound, "Contents of file not retained: entity_id=%q filename=%q row_guid=%q", entityID, filename, row.RowGUID)
+	}
+
+	return &pb.ReadFileResponse{
+		File: file,
+	}, nil
+}
+
+// entityFile returns the file held by the row, or nil if its contents were
+// not retained, which is only possible for old revisions.
+func (row *fullFileData) entityFile() *pb.EntityFile {
 	data := append(row.WhitespacePrefix, append(row.TrimmedData, row.WhitespaceSuffix...)...)
 
+	if int64(len(data)) != row.DataLength {
+		return nil
+	}
+
 	hdr := &pb.EntityFileHeader{
 		Namespace: row.Namespace,
 		EntityId:  row.EntityID,
@@ -854,14 +1463,13 @@ func (d *Database) ReadFile(ctx context.Context, req *pb.ReadFileRequest) (*pb.R
 		},
 		RowGuid:   row.RowGUID,
 		Directory: row.Directory,
+		ExpiresAt: expiresAtTimestamp(row.ExpiresAtUnixNano),
 	}
 
-	return &pb.ReadFileResponse{
-		File: &pb.EntityFile{
-			Header: hdr,
-			Data:   data,
-		},
-	}, nil
+	return &pb.EntityFile{
+		Header: hdr,
+		Data:   data,
+	}
 }
 
 var listNamespacesTransactor = sqlitedb.Transactor("ListNamespaces")
@@ -952,27 +1560,71 @@ func (d *Database) GetDatabaseMetadata(ctx context.Context, req *pb.GetDatabaseM
 }
 
 func andJoinSQL(clauses []string) string {
+	if len(clauses) == 0 {
+		return "1"
+	}
 	return "(" + strings.Join(clauses, ") AND (") + ")"
 }
 
-func (d *Database) prepareDynamicEntitiesQuery(ctx context.Context, namespace string, query *pb.EntitiesQuery) (*sqlitedb.PreparedQuery, map[string]interface{}, func(context.Context, string) (bool, error), error) {
+// liveRowCondition returns an SQL condition selecting the rows of the
+// table aliased as tbl that hold the current contents of a file that exists.
+// If asOf is set, "current" is instead relative to the :as_of_unix_nano
+// parameter, i.e. it selects the newest row no newer than that for each file.
+func liveRowCondition(tbl string, asOf bool) string {
+	if !asOf {
+		return tbl + ".active=1 AND " + tbl + ".tombstone=0"
+	}
+
+	return strings.Replace(`{tbl}.tombstone=0
+AND {tbl}.timestamp_unix_nano <= :as_of_unix_nano
+AND NOT EXISTS (
+	SELECT 1 FROM items AS later_{tbl}
+	WHERE later_{tbl}.namespace = {tbl}.namespace
+	AND   later_{tbl}.entity_id = {tbl}.entity_id
+	AND   later_{tbl}.filename = {tbl}.filename
+	AND   later_{tbl}.timestamp_unix_nano > {tbl}.timestamp_unix_nano
+	AND   later_{tbl}.timestamp_unix_nano <= :as_of_unix_nano
+)`, "{tbl}", tbl, -1)
+}
+
+// checkContentsAsOf rejects uses of file contents in queries about the past
+// unless old revisions keep their contents (see Options.KeepRevisionData).
+// Otherwise superseded revisions read as empty, which would silently give
+// wrong results.
+func (d *Database) checkContentsAsOf(asOf bool, what string) error {
+	if asOf && !d.opts.KeepRevisionData {
+		return status.Errorf(codes.FailedPrecondition, "query error: %s cannot be applied to past states unless the contents of old revisions are kept", what)
+	}
+	return nil
+}
+
+// entityCheck is a condition that cannot be expressed in SQL, checked
+// against the named files of each entity selected by the SQL.
+type entityCheck struct {
+	filenames []string
+	matches   func(files map[string]*pb.EntityFile) bool
+}
+
+// compileEntitiesQuery returns SQL selecting the entity_id of each entity
+// matching the query, along with its arguments and any check that must be
+// applied to the results afterwards.
+func (d *Database) compileEntitiesQuery(asOf bool, query *pb.EntitiesQuery) (string, map[string]interface{}, *entityCheck, error) {
 	sqlquery := `
 SELECT DISTINCT base.entity_id AS entity_id
 FROM items AS base
 `
 
 	whereClauses := []string{
-		"base.active=1",
-		"base.tombstone=0",
+		liveRowCondition("base", asOf),
 		"base.namespace = :namespace",
 	}
 
 	moreArgs := map[string]interface{}{}
 
-	basicJoinExpr := "{tbl}.namespace = :namespace AND base.entity_id = {tbl}.entity_id AND {tbl}.active=1 AND {tbl}.tombstone=0"
+	basicJoinExpr := "{tbl}.namespace = :namespace AND base.entity_id = {tbl}.entity_id AND " + liveRowCondition("{tbl}", asOf)
 
 	nextTable := 1
-	addCondition := func(moreJoinexpr, condexpr string, invert bool) {
+	addCondition := func(moreJoinexpr, condexpr string, invert bool) string {
 		tblName := fmt.Sprintf("j%d", nextTable)
 		nextTable++
 		joinexpr := basicJoinExpr + " AND " + moreJoinexpr
@@ -982,11 +1634,18 @@ FROM items AS base
 		if invert {
 			condexprRepl = "NOT (" + condexprRepl + ")"
 		}
-		whereClauses = append(whereClauses, condexprRepl)
+		return condexprRepl
 	}
 
 	var orderLimitSection string
 
+	var orderings []string
+	var limitVar, offsetVar string
+
+	// Checks that cannot be expressed in SQL, applied to each resulting entity.
+	var checks []func(map[string]*pb.EntityFile) bool
+	var checkFilenames []string
+
 	nextVar := 1
 	assocVariable := func(value interface{}) string {
 		varName := fmt.Sprintf("var%d", nextVar)
@@ -996,117 +1655,374 @@ FROM items AS base
 		return ":" + varName
 	}
 
-	for _, clause := range query.Clause {
-		switch value := clause.Kind.(type) {
-		case *pb.EntitiesQuery_Clause_FileExists:
-			varname := assocVariable(value.FileExists)
-			addCondition(
-				"{tbl}.filename = "+varname,
-				"{tbl}.row_guid IS NOT NULL",
-				clause.Invert)
-
-		case *pb.EntitiesQuery_Clause_EntityId:
-			varname := assocVariable(value.EntityId)
-			if clause.Invert {
-				addCondition(
-					"{tbl}.entity_id = "+varname,
-					"{tbl}.row_guid IS NULL",
-					false)
-			} else {
-				whereClauses = append(whereClauses, "base.entity_id = "+varname)
-			}
+	// compileClauses returns the conditions for each clause, to be combined
+	// with AND. Clauses nested within a disjunction may not affect ordering.
+	var compileClauses func(clauses []*pb.EntitiesQuery_Clause, nested bool) ([]string, error)
+	compileClauses = func(clauses []*pb.EntitiesQuery_Clause, nested bool) ([]string, error) {
+		var conds []string
+
+		for _, clause := range clauses {
+			switch value := clause.Kind.(type) {
+			case *pb.EntitiesQuery_Clause_FileExists:
+				varname := assocVariable(value.FileExists)
+				conds = append(conds, addCondition(
+					"{tbl}.filename = "+varname,
+					"{tbl}.row_guid IS NOT NULL",
+					clause.Invert))
+
+			case *pb.EntitiesQuery_Clause_EntityId:
+				varname := assocVariable(value.EntityId)
+				if clause.Invert {
+					conds = append(conds, addCondition(
+						"{tbl}.entity_id = "+varname,
+						"{tbl}.row_guid IS NULL",
+						false))
+				} else {
+					conds = append(conds, "base.entity_id = "+varname)
+				}
 
-		case *pb.EntitiesQuery_Clause_Shard:
-			shards := value.Shard.Shard
-			if len(shards) > 2 || len(shards) < 1 {
-				return nil, nil, nil, status.Errorf(codes.InvalidArgument, "invalid number of shards: %d (%v)", len(shards), shards)
-			}
-			shard1 := assocVariable(shards[0])
-			var shard2 string
-			if len(shards) >= 2 {
-				shard2 = assocVariable(shards[1])
-			}
-			if clause.Invert {
-				cond := "{tbl}.entity_id_shard1 = " + shard1
-				if len(shards) >= 2 {
-					cond += " AND {tbl}.entity_id_shard2 = " + shard2
+			case *pb.EntitiesQuery_Clause_Shard:
+				shards := value.Shard.Shard
+				if len(shards) > 2 || len(shards) < 1 {
+					return nil, status.Errorf(codes.InvalidArgument, "invalid number of shards: %d (%v)", len(shards), shards)
 				}
-				addCondition(cond, "{tbl}.row_guid IS NULL", false)
-			} else {
-				whereClauses = append(whereClauses, "base.entity_id_shard1 = "+shard1)
+				shard1 := assocVariable(shards[0])
+				var shard2 string
 				if len(shards) >= 2 {
-					whereClauses = append(whereClauses, "base.entity_id_shard2 = "+shard2)
+					shard2 = assocVariable(shards[1])
+				}
+				if clause.Invert {
+					cond := "{tbl}.entity_id_shard1 = " + shard1
+					if len(shards) >= 2 {
+						cond += " AND {tbl}.entity_id_shard2 = " + shard2
+					}
+					conds = append(conds, addCondition(cond, "{tbl}.row_guid IS NULL", false))
+				} else {
+					conds = append(conds, "base.entity_id_shard1 = "+shard1)
+					if len(shards) >= 2 {
+						conds = append(conds, "base.entity_id_shard2 = "+shard2)
+					}
 				}
-			}
 
-		case *pb.EntitiesQuery_Clause_Random:
-			n := int(value.Random.GetNumber())
+			case *pb.EntitiesQuery_Clause_Random:
+				n := int(value.Random.GetNumber())
 
-			if n <= 0 {
-				return nil, nil, nil, fmt.Errorf("query error: random[] cannot take a non-positive value, got %d", n)
-			}
+				if n <= 0 {
+					return nil, fmt.Errorf("query error: random[] cannot take a non-positive value, got %d", n)
+				}
 
-			if clause.Invert {
-				return nil, nil, nil, fmt.Errorf("query error: random[] cannot be inverted")
-			}
+				if clause.Invert {
+					return nil, fmt.Errorf("query error: random[] cannot be inverted")
+				}
 
-			if orderLimitSection != "" {
-				return nil, nil, nil, fmt.Errorf("query error: cannot have multiple selection clauses")
-			}
+				if nested {
+					return nil, fmt.Errorf("query error: random[] cannot be part of a disjunction")
+				}
 
-			numVar := assocVariable(fmt.Sprintf("%d", n))
+				if orderLimitSection != "" {
+					return nil, fmt.Errorf("query error: cannot have multiple selection clauses")
+				}
 
-			orderLimitSection = "ORDER BY RANDOM() LIMIT " + numVar
+				numVar := assocVariable(fmt.Sprintf("%d", n))
 
-		case *pb.EntitiesQuery_Clause_FileContents:
-			contents := []byte(value.FileContents.GetContents())
-			filename := value.FileContents.GetFilename()
+				orderLimitSection = "ORDER BY RANDOM() LIMIT " + numVar
 
-			checksums, err := computeFileMetadata(contents)
-			if err != nil {
-				return nil, nil, nil, status.Errorf(codes.Internal, "error computing checksums: %v", err)
-			}
+			case *pb.EntitiesQuery_Clause_FileContents:
+				contents := []byte(value.FileContents.GetContents())
+				filename := value.FileContents.GetFilename()
 
-			varFilename := assocVariable(filename)
-			varTrimmedLength := assocVariable(int64(checksums.TrimmedLength))
-			varTrimmedSha256 := assocVariable(checksums.TrimmedSha256)
+				checksums, err := computeFileMetadata(contents)
+				if err != nil {
+					return nil, status.Errorf(codes.Internal, "error computing checksums: %v", err)
+				}
+
+				varFilename := assocVariable(filename)
+				varTrimmedLength := assocVariable(int64(checksums.TrimmedLength))
+				varTrimmedSha256 := assocVariable(checksums.TrimmedSha256)
+
+				trimmedData := string(contents)
+
+				suffix := ""
+				switch {
+				case asOf:
+					// Superseded revisions may have had their contents
+					// discarded, so only the checksums can be compared.
+				case len(trimmedData) > 0:
+					varTrimmedData := assocVariable([]byte(trimmedData))
+					suffix = " AND {tbl}.trimmed_data = " + varTrimmedData
+				default:
+					suffix = " AND {tbl}.trimmed_data IS NULL "
+				}
+
+				conds = append(conds, addCondition(
+					"{tbl}.filename = "+varFilename+
+						" AND {tbl}.trimmed_data_length = "+varTrimmedLength+
+						" AND {tbl}.trimmed_sha256_hash = "+varTrimmedSha256+
+						suffix,
+					"{tbl}.row_guid IS NOT NULL",
+					clause.Invert))
+
+			case *pb.EntitiesQuery_Clause_Compare:
+				if err := d.checkContentsAsOf(asOf, "comparison"); err != nil {
+					return nil, err
+				}
+
+				cmp := value.Compare
+
+				var sqlop string
+				switch cmp.GetOperator() {
+				case pb.EntitiesQuery_Clause_FileComparison_LESS:
+					sqlop = "<"
+				case pb.EntitiesQuery_Clause_FileComparison_LESS_OR_EQUAL:
+					sqlop = "<="
+				case pb.EntitiesQuery_Clause_FileComparison_GREATER:
+					sqlop = ">"
+				case pb.EntitiesQuery_Clause_FileComparison_GREATER_OR_EQUAL:
+					sqlop = ">="
+				default:
+					return nil, status.Errorf(codes.InvalidArgument, "invalid comparison operator (%v)", cmp.GetOperator())
+				}
+
+				varFilename := assocVariable(cmp.GetFilename())
+				textExpr := "COALESCE(CAST({tbl}.trimmed_data AS TEXT), '')"
+
+				var cmpExpr string
+				switch cmp.GetSemantics() {
+				case pb.EntitiesQuery_Clause_FileComparison_NUMERIC:
+					n, err := strconv.ParseFloat(strings.TrimSpace(cmp.GetValue()), 64)
+					if err != nil {
+						return nil, status.Errorf(codes.InvalidArgument, "not a number: %q", cmp.GetValue())
+					}
+					varValue := assocVariable(n)
+					cmpExpr = numericTextCondition(textExpr) + " AND CAST(" + textExpr + " AS REAL) " + sqlop + " " + varValue
+
+				case pb.EntitiesQuery_Clause_FileComparison_STRING:
+					varValue := assocVariable(strings.TrimSpace(cmp.GetValue()))
+					cmpExpr = textExpr + " " + sqlop + " " + varValue
+
+				default:
+					return nil, status.Errorf(codes.InvalidArgument, "invalid comparison semantics (%v)", cmp.GetSemantics())
+				}
 
-			trimmedData := string(contents)
+				conds = append(conds, addCondition(
+					"{tbl}.filename = "+varFilename+" AND "+cmpExpr,
+					"{tbl}.row_guid IS NOT NULL",
+					clause.Invert))
 
-			suffix := ""
-			if len(trimmedData) > 0 {
-				varTrimmedData := assocVariable([]byte(trimmedData))
-				suffix = " AND {tbl}.trimmed_data = " + varTrimmedData
-			} else {
-				suffix = " AND {tbl}.trimmed_data IS NULL "
+			case *pb.EntitiesQuery_Clause_Sort:
+				if clause.Invert {
+					return nil, fmt.Errorf("query error: sort[] cannot be inverted")
+				}
+				if nested {
+					return nil, fmt.Errorf("query error: sort[] cannot be part of a disjunction")
+				}
+				if err := d.checkContentsAsOf(asOf, "sort[]"); err != nil {
+					return nil, err
+				}
+
+				varFilename := assocVariable(value.Sort.GetFilename())
+				tblName := fmt.Sprintf("j%d", nextTable)
+				// Always true; the join only serves to make the contents available for ordering.
+				conds = append(conds, addCondition("{tbl}.filename = "+varFilename, "1", false))
+
+				textExpr := "CAST(" + tblName + ".trimmed_data AS TEXT)"
+				if value.Sort.GetNumeric() {
+					textExpr = "CAST(" + textExpr + " AS REAL)"
+				}
+				direction := "ASC"
+				if value.Sort.GetDescending() {
+					direction = "DESC"
+				}
+				orderings = append(orderings, tblName+".row_guid IS NULL", textExpr+" "+direction)
+
+			case *pb.EntitiesQuery_Clause_Limit, *pb.EntitiesQuery_Clause_Offset:
+				if clause.Invert {
+					return nil, fmt.Errorf("query error: limit[] and offset[] cannot be inverted")
+				}
+				if nested {
+					return nil, fmt.Errorf("query error: limit[] and offset[] cannot be part of a disjunction")
+				}
+
+				if n := clause.GetLimit(); n < 0 {
+					return nil, fmt.Errorf("query error: limit[] cannot take a negative value, got %d", n)
+				}
+				if n := clause.GetOffset(); n < 0 {
+					return nil, fmt.Errorf("query error: offset[] cannot take a negative value, got %d", n)
+				}
+
+				if _, ok := value.(*pb.EntitiesQuery_Clause_Limit); ok {
+					if limitVar != "" {
+						return nil, fmt.Errorf("query error: cannot have multiple limit[] clauses")
+					}
+					limitVar = assocVariable(int64(clause.GetLimit()))
+				} else {
+					if offsetVar != "" {
+						return nil, fmt.Errorf("query error: cannot have multiple offset[] clauses")
+					}
+					offsetVar = assocVariable(int64(clause.GetOffset()))
+				}
+
+			case *pb.EntitiesQuery_Clause_Matches:
+				if err := d.checkContentsAsOf(asOf, "pattern matching"); err != nil {
+					return nil, err
+				}
+
+				match := value.Matches
+
+				if match.GetMode() == pb.EntitiesQuery_Clause_FileMatchesPattern_REGEX {
+					// SQLite has no built-in regex support, so the SQL only
+					// narrows down the candidates and the match is checked afterwards.
+					if nested {
+						return nil, fmt.Errorf("query error: regex[] cannot be part of a disjunction")
+					}
+
+					re, err := regexp.Compile(match.GetPattern())
+					if err != nil {
+						return nil, status.Errorf(codes.InvalidArgument, "bad regex %q: %v", match.GetPattern(), err)
+					}
+
+					filename := match.GetFilename()
+					invert := clause.Invert
+
+					if !invert {
+						varFilename := assocVariable(filename)
+						conds = append(conds, addCondition(
+							"{tbl}.filename = "+varFilename,
+							"{tbl}.row_guid IS NOT NULL",
+							false))
+					}
+
+					checkFilenames = append(checkFilenames, filename)
+					checks = append(checks, func(files map[string]*pb.EntityFile) bool {
+						file, ok := files[filename]
+						if !ok {
+							return invert
+						}
+						trimmed := strings.TrimSpace(string(file.GetData()))
+						return re.MatchString(trimmed) != invert
+					})
+					continue
+				}
+
+				varFilename := assocVariable(match.GetFilename())
+				varPattern := assocVariable(match.GetPattern())
+				textExpr := "COALESCE(CAST({tbl}.trimmed_data AS TEXT), '')"
+
+				var matchExpr string
+				switch match.GetMode() {
+				case pb.EntitiesQuery_Clause_FileMatchesPattern_PREFIX:
+					matchExpr = "substr(" + textExpr + ", 1, length(" + varPattern + ")) = " + varPattern
+
+				case pb.EntitiesQuery_Clause_FileMatchesPattern_CONTAINS:
+					matchExpr = "instr(" + textExpr + ", " + varPattern + ") > 0"
+
+				case pb.EntitiesQuery_Clause_FileMatchesPattern_GLOB:
+					matchExpr = textExpr + " GLOB " + varPattern
+
+				default:
+					return nil, status.Errorf(codes.InvalidArgument, "invalid pattern mode (%v)", match.GetMode())
+				}
+
+				conds = append(conds, addCondition(
+					"{tbl}.filename = "+varFilename+" AND "+matchExpr,
+					"{tbl}.row_guid IS NOT NULL",
+					clause.Invert))
+
+			case *pb.EntitiesQuery_Clause_AnyOf_:
+				var alternatives []string
+				for _, alternative := range value.AnyOf.GetAlternative() {
+					altConds, err := compileClauses(alternative.GetClause(), true)
+					if err != nil {
+						return nil, err
+					}
+					alternatives = append(alternatives, andJoinSQL(altConds))
+				}
+
+				cond := "0"
+				if len(alternatives) > 0 {
+					cond = "(" + strings.Join(alternatives, ") OR (") + ")"
+				}
+				if clause.Invert {
+					cond = "NOT (" + cond + ")"
+				}
+				conds = append(conds, cond)
+
+			default:
+				return nil, status.Errorf(codes.Unimplemented, "unsupported query clause %v", clause)
 			}
+		}
+
+		return conds, nil
+	}
 
-			addCondition(
-				"{tbl}.filename = "+varFilename+
-					" AND {tbl}.trimmed_data_length = "+varTrimmedLength+
-					" AND {tbl}.trimmed_sha256_hash = "+varTrimmedSha256+
-					suffix,
-				"{tbl}.row_guid IS NOT NULL",
-				clause.Invert)
+	conds, err := compileClauses(query.Clause, false)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	whereClauses = append(whereClauses, conds...)
 
-		default:
-			return nil, nil, nil, status.Errorf(codes.Unimplemented, "unsupported query clause %v", clause)
+	if len(orderings) > 0 || limitVar != "" || offsetVar != "" {
+		if orderLimitSection != "" {
+			return "", nil, nil, fmt.Errorf("query error: random[] cannot be combined with sort[], limit[] or offset[]")
+		}
+		if len(checks) > 0 && (limitVar != "" || offsetVar != "") {
+			return "", nil, nil, fmt.Errorf("query error: regex[] cannot be combined with limit[] or offset[]")
+		}
+
+		// Ties are broken by entity ID, so that paging through results is stable.
+		orderLimitSection = "ORDER BY " + strings.Join(append(orderings, "base.entity_id"), ", ")
+
+		if limitVar == "" {
+			limitVar = "-1"
+		}
+		orderLimitSection += " LIMIT " + limitVar
+		if offsetVar != "" {
+			orderLimitSection += " OFFSET " + offsetVar
 		}
 	}
 
 	fullSQL := sqlquery + "\nWHERE\n" + andJoinSQL(whereClauses) + "\n" + orderLimitSection
 
+	var check *entityCheck
+	if len(checks) > 0 {
+		check = &entityCheck{
+			filenames: checkFilenames,
+			matches: func(files map[string]*pb.EntityFile) bool {
+				for _, check := range checks {
+					if !check(files) {
+						return false
+					}
+				}
+				return true
+			},
+		}
+	}
+
+	return fullSQL, moreArgs, check, nil
+}
+
+// numericTextCondition is true for texts that look like a number; only
+// those take part in numeric comparisons and aggregates.
+func numericTextCondition(textExpr string) string {
+	return textExpr + " GLOB '*[0-9]*' AND NOT " + textExpr + " GLOB '*[^0-9eE.+-]*'"
+}
+
+func (d *Database) prepareDynamicEntitiesQuery(asOf bool, query *pb.EntitiesQuery) (*sqlitedb.PreparedQuery, map[string]interface{}, *entityCheck, error) {
+	fullSQL, moreArgs, check, err := d.compileEntitiesQuery(asOf, query)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	logrus.Infof("Final SQL: %s", fullSQL)
 	logrus.Infof("Final fields: %v", moreArgs)
 
-	var err error
 	prepared := d.db.PrepareQuery(&err, "qmfsdb-dynamic-entities-query", fullSQL)
 	if err != nil {
 		logrus.Infof("SQL error (query was: %s): %v", fullSQL, err)
 		return nil, nil, nil, err
 	}
 
-	return prepared, moreArgs, nil, nil
+	return prepared, moreArgs, check, nil
 }
 
 // TODO when creating anything, require parent director(ies) to exist
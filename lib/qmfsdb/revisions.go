@@ -0,0 +1,217 @@
+package qmfsdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/steinarvk/orclib/lib/sqlitedb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+type revisionHeader struct {
+	Namespace          string
+	EntityID           string
+	Filename           string
+	RowGUID            string
+	TimestampUnixNano  int64
+	Sha256Hash         []byte
+	DataLength         *int64
+	TrimmedSha256Hash  []byte
+	TrimmedDataLength  *int64
+	Tombstone          bool
+	Active             bool
+	Directory          bool
+	AuthorshipMetadata []byte
+	HasData            bool
+}
+
+type fullRevisionData struct {
+	Namespace          string
+	EntityID           string
+	Filename           string
+	RowGUID            string
+	TimestampUnixNano  int64
+	Sha256Hash         []byte
+	DataLength         *int64
+	TrimmedSha256Hash  []byte
+	TrimmedDataLength  *int64
+	WhitespacePrefix   []byte
+	TrimmedData        []byte
+	WhitespaceSuffix   []byte
+	Tombstone          bool
+	Active             bool
+	Directory          bool
+	AuthorshipMetadata []byte
+	ExpiresAtUnixNano  *int64
+}
+
+func deserializeAuthorshipMetadata(data []byte) (*pb.AuthorshipMetadata, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	rv := &pb.AuthorshipMetadata{}
+	if err := proto.Unmarshal(data, rv); err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func makeRevisionHeader(namespace, entityID, filename, rowGUID string, timestampUnixNano int64, sha256Hash, trimmedSha256Hash []byte, dataLength, trimmedDataLength *int64, tombstone, directory bool) *pb.EntityFileHeader {
+	hdr := &pb.EntityFileHeader{
+		Namespace: namespace,
+		EntityId:  entityID,
+		Filename:  filename,
+		LastChanged: &pb.Timestamp{
+			UnixNano: timestampUnixNano,
+		},
+		RowGuid:   rowGUID,
+		Tombstone: tombstone,
+		Directory: directory,
+	}
+
+	if !tombstone {
+		hdr.Checksums = &pb.Checksums{
+			Sha256:        sha256Hash,
+			TrimmedSha256: trimmedSha256Hash,
+		}
+		if dataLength != nil {
+			hdr.Checksums.Length = *dataLength
+		}
+		if trimmedDataLength != nil {
+			hdr.Checksums.TrimmedLength = *trimmedDataLength
+		}
+	}
+
+	return hdr
+}
+
+var listFileRevisionsTransactor = sqlitedb.Transactor("ListFileRevisions")
+
+func (d *Database) ListFileRevisions(ctx context.Context, req *pb.ListFileRevisionsRequest) (*pb.ListFileRevisionsResponse, error) {
+	entityID := req.GetEntityId()
+	if entityID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Missing EntityID")
+	}
+
+	filename := req.GetFilename()
+	if filename == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Missing Filename")
+	}
+
+	var rv pb.ListFileRevisionsResponse
+
+	var row revisionHeader
+	err := listFileRevisionsTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		return d.queryListFileRevisions.Query(ctx, tx, map[string]interface{}{
+			"namespace": req.GetNamespace(),
+			"entity_id": entityID,
+			"filename":  filename,
+		}, &row, func() (bool, error) {
+			authorship, err := deserializeAuthorshipMetadata(row.AuthorshipMetadata)
+			if err != nil {
+				return false, status.Errorf(codes.Internal, "Error deserializing authorship metadata: %v", err)
+			}
+
+			hdr := makeRevisionHeader(row.Namespace, row.EntityID, row.Filename, row.RowGUID, row.TimestampUnixNano, row.Sha256Hash, row.TrimmedSha256Hash, row.DataLength, row.TrimmedDataLength, row.Tombstone, row.Directory)
+
+			// Empty files are stored with no data at all, so they are
+			// always trivially retained.
+			dataRetained := !row.Tombstone && (row.HasData || hdr.GetChecksums().GetLength() == 0)
+
+			rv.Revision = append(rv.Revision, &pb.FileRevision{
+				Header:             hdr,
+				AuthorshipMetadata: authorship,
+				Active:             row.Active,
+				DataRetained:       dataRetained,
+			})
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rv.Revision) == 0 {
+		return nil, status.Errorf(codes.NotFound, "No revisions found: entity_id=%q filename=%q", entityID, filename)
+	}
+
+	return &rv, nil
+}
+
+var readFileRevisionTransactor = sqlitedb.Transactor("ReadFileRevision")
+
+func (d *Database) ReadFileRevision(ctx context.Context, req *pb.ReadFileRevisionRequest) (*pb.ReadFileRevisionResponse, error) {
+	entityID := req.GetEntityId()
+	if entityID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Missing EntityID")
+	}
+
+	filename := req.GetFilename()
+	if filename == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Missing Filename")
+	}
+
+	rowGUID := req.GetRowGuid()
+	if rowGUID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Missing RowGUID")
+	}
+
+	success := false
+
+	prepq := d.queryReadFileRevision
+	if req.GetHeaderOnly() {
+		prepq = d.queryReadFileRevisionHeader
+	}
+
+	var row fullRevisionData
+	err := readFileRevisionTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		return prepq.Query(ctx, tx, map[string]interface{}{
+			"namespace": req.GetNamespace(),
+			"entity_id": entityID,
+			"filename":  filename,
+			"row_guid":  rowGUID,
+		}, &row, func() (bool, error) {
+			success = true
+			return false, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !success {
+		return nil, status.Errorf(codes.NotFound, "Revision not found: entity_id=%q filename=%q row_guid=%q", entityID, filename, rowGUID)
+	}
+
+	hdr := makeRevisionHeader(row.Namespace, row.EntityID, row.Filename, row.RowGUID, row.TimestampUnixNano, row.Sha256Hash, row.TrimmedSha256Hash, row.DataLength, row.TrimmedDataLength, row.Tombstone, row.Directory)
+	hdr.ExpiresAt = expiresAtTimestamp(row.ExpiresAtUnixNano)
+
+	data := append(row.WhitespacePrefix, append(row.TrimmedData, row.WhitespaceSuffix...)...)
+
+	if !req.GetHeaderOnly() && !row.Tombstone && int64(len(data)) != hdr.GetChecksums().GetLength() {
+		return nil, status.Errorf(codes.NotFound, "Contents of revision not retained: entity_id=%q filename=%q row_guid=%q", entityID, filename, rowGUID)
+	}
+
+	authorship, err := deserializeAuthorshipMetadata(row.AuthorshipMetadata)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error deserializing authorship metadata: %v", err)
+	}
+
+	if req.GetHeaderOnly() {
+		data = nil
+	}
+
+	return &pb.ReadFileRevisionResponse{
+		File: &pb.EntityFile{
+			Header: hdr,
+			Data:   data,
+		},
+		AuthorshipMetadata: authorship,
+	}, nil
+}
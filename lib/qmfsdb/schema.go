@@ -0,0 +1,232 @@
+package qmfsdb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// SchemaNamespace holds the schemas of the other namespaces. The schema
+	// of a namespace is the entity named after it, or DefaultSchemaEntity
+	// for the default namespace. Each file in that entity allows the file
+	// of the same name, and holds the rule its contents must follow. A
+	// namespace without a schema entity allows anything.
+	SchemaNamespace     = "schema"
+	DefaultSchemaEntity = "_default"
+)
+
+// A schemaRule is the parsed contents of a file in a schema entity:
+//
+//	(empty)           anything
+//	integer           a base-10 integer
+//	enum A B C        one of the listed words
+//	regex RE          a match of the regular expression RE
+//	json              a JSON document
+//	ref [NAMESPACE]   the ID of an existing entity in NAMESPACE (by default,
+//	                  the namespace the schema applies to; DefaultSchemaEntity
+//	                  names the default namespace)
+//
+// Contents are checked with surrounding whitespace removed.
+type schemaRule struct {
+	kind         string
+	values       map[string]bool
+	pattern      string
+	re           *regexp.Regexp
+	refNamespace string
+}
+
+func parseSchemaRule(namespace string, data []byte) (*schemaRule, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return &schemaRule{}, nil
+	}
+
+	rule := &schemaRule{kind: fields[0]}
+	args := fields[1:]
+
+	switch rule.kind {
+	case "integer", "json":
+		if len(args) != 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "schema rule %q takes no arguments", rule.kind)
+		}
+
+	case "enum":
+		if len(args) == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "schema rule \"enum\" requires at least one value")
+		}
+		rule.values = map[string]bool{}
+		for _, value := range args {
+			rule.values[value] = true
+		}
+
+	case "regex":
+		rule.pattern = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(data)), "regex"))
+		re, err := regexp.Compile("^(?:" + rule.pattern + ")$")
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid regex in schema rule: %v", err)
+		}
+		rule.re = re
+
+	case "ref":
+		switch len(args) {
+		case 0:
+			rule.refNamespace = namespace
+		case 1:
+			rule.refNamespace = args[0]
+			if rule.refNamespace == DefaultSchemaEntity {
+				rule.refNamespace = ""
+			}
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "schema rule \"ref\" takes at most one namespace")
+		}
+
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown schema rule %q", rule.kind)
+	}
+
+	return rule, nil
+}
+
+func (d *Database) entityExists(ctx context.Context, tx *sql.Tx, namespace, entityID string) (bool, error) {
+	var found bool
+
+	var row struct {
+		EntityID string
+	}
+	if err := d.queryEntityExists.Query(ctx, tx, map[string]interface{}{
+		"namespace": namespace,
+		"entity_id": entityID,
+	}, &row, func() (bool, error) {
+		found = true
+		return false, nil
+	}); err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+func (d *Database) checkSchemaRule(ctx context.Context, tx *sql.Tx, rule *schemaRule, w *pendingWrite) error {
+	value := string(bytes.TrimSpace(w.data))
+
+	switch rule.kind {
+	case "":
+		return nil
+
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return status.Errorf(codes.InvalidArgument, "%q in namespace %q must be an integer, not %q", w.filename, w.namespace, value)
+		}
+
+	case "enum":
+		if !rule.values[value] {
+			return status.Errorf(codes.InvalidArgument, "%q in namespace %q cannot be %q", w.filename, w.namespace, value)
+		}
+
+	case "regex":
+		if !rule.re.MatchString(value) {
+			return status.Errorf(codes.InvalidArgument, "%q in namespace %q must match %q, not %q", w.filename, w.namespace, rule.pattern, value)
+		}
+
+	case "json":
+		if !json.Valid([]byte(value)) {
+			return status.Errorf(codes.InvalidArgument, "%q in namespace %q must be JSON", w.filename, w.namespace)
+		}
+
+	case "ref":
+		exists, err := d.entityExists(ctx, tx, rule.refNamespace, value)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return status.Errorf(codes.InvalidArgument, "%q in namespace %q refers to nonexistent entity %q in namespace %q", w.filename, w.namespace, value, rule.refNamespace)
+		}
+	}
+
+	return nil
+}
+
+func schemaEntityFor(namespace string) string {
+	if namespace == "" {
+		return DefaultSchemaEntity
+	}
+	return namespace
+}
+
+// checkSchema rejects writes not allowed by the schema of their namespace,
+// as well as invalid rules written to the schema namespace itself.
+func (d *Database) checkSchema(ctx context.Context, tx *sql.Tx, w *pendingWrite) error {
+	if w.tombstone {
+		return nil
+	}
+
+	if w.namespace == SchemaNamespace {
+		if w.directory {
+			return nil
+		}
+		_, err := parseSchemaRule(w.entityID, w.data)
+		return err
+	}
+
+	var row entityFileHeader
+	var hasSchema, allowed, directory bool
+	if err := d.queryEntityFileHeaders.Query(ctx, tx, map[string]interface{}{
+		"namespace": SchemaNamespace,
+		"entity_id": schemaEntityFor(w.namespace),
+	}, &row, func() (bool, error) {
+		hasSchema = true
+		if row.Filename == w.filename {
+			allowed = true
+			directory = row.Directory
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		return err
+	}
+
+	if !hasSchema {
+		return nil
+	}
+
+	if !allowed {
+		return status.Errorf(codes.InvalidArgument, "%q is not allowed by the schema of namespace %q", w.filename, w.namespace)
+	}
+
+	switch {
+	case directory && !w.directory:
+		return status.Errorf(codes.InvalidArgument, "%q in namespace %q must be a directory", w.filename, w.namespace)
+	case w.directory && !directory:
+		return status.Errorf(codes.InvalidArgument, "%q in namespace %q must not be a directory", w.filename, w.namespace)
+	case directory:
+		return nil
+	}
+
+	var ruleFile fullFileData
+	var ruleData []byte
+	if err := d.queryReadFile.Query(ctx, tx, map[string]interface{}{
+		"namespace": SchemaNamespace,
+		"entity_id": schemaEntityFor(w.namespace),
+		"filename":  w.filename,
+	}, &ruleFile, func() (bool, error) {
+		ruleData = append(ruleFile.WhitespacePrefix, append(ruleFile.TrimmedData, ruleFile.WhitespaceSuffix...)...)
+		return false, nil
+	}); err != nil {
+		return err
+	}
+
+	rule, err := parseSchemaRule(w.namespace, ruleData)
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, "schema of namespace %q has an invalid rule for %q: %v", w.namespace, w.filename, err)
+	}
+
+	return d.checkSchemaRule(ctx, tx, rule, w)
+}
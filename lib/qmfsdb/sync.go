@@ -0,0 +1,267 @@
+package qmfsdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/steinarvk/orclib/lib/sqlitedb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+const (
+	maxPullBatchSize = 1000
+
+	// Revisions carry their contents, so batches exchanged while syncing
+	// are kept well below the default gRPC message size limit.
+	syncBatchSize = 100
+)
+
+type syncRevisionRow struct {
+	Sequence           int64
+	Namespace          string
+	EntityID           string
+	Filename           string
+	RowGUID            string
+	TimestampUnixNano  int64
+	Sha256Hash         []byte
+	DataLength         *int64
+	TrimmedSha256Hash  []byte
+	TrimmedDataLength  *int64
+	WhitespacePrefix   []byte
+	TrimmedData        []byte
+	WhitespaceSuffix   []byte
+	Tombstone          bool
+	Active             bool
+	Directory          bool
+	AuthorshipMetadata []byte
+	ExpiresAtUnixNano  *int64
+}
+
+var syncTransactor = sqlitedb.Transactor("Sync")
+
+func (d *Database) PullChanges(ctx context.Context, req *pb.PullChangesRequest) (*pb.PullChangesResponse, error) {
+	if req.GetAfterSequence() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "negative sequence: %d", req.GetAfterSequence())
+	}
+
+	limit := int(req.GetLimit())
+	if limit <= 0 || limit > maxPullBatchSize {
+		limit = maxPullBatchSize
+	}
+
+	rv := &pb.PullChangesResponse{
+		LastSequence: req.GetAfterSequence(),
+	}
+
+	var row syncRevisionRow
+
+	if err := syncTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		rv.Revision = nil
+		rv.LastSequence = req.GetAfterSequence()
+
+		return d.querySyncRevisions.Query(ctx, tx, map[string]interface{}{
+			"after_sequence": req.GetAfterSequence(),
+			"limit":          limit,
+		}, &row, func() (bool, error) {
+			rec, err := archivedRevision(&fullRevisionData{
+				Namespace:          row.Namespace,
+				EntityID:           row.EntityID,
+				Filename:           row.Filename,
+				RowGUID:            row.RowGUID,
+				TimestampUnixNano:  row.TimestampUnixNano,
+				Sha256Hash:         row.Sha256Hash,
+				DataLength:         row.DataLength,
+				TrimmedSha256Hash:  row.TrimmedSha256Hash,
+				TrimmedDataLength:  row.TrimmedDataLength,
+				WhitespacePrefix:   row.WhitespacePrefix,
+				TrimmedData:        row.TrimmedData,
+				WhitespaceSuffix:   row.WhitespaceSuffix,
+				Tombstone:          row.Tombstone,
+				Active:             row.Active,
+				Directory:          row.Directory,
+				AuthorshipMetadata: row.AuthorshipMetadata,
+				ExpiresAtUnixNano:  row.ExpiresAtUnixNano,
+			}, true)
+			if err != nil {
+				return false, err
+			}
+
+			rv.Revision = append(rv.Revision, rec)
+			rv.LastSequence = row.Sequence
+			return true, nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// PushChanges merges revisions written elsewhere, resolving concurrent
+// edits of the same file by last-writer-wins.
+func (d *Database) PushChanges(ctx context.Context, req *pb.PushChangesRequest) (*pb.PushChangesResponse, error) {
+	var rv pb.PushChangesResponse
+
+	if err := syncTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		rv.Applied, rv.Skipped = 0, 0
+
+		for _, rec := range req.GetRevision() {
+			applied, err := d.mergeRevision(ctx, tx, rec)
+			if err != nil {
+				return err
+			}
+
+			if applied {
+				rv.Applied++
+			} else {
+				rv.Skipped++
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if rv.Applied > 0 {
+		d.onChange()
+	}
+
+	return &rv, nil
+}
+
+// SyncWatermarks records how far this database has synced with a peer.
+type SyncWatermarks struct {
+	// PulledSequence is the sequence number, in the peer's database, of the
+	// last revision pulled from it.
+	PulledSequence int64
+	// PushedSequence is the sequence number, in this database, of the last
+	// revision pushed to the peer.
+	PushedSequence int64
+}
+
+func (d *Database) GetSyncWatermarks(ctx context.Context, peer string) (*SyncWatermarks, error) {
+	var rv SyncWatermarks
+
+	var row SyncWatermarks
+	if err := syncTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		return d.querySyncWatermarks.Query(ctx, tx, map[string]interface{}{
+			"peer": peer,
+		}, &row, func() (bool, error) {
+			rv = row
+			return false, nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return &rv, nil
+}
+
+func (d *Database) SetSyncWatermarks(ctx context.Context, peer string, w *SyncWatermarks) error {
+	return syncTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		return d.stmtSetSyncWatermarks.Exec(ctx, tx, map[string]interface{}{
+			"peer":            peer,
+			"pulled_sequence": w.PulledSequence,
+			"pushed_sequence": w.PushedSequence,
+		})
+	})
+}
+
+type SyncResult struct {
+	Pulled        int64
+	PulledSkipped int64
+	Pushed        int64
+	PushedSkipped int64
+}
+
+// Sync exchanges all revisions written since the last sync with the peer,
+// first pulling its changes and then pushing ours. Progress is recorded
+// after every batch, so an interrupted sync resumes where it left off.
+func (d *Database) Sync(ctx context.Context, peer string, remote pb.QMetadataServiceClient) (*SyncResult, error) {
+	var rv SyncResult
+
+	watermarks, err := d.GetSyncWatermarks(ctx, peer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Revisions pulled from the peer are not pushed back to it.
+	pulled := map[string]bool{}
+
+	for {
+		resp, err := remote.PullChanges(ctx, &pb.PullChangesRequest{
+			AfterSequence: watermarks.PulledSequence,
+			Limit:         syncBatchSize,
+		})
+		if err != nil {
+			return &rv, err
+		}
+
+		if len(resp.GetRevision()) == 0 {
+			break
+		}
+
+		merged, err := d.PushChanges(ctx, &pb.PushChangesRequest{
+			Revision: resp.GetRevision(),
+		})
+		if err != nil {
+			return &rv, err
+		}
+
+		for _, rec := range resp.GetRevision() {
+			pulled[rec.GetFile().GetHeader().GetRowGuid()] = true
+		}
+
+		rv.Pulled += merged.GetApplied()
+		rv.PulledSkipped += merged.GetSkipped()
+
+		watermarks.PulledSequence = resp.GetLastSequence()
+		if err := d.SetSyncWatermarks(ctx, peer, watermarks); err != nil {
+			return &rv, err
+		}
+	}
+
+	for {
+		changes, err := d.PullChanges(ctx, &pb.PullChangesRequest{
+			AfterSequence: watermarks.PushedSequence,
+			Limit:         syncBatchSize,
+		})
+		if err != nil {
+			return &rv, err
+		}
+
+		if len(changes.GetRevision()) == 0 {
+			break
+		}
+
+		var revisions []*pb.ArchivedRevision
+		for _, rec := range changes.GetRevision() {
+			if !pulled[rec.GetFile().GetHeader().GetRowGuid()] {
+				revisions = append(revisions, rec)
+			}
+		}
+
+		if len(revisions) > 0 {
+			resp, err := remote.PushChanges(ctx, &pb.PushChangesRequest{
+				Revision: revisions,
+			})
+			if err != nil {
+				return &rv, err
+			}
+
+			rv.Pushed += resp.GetApplied()
+			rv.PushedSkipped += resp.GetSkipped()
+		}
+
+		watermarks.PushedSequence = changes.GetLastSequence()
+		if err := d.SetSyncWatermarks(ctx, peer, watermarks); err != nil {
+			return &rv, err
+		}
+	}
+
+	return &rv, nil
+}
@@ -0,0 +1,274 @@
+package qmfsdb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/steinarvk/orclib/lib/sqlitedb"
+	"github.com/steinarvk/qmfs/lib/qmfsquery"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/steinarvk/qmfs/gen/qmfspb"
+)
+
+const (
+	watchBatchSize = 1000
+
+	// Changes made by other processes sharing the database file do not
+	// trigger notifications, so watchers also poll occasionally.
+	watchPollInterval = 10 * time.Second
+)
+
+// changeNotification returns a channel that is closed on the next change.
+func (d *Database) changeNotification() <-chan struct{} {
+	d.changeMu.Lock()
+	defer d.changeMu.Unlock()
+
+	if d.changeCh == nil {
+		d.changeCh = make(chan struct{})
+	}
+	return d.changeCh
+}
+
+func (d *Database) notifyWatchers() {
+	d.changeMu.Lock()
+	defer d.changeMu.Unlock()
+
+	if d.changeCh != nil {
+		close(d.changeCh)
+		d.changeCh = nil
+	}
+}
+
+type changeRow struct {
+	Sequence          int64
+	Namespace         string
+	EntityID          string
+	Filename          string
+	RowGUID           string
+	TimestampUnixNano int64
+	Sha256Hash        []byte
+	DataLength        *int64
+	TrimmedSha256Hash []byte
+	TrimmedDataLength *int64
+	Tombstone         bool
+	Directory         bool
+}
+
+var watchChangesTransactor = sqlitedb.Transactor("WatchChanges")
+
+func (d *Database) latestSequence(ctx context.Context) (int64, error) {
+	var rv int64
+
+	var row struct {
+		Sequence *int64
+	}
+	err := watchChangesTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		return d.queryLatestSequence.Query(ctx, tx, nil, &row, func() (bool, error) {
+			if row.Sequence != nil {
+				rv = *row.Sequence
+			}
+			return false, nil
+		})
+	})
+	return rv, err
+}
+
+func (d *Database) changesAfter(ctx context.Context, req *pb.WatchChangesRequest, afterSequence int64) ([]changeRow, error) {
+	var rv []changeRow
+
+	var row changeRow
+	err := watchChangesTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		return d.queryChangesAfter.Query(ctx, tx, map[string]interface{}{
+			"after_sequence": afterSequence,
+			"all_namespaces": req.GetAllNamespaces(),
+			"namespace":      req.GetNamespace(),
+			"limit":          watchBatchSize,
+		}, &row, func() (bool, error) {
+			rv = append(rv, row)
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// watchFilter checks whether entities match the query of a change feed at
+// a given point in time. It is compiled once per feed.
+type watchFilter struct {
+	prepq  *sqlitedb.PreparedQuery
+	args   map[string]interface{}
+	check  *entityCheck
+	filesq *sqlitedb.PreparedQuery
+}
+
+func (d *Database) newWatchFilter(query *pb.EntitiesQuery) (*watchFilter, error) {
+	innerSQL, args, check, err := d.compileEntitiesQuery(true, query)
+	if err != nil {
+		return nil, err
+	}
+
+	fullSQL := "SELECT matches.entity_id AS entity_id\nFROM (" + innerSQL + ") AS matches\nWHERE matches.entity_id = :watch_entity_id"
+
+	rv := &watchFilter{
+		args:  args,
+		check: check,
+	}
+
+	rv.prepq = d.db.PrepareQuery(&err, "qmfsdb-watch-filter-query", fullSQL)
+	if err != nil {
+		logrus.Infof("SQL error (query was: %s): %v", fullSQL, err)
+		return nil, err
+	}
+
+	if check != nil {
+		rv.filesq, err = d.prepareEntityFilesQuery(true, len(check.filenames))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rv, nil
+}
+
+// matches reports whether the entity matched the query as of the given
+// time. Queries depending on the contents of old revisions are only
+// accepted when those are kept, as for any query of the past.
+func (f *watchFilter) matches(ctx context.Context, tx *sql.Tx, namespace, entityID string, asOfUnixNano int64) (bool, error) {
+	args := map[string]interface{}{}
+	for k, v := range f.args {
+		args[k] = v
+	}
+	args["namespace"] = namespace
+	args["as_of_unix_nano"] = asOfUnixNano
+	args["watch_entity_id"] = entityID
+
+	var found bool
+
+	var row struct {
+		EntityID string
+	}
+	if err := f.prepq.Query(ctx, tx, args, &row, func() (bool, error) {
+		found = true
+		return false, nil
+	}); err != nil {
+		return false, err
+	}
+
+	if !found || f.check == nil {
+		return found, nil
+	}
+
+	files, err := readEntityFiles(ctx, tx, f.filesq, args, []string{entityID}, f.check.filenames)
+	if err != nil {
+		return false, err
+	}
+
+	return f.check.matches(files[entityID]), nil
+}
+
+// affects reports whether the change is relevant to a feed filtered by a
+// query: that is, whether the entity matched either just before or just
+// after the change. This includes the changes and deletions that make an
+// entity stop matching.
+func (d *Database) affects(ctx context.Context, filter *watchFilter, row *changeRow) (bool, error) {
+	var rv bool
+
+	err := watchChangesTransactor(ctx, d.db, func(ctx context.Context, tx *sql.Tx) error {
+		before, err := filter.matches(ctx, tx, row.Namespace, row.EntityID, row.TimestampUnixNano-1)
+		if err != nil {
+			return err
+		}
+
+		after, err := filter.matches(ctx, tx, row.Namespace, row.EntityID, row.TimestampUnixNano)
+		if err != nil {
+			return err
+		}
+
+		rv = before || after
+		return nil
+	})
+
+	return rv, err
+}
+
+func (d *Database) WatchChanges(req *pb.WatchChangesRequest, stream pb.QMetadataService_WatchChangesServer) error {
+	ctx := stream.Context()
+
+	if qmfsquery.HasLimit(req.GetQuery()) {
+		return status.Errorf(codes.InvalidArgument, "limit[] and offset[] are not supported when watching changes")
+	}
+
+	var filter *watchFilter
+	if req.GetQuery() != nil {
+		var err error
+		filter, err = d.newWatchFilter(req.GetQuery())
+		if err != nil {
+			return err
+		}
+	}
+
+	cursor := req.GetAfterSequence()
+	if req.GetFromNow() {
+		latest, err := d.latestSequence(ctx)
+		if err != nil {
+			return err
+		}
+		cursor = latest
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"namespace":      req.GetNamespace(),
+		"all_namespaces": req.GetAllNamespaces(),
+		"cursor":         cursor,
+	}).Infof("Starting change feed")
+
+	for {
+		// Fetch the notification channel before querying, so that no change
+		// committed after the query can be missed.
+		changed := d.changeNotification()
+
+		rows, err := d.changesAfter(ctx, req, cursor)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			cursor = row.Sequence
+
+			if filter != nil {
+				ok, err := d.affects(ctx, filter, &row)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+			}
+
+			if err := stream.Send(&pb.ChangeEvent{
+				Header:   makeRevisionHeader(row.Namespace, row.EntityID, row.Filename, row.RowGUID, row.TimestampUnixNano, row.Sha256Hash, row.TrimmedSha256Hash, row.DataLength, row.TrimmedDataLength, row.Tombstone, row.Directory),
+				Sequence: row.Sequence,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if len(rows) == watchBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		case <-time.After(watchPollInterval):
+		}
+	}
+}
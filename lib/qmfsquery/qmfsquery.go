@@ -14,7 +14,10 @@ var (
 	// Explicitly used for queries:
 	//  - (negation)
 	//  , (AND clause separation)
+	//  | (OR clause separation; binds tighter than AND)
+	//  ( ) (grouping)
 	//  = (content query)
+	//  < <= > >= (comparison query)
 
 	// Allowed:
 	validFilenameRE = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
@@ -38,6 +41,40 @@ func EntityIDEquals(entityID string) *pb.EntitiesQuery_Clause {
 	}
 }
 
+// HasLimit returns whether the query selects only part of its matches with
+// limit[] or offset[], in which case membership of a single entity can't be
+// checked by narrowing the query down to that entity.
+func HasLimit(query *pb.EntitiesQuery) bool {
+	for _, clause := range query.GetClause() {
+		switch clause.Kind.(type) {
+		case *pb.EntitiesQuery_Clause_Limit, *pb.EntitiesQuery_Clause_Offset:
+			return true
+		}
+	}
+	return false
+}
+
+func FileContentsEquals(filename, contents string) *pb.EntitiesQuery_Clause {
+	return &pb.EntitiesQuery_Clause{
+		Kind: &pb.EntitiesQuery_Clause_FileContents{
+			FileContents: &pb.EntitiesQuery_Clause_FileHasTrimmedContents{
+				Filename: filename,
+				Contents: contents,
+			},
+		},
+	}
+}
+
+func AnyOf(alternatives ...*pb.EntitiesQuery) *pb.EntitiesQuery_Clause {
+	return &pb.EntitiesQuery_Clause{
+		Kind: &pb.EntitiesQuery_Clause_AnyOf_{
+			AnyOf: &pb.EntitiesQuery_Clause_AnyOf{
+				Alternative: alternatives,
+			},
+		},
+	}
+}
+
 func parseArgs(unparsedArgs []string, spec string) ([]interface{}, error) {
 	if len(spec) != len(unparsedArgs) {
 		return nil, fmt.Errorf("want %d args got %d (%v)", len(spec), len(unparsedArgs), unparsedArgs)
@@ -105,6 +142,65 @@ func parseFunctionClause(clausestring string, clause *pb.EntitiesQuery_Clause) e
 			},
 		}
 
+	case "sort", "sortdesc", "numsort", "numsortdesc":
+		args, err := parseArgs(simp.args, "f")
+		if err != nil {
+			return err
+		}
+
+		clause.Kind = &pb.EntitiesQuery_Clause_Sort{
+			Sort: &pb.EntitiesQuery_Clause_Ordering{
+				Filename:   args[0].(string),
+				Descending: strings.HasSuffix(simp.functionName, "desc"),
+				Numeric:    strings.HasPrefix(simp.functionName, "num"),
+			},
+		}
+
+	case "limit":
+		args, err := parseArgs(simp.args, "i")
+		if err != nil {
+			return err
+		}
+
+		clause.Kind = &pb.EntitiesQuery_Clause_Limit{
+			Limit: int32(args[0].(int)),
+		}
+
+	case "offset":
+		args, err := parseArgs(simp.args, "i")
+		if err != nil {
+			return err
+		}
+
+		clause.Kind = &pb.EntitiesQuery_Clause_Offset{
+			Offset: int32(args[0].(int)),
+		}
+
+	case "prefix", "contains", "glob", "regex":
+		// The pattern may itself contain commas.
+		if len(simp.args) < 2 {
+			return fmt.Errorf("%s[] requires a filename and a pattern (%v)", simp.functionName, simp.args)
+		}
+		args, err := parseArgs(simp.args[:1], "f")
+		if err != nil {
+			return err
+		}
+		pattern := strings.Join(simp.args[1:], ",")
+
+		if simp.functionName == "regex" {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("bad regex %q: %v", pattern, err)
+			}
+		}
+
+		clause.Kind = &pb.EntitiesQuery_Clause_Matches{
+			Matches: &pb.EntitiesQuery_Clause_FileMatchesPattern{
+				Filename: args[0].(string),
+				Mode:     patternModes[simp.functionName],
+				Pattern:  pattern,
+			},
+		}
+
 	default:
 		return fmt.Errorf("unknown query function %q", simp.functionName)
 	}
@@ -112,6 +208,50 @@ func parseFunctionClause(clausestring string, clause *pb.EntitiesQuery_Clause) e
 	return nil
 }
 
+var patternModes = map[string]pb.EntitiesQuery_Clause_FileMatchesPattern_Mode{
+	"prefix":   pb.EntitiesQuery_Clause_FileMatchesPattern_PREFIX,
+	"contains": pb.EntitiesQuery_Clause_FileMatchesPattern_CONTAINS,
+	"glob":     pb.EntitiesQuery_Clause_FileMatchesPattern_GLOB,
+	"regex":    pb.EntitiesQuery_Clause_FileMatchesPattern_REGEX,
+}
+
+var comparisonOperators = map[string]pb.EntitiesQuery_Clause_FileComparison_Operator{
+	"<":  pb.EntitiesQuery_Clause_FileComparison_LESS,
+	"<=": pb.EntitiesQuery_Clause_FileComparison_LESS_OR_EQUAL,
+	">":  pb.EntitiesQuery_Clause_FileComparison_GREATER,
+	">=": pb.EntitiesQuery_Clause_FileComparison_GREATER_OR_EQUAL,
+}
+
+// findOperator returns the position of the first operator in a clause, and
+// the operator itself ("=", "<", "<=", ">" or ">="), or "" if there is none.
+func findOperator(s string) (int, string) {
+	i := strings.IndexAny(s, "=<>")
+	if i < 0 {
+		return -1, ""
+	}
+	if s[i] != '=' && i+1 < len(s) && s[i+1] == '=' {
+		return i, s[i : i+2]
+	}
+	return i, s[i : i+1]
+}
+
+// parseComparisonValue decides how a comparison should be performed: values
+// that look like numbers are compared numerically, others as strings. A value
+// can be compared as a string regardless by quoting it, e.g. version>'10'.
+func parseComparisonValue(s string) (string, pb.EntitiesQuery_Clause_FileComparison_Semantics) {
+	s = strings.TrimSpace(s)
+
+	if len(s) >= 2 && strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") {
+		return s[1 : len(s)-1], pb.EntitiesQuery_Clause_FileComparison_STRING
+	}
+
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s, pb.EntitiesQuery_Clause_FileComparison_NUMERIC
+	}
+
+	return s, pb.EntitiesQuery_Clause_FileComparison_STRING
+}
+
 func parseClause(clausestring string) (*pb.EntitiesQuery_Clause, error) {
 	unescaped, err := url.PathUnescape(clausestring)
 	if err != nil {
@@ -132,17 +272,27 @@ func parseClause(clausestring string) (*pb.EntitiesQuery_Clause, error) {
 		return clause, nil
 	}
 
-	sep := "="
-
-	if strings.Contains(clausestring, sep) {
-		keyval := strings.SplitN(clausestring, sep, 2)
-		filename := keyval[0]
-		contents := keyval[1]
+	if opIndex, op := findOperator(clausestring); op != "" {
+		filename := clausestring[:opIndex]
+		contents := clausestring[opIndex+len(op):]
 
 		if !ValidPath(filename) {
 			return nil, fmt.Errorf("invalid filename: %q", filename)
 		}
 
+		if op != "=" {
+			value, semantics := parseComparisonValue(contents)
+			clause.Kind = &pb.EntitiesQuery_Clause_Compare{
+				Compare: &pb.EntitiesQuery_Clause_FileComparison{
+					Filename:  filename,
+					Operator:  comparisonOperators[op],
+					Value:     value,
+					Semantics: semantics,
+				},
+			}
+			return clause, nil
+		}
+
 		clause.Kind = &pb.EntitiesQuery_Clause_FileContents{
 			FileContents: &pb.EntitiesQuery_Clause_FileHasTrimmedContents{
 				Filename: filename,
@@ -203,12 +353,17 @@ func parseSimpleFunction(s string) (*simpleFunction, error) {
 }
 
 func splitQuerystring(s string) ([]string, error) {
-	sep := ','
-	inc := '['
-	dec := ']'
+	return splitTopLevel(s, ',')
+}
 
+var closingBracket = map[rune]rune{
+	'[': ']',
+	'(': ')',
+}
+
+func splitTopLevel(s string, sep rune) ([]string, error) {
 	var rv []string
-	var level int
+	var expectClosing []rune
 	var collected []rune
 
 	flush := func() {
@@ -221,26 +376,93 @@ func splitQuerystring(s string) ([]string, error) {
 	for _, ch := range s {
 		switch ch {
 		case sep:
-			if level == 0 {
+			if len(expectClosing) == 0 {
 				flush()
 				continue
 			}
-		case inc:
-			level++
-		case dec:
-			if level == 0 {
-				return nil, fmt.Errorf("unmatched %q", dec)
+		case '[', '(':
+			expectClosing = append(expectClosing, closingBracket[ch])
+		case ']', ')':
+			if len(expectClosing) == 0 || expectClosing[len(expectClosing)-1] != ch {
+				return nil, fmt.Errorf("unmatched %q", ch)
 			}
-			level--
+			expectClosing = expectClosing[:len(expectClosing)-1]
 		}
 		collected = append(collected, ch)
 	}
 
+	if len(expectClosing) > 0 {
+		return nil, fmt.Errorf("missing %q", expectClosing[len(expectClosing)-1])
+	}
+
 	flush()
 
 	return rv, nil
 }
 
+func isGroup(s string) bool {
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return false
+	}
+	// Must be a single group, not e.g. "(a)|(b)" or "(a),(b)".
+	inner := s[1 : len(s)-1]
+	_, err := splitTopLevel(inner, ',')
+	return err == nil
+}
+
+// parseTerm parses a single alternative of a disjunction: either a plain
+// clause or a (possibly negated) parenthesised subquery.
+func parseTerm(s string) (*pb.EntitiesQuery, error) {
+	if isGroup(s) {
+		return Parse(s[1 : len(s)-1])
+	}
+
+	if strings.HasPrefix(s, "-") && isGroup(s[1:]) {
+		group, err := Parse(s[2 : len(s)-1])
+		if err != nil {
+			return nil, err
+		}
+		negated := AnyOf(group)
+		negated.Invert = true
+		return &pb.EntitiesQuery{
+			Clause: []*pb.EntitiesQuery_Clause{negated},
+		}, nil
+	}
+
+	clause, err := parseClause(s)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.EntitiesQuery{
+		Clause: []*pb.EntitiesQuery_Clause{clause},
+	}, nil
+}
+
+func parseConjunct(s string) ([]*pb.EntitiesQuery_Clause, error) {
+	termstrings, err := splitTopLevel(s, '|')
+	if err != nil {
+		return nil, err
+	}
+
+	var terms []*pb.EntitiesQuery
+	for _, termstring := range termstrings {
+		term, err := parseTerm(termstring)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+
+	switch len(terms) {
+	case 0:
+		return nil, fmt.Errorf("empty clause: %q", s)
+	case 1:
+		return terms[0].Clause, nil
+	default:
+		return []*pb.EntitiesQuery_Clause{AnyOf(terms...)}, nil
+	}
+}
+
 func Parse(querystring string) (*pb.EntitiesQuery, error) {
 	clausestrings, err := splitQuerystring(querystring)
 	if err != nil {
@@ -249,11 +471,11 @@ func Parse(querystring string) (*pb.EntitiesQuery, error) {
 
 	query := &pb.EntitiesQuery{}
 	for _, clausestring := range clausestrings {
-		clauseq, err := parseClause(clausestring)
+		clauses, err := parseConjunct(clausestring)
 		if err != nil {
 			return nil, err
 		}
-		query.Clause = append(query.Clause, clauseq)
+		query.Clause = append(query.Clause, clauses...)
 	}
 	return query, nil
 }
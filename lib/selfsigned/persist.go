@@ -0,0 +1,99 @@
+package selfsigned
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+type certFiles struct {
+	cert, key string
+}
+
+var (
+	currentFiles  = certFiles{cert: "cert.pem", key: "key.pem"}
+	nextFiles     = certFiles{cert: "next_cert.pem", key: "next_key.pem"}
+	previousFiles = certFiles{cert: "previous_cert.pem", key: "previous_key.pem"}
+)
+
+// loadCertificate returns nil if there is no usable certificate stored.
+func loadCertificate(dir string, files certFiles, hostname string) (*tls.Certificate, error) {
+	certPath := filepath.Join(dir, files.cert)
+	keyPath := filepath.Join(dir, files.key)
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		logrus.Warningf("Ignoring unreadable certificate %q: %v", certPath, err)
+		return nil, nil
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing certificate from %q: %v", certPath, err)
+	}
+	cert.Leaf = leaf
+
+	if err := leaf.VerifyHostname(hostname); err != nil {
+		logrus.Warningf("Ignoring stored certificate %q: %v", certPath, err)
+		return nil, nil
+	}
+
+	logrus.Infof("Loaded certificate for %q from %q (expiring %v)", hostname, certPath, leaf.NotAfter)
+
+	return &cert, nil
+}
+
+func saveCertificate(dir string, files certFiles, cert *tls.Certificate) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	var certBuf bytes.Buffer
+	if err := pem.Encode(&certBuf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}); err != nil {
+		return err
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	var keyBuf bytes.Buffer
+	if err := pem.Encode(&keyBuf, &pem.Block{Type: "PRIVATE KEY", Bytes: privBytes}); err != nil {
+		return err
+	}
+
+	// A crash in between leaves a mismatched pair, which fails to load and
+	// is then replaced.
+	if err := writeFileAtomically(filepath.Join(dir, files.key), keyBuf.Bytes()); err != nil {
+		return err
+	}
+	return writeFileAtomically(filepath.Join(dir, files.cert), certBuf.Bytes())
+}
+
+func removeCertificate(dir string, files certFiles) error {
+	for _, filename := range []string{files.cert, files.key} {
+		if err := os.Remove(filepath.Join(dir, filename)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFileAtomically(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
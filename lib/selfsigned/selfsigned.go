@@ -18,8 +18,14 @@ import (
 
 var Organization = "Self-signed dummy certificate"
 var RSABits = 2048
+var ValidFor = 365 * 24 * time.Hour
 
-func Generate(hostname string) (*tls.Certificate, error) {
+// DefaultRotationOverlap is how long before expiry a successor certificate
+// is generated and trusted alongside the current one, unless configured
+// otherwise.
+var DefaultRotationOverlap = 30 * 24 * time.Hour
+
+func Generate(hostname string, validFor time.Duration) (*tls.Certificate, error) {
 	logrus.Infof("Generating self-signed certificate for %q", hostname)
 
 	priv, err := rsa.GenerateKey(rand.Reader, RSABits)
@@ -28,12 +34,9 @@ func Generate(hostname string) (*tls.Certificate, error) {
 	}
 
 	notBefore := time.Now()
-	validFor := 365 * 24 * time.Hour
-
 	notAfter := notBefore.Add(validFor)
 
-	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
-	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+	serialNumber, err := newSerialNumber()
 	if err != nil {
 		return nil, fmt.Errorf("Error generating self-signed certificate: error generating serial number: %v", err)
 	}
@@ -75,10 +78,75 @@ func Generate(hostname string) (*tls.Certificate, error) {
 	}, nil
 }
 
+// GenerateClient issues a certificate for clients to authenticate with,
+// signed by the given (self-signed) server certificate. It is valid no longer
+// than its issuer and cannot itself sign certificates or serve.
+func GenerateClient(issuer *tls.Certificate, hostname string) (*tls.Certificate, error) {
+	logrus.Infof("Generating client certificate for %q", hostname)
+
+	priv, err := rsa.GenerateKey(rand.Reader, RSABits)
+	if err != nil {
+		return nil, fmt.Errorf("Error generating client certificate: %v", err)
+	}
+
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, fmt.Errorf("Error generating client certificate: error generating serial number: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{Organization},
+			CommonName:   "client of " + hostname,
+		},
+		NotBefore: time.Now(),
+		NotAfter:  issuer.Leaf.NotAfter,
+
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, issuer.Leaf, &priv.PublicKey, issuer.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error generating client certificate: CreateCertificate: %v", err)
+	}
+
+	parsedCert, err := x509.ParseCertificate(derBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{derBytes},
+		PrivateKey:  priv,
+		Leaf:        parsedCert,
+	}, nil
+}
+
+func newSerialNumber() (*big.Int, error) {
+	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	return rand.Int(rand.Reader, serialNumberLimit)
+}
+
 type Provider struct {
+	// Dir, if set, is where the certificates are kept so that they
+	// survive restarts.
+	Dir string
+	// ValidFor is how long generated certificates are valid, defaulting
+	// to the package-level ValidFor.
+	ValidFor time.Duration
+	// RotationOverlap defaults to DefaultRotationOverlap.
+	RotationOverlap time.Duration
+
 	mu       sync.Mutex
 	cert     *tls.Certificate
+	next     *tls.Certificate
+	previous *tls.Certificate
+	client   *tls.Certificate
 	config   *tls.Config
+	serving  *tls.Config
 	hostname string
 	pemBuf   []byte
 	done     bool
@@ -95,6 +163,8 @@ func (p *Provider) GetHostname() (string, error) {
 	return p.hostname, nil
 }
 
+// GetPEM returns the certificates clients should trust: the current one and,
+// close to its expiry, its successor.
 func (p *Provider) GetPEM(hostname string) ([]byte, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -107,44 +177,219 @@ func (p *Provider) GetPEM(hostname string) ([]byte, error) {
 	return p.pemBuf, nil
 }
 
+// GetClientCertificate returns the certificate clients should present,
+// issued by the current server certificate. A new one is issued whenever
+// the server certificate is rotated.
+func (p *Provider) GetClientCertificate(hostname string) (*tls.Certificate, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err := p.holdingLockGetTLSConfig(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.client == nil || p.client.Leaf.CheckSignatureFrom(p.cert.Leaf) != nil {
+		client, err := GenerateClient(p.cert, p.hostname)
+		if err != nil {
+			return nil, err
+		}
+		p.client = client
+	}
+
+	return p.client, nil
+}
+
+func (p *Provider) validFor() time.Duration {
+	if p.ValidFor > 0 {
+		return p.ValidFor
+	}
+	return ValidFor
+}
+
+func (p *Provider) rotationOverlap() time.Duration {
+	if p.RotationOverlap > 0 {
+		return p.RotationOverlap
+	}
+	return DefaultRotationOverlap
+}
+
 func (p *Provider) holdingLockGetTLSConfig(hostname string) (*tls.Config, error) {
 	if p.config != nil {
 		if p.hostname != hostname {
 			return nil, fmt.Errorf("Hostname changed (from %q to %q)", p.hostname, hostname)
 		}
+		if err := p.holdingLockRotate(time.Now()); err != nil {
+			return nil, err
+		}
 		return p.config, nil
 	}
 
-	cert, err := Generate(hostname)
-	if err != nil {
+	if p.rotationOverlap() >= p.validFor() {
+		return nil, fmt.Errorf("Rotation overlap (%v) must be shorter than certificate validity (%v)", p.rotationOverlap(), p.validFor())
+	}
+
+	p.hostname = hostname
+
+	if err := p.holdingLockLoad(); err != nil {
 		return nil, err
 	}
 
-	certpool := x509.NewCertPool()
+	if err := p.holdingLockRotate(time.Now()); err != nil {
+		return nil, err
+	}
+
+	// The certificates in use may change under rotation, so every handshake
+	// picks up the latest configuration.
+	p.config = p.serving.Clone()
+	p.config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if err := p.holdingLockRotate(time.Now()); err != nil {
+			logrus.Errorf("Failed to rotate certificate: %v", err)
+		}
+		return p.serving, nil
+	}
+
+	p.done = true
+
+	return p.config, nil
+}
+
+func (p *Provider) holdingLockLoad() error {
+	if p.Dir == "" {
+		cert, err := Generate(p.hostname, p.validFor())
+		if err != nil {
+			return err
+		}
+		p.cert = cert
+		return nil
+	}
+
+	cert, err := loadCertificate(p.Dir, currentFiles, p.hostname)
+	if err != nil {
+		return err
+	}
+
+	if cert == nil {
+		cert, err = Generate(p.hostname, p.validFor())
+		if err != nil {
+			return err
+		}
+		if err := saveCertificate(p.Dir, currentFiles, cert); err != nil {
+			return err
+		}
+		for _, files := range []certFiles{nextFiles, previousFiles} {
+			if err := removeCertificate(p.Dir, files); err != nil {
+				return err
+			}
+		}
+	} else {
+		next, err := loadCertificate(p.Dir, nextFiles, p.hostname)
+		if err != nil {
+			return err
+		}
+		p.next = next
+
+		previous, err := loadCertificate(p.Dir, previousFiles, p.hostname)
+		if err != nil {
+			return err
+		}
+		p.previous = previous
+	}
 
-	p.hostname = hostname
 	p.cert = cert
-	p.config = &tls.Config{
-		Certificates: []tls.Certificate{*cert},
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		RootCAs:      certpool,
-		ClientCAs:    certpool,
+	return nil
+}
+
+// holdingLockRotate prepares a successor when the current certificate nears
+// expiry, and switches over to it halfway through the overlap. Clients still
+// presenting the replaced certificate are accepted until it expires.
+func (p *Provider) holdingLockRotate(now time.Time) error {
+	overlap := p.rotationOverlap()
+	changed := p.serving == nil
+
+	for {
+		notAfter := p.cert.Leaf.NotAfter
+
+		if p.next == nil && now.After(notAfter.Add(-overlap)) {
+			next, err := Generate(p.hostname, p.validFor())
+			if err != nil {
+				return err
+			}
+			if p.Dir != "" {
+				if err := saveCertificate(p.Dir, nextFiles, next); err != nil {
+					return err
+				}
+			}
+			p.next = next
+			changed = true
+		}
+
+		if p.next != nil && now.After(notAfter.Add(-overlap/2)) {
+			logrus.Infof("Rotating certificate for %q (expiring %v)", p.hostname, notAfter)
+			if p.Dir != "" {
+				if err := saveCertificate(p.Dir, previousFiles, p.cert); err != nil {
+					return err
+				}
+				if err := saveCertificate(p.Dir, currentFiles, p.next); err != nil {
+					return err
+				}
+				if err := removeCertificate(p.Dir, nextFiles); err != nil {
+					return err
+				}
+			}
+			p.previous = p.cert
+			p.cert = p.next
+			p.next = nil
+			changed = true
+			continue
+		}
+
+		break
 	}
-	p.config.BuildNameToCertificate()
 
+	if p.previous != nil && now.After(p.previous.Leaf.NotAfter) {
+		if p.Dir != "" {
+			if err := removeCertificate(p.Dir, previousFiles); err != nil {
+				return err
+			}
+		}
+		p.previous = nil
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	certpool := x509.NewCertPool()
 	var buf bytes.Buffer
-	if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}); err != nil {
-		return nil, fmt.Errorf("Error generating self-signed certificate: Encode: %v", err)
+
+	if p.previous != nil {
+		certpool.AddCert(p.previous.Leaf)
 	}
 
-	p.pemBuf = buf.Bytes()
-	p.done = true
+	for _, cert := range []*tls.Certificate{p.cert, p.next} {
+		if cert == nil {
+			continue
+		}
+		certpool.AddCert(cert.Leaf)
+		if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}); err != nil {
+			return fmt.Errorf("Error encoding self-signed certificate: Encode: %v", err)
+		}
+	}
 
-	if ok := certpool.AppendCertsFromPEM(p.pemBuf); !ok {
-		return nil, err
+	p.serving = &tls.Config{
+		Certificates: []tls.Certificate{*p.cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		RootCAs:      certpool,
+		ClientCAs:    certpool,
 	}
+	p.pemBuf = buf.Bytes()
 
-	return p.config, nil
+	return nil
 }
 
 func (p *Provider) GetTLSConfig(hostname string) (*tls.Config, error) {
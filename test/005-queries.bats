@@ -106,3 +106,161 @@ setup_simpsons() {
   rm ${Q}/entities/all/lisa/lastname
   [ "$(echo $(cat ${Q}/query/lastname=Simpson/all/*/firstname | sort))" = "Bart Homer Maggie Marge" ]
 }
+
+@test "can query by either of two contents" {
+  setup_simpsons
+  [ "$(echo $(cat ${Q}/query/age=8\|age=10/all/*/firstname | sort))" = "Bart Lisa" ]
+}
+
+@test "disjunction binds tighter than conjunction" {
+  setup_simpsons
+  [ "$(echo $(cat ${Q}/query/sex=female\|religion,lastname=Simpson/all/*/firstname | sort))" = "Lisa Maggie Marge" ]
+}
+
+@test "can group clauses with parentheses" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}/query/(sex=male,lastname=Simpson)|fictional/all/"*/firstname | sort))" = "Bart Homer Itchy Scratchy" ]
+}
+
+@test "can negate a group" {
+  setup_simpsons
+  [ "$(ls "${Q}/query/-(sex=male,lastname=Simpson)/all" | wc -l | tr -d '[:space:]')" = "6" ]
+}
+
+@test "cannot use random selection within a disjunction" {
+  setup_simpsons
+  run ls "${Q}/query/random[1]|fictional/all"
+  [ $status -ne 0 ]
+}
+
+@test "can query by numeric comparison" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}/query/age>10/all/"*/firstname | sort))" = "Homer Marge Ned" ]
+  [ "$(echo $(cat "${Q}/query/age<=10/all/"*/firstname | sort))" = "Bart Lisa Maggie" ]
+}
+
+@test "can query by string comparison" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}/query/firstname>=M/all/"*/firstname | sort))" = "Maggie Marge Ned Scratchy" ]
+}
+
+@test "quoted comparison values are compared as strings" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}/query/age>'5'/all/"*/firstname | sort))" = "Lisa Ned" ]
+}
+
+@test "can negate a comparison" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}/query/age,-age>10/all/"*/firstname | sort))" = "Bart Lisa Maggie" ]
+}
+
+@test "can query by prefix" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}/query/prefix[firstname,Ma]/all/"*/firstname | sort))" = "Maggie Marge" ]
+}
+
+@test "can query by substring" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}/query/contains[firstname,a]/all/"*/firstname | sort))" = "Bart Lisa Maggie Marge Scratchy" ]
+}
+
+@test "can query by glob" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}"/query/'glob[firstname,*er]'/all/*/firstname | sort))" = "Homer" ]
+}
+
+@test "can query by regex" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}"/query/'regex[age,^[0-9]$]'/all/*/firstname | sort))" = "Lisa Maggie" ]
+  [ "$(ls "${Q}"/query/'-regex[age,^[0-9]$]'/all | wc -l | tr -d '[:space:]')" = "6" ]
+}
+
+@test "cannot use regex within a disjunction" {
+  setup_simpsons
+  run ls "${Q}"/query/'regex[age,^1]|fictional'/all
+  [ $status -ne 0 ]
+}
+
+@test "can sort query results" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}/query/lastname=Simpson,sort[firstname]/list" | xargs -n1 basename))" = "bart homer lisa maggie marge" ]
+  [ "$(echo $(cat "${Q}/query/lastname=Simpson,sortdesc[firstname]/list" | xargs -n1 basename))" = "marge maggie lisa homer bart" ]
+}
+
+@test "can sort query results numerically" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}/query/lastname=Simpson,sort[age]/list" | xargs -n1 basename))" = "maggie bart marge homer lisa" ]
+  [ "$(echo $(cat "${Q}/query/lastname=Simpson,numsort[age]/list" | xargs -n1 basename))" = "maggie lisa bart marge homer" ]
+}
+
+@test "entities lacking the sort file come last" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}/query/sex=male,sort[lastname]/list" | xargs -n1 basename))" = "flanders bart homer itchy scratchy" ]
+}
+
+@test "can limit and offset query results" {
+  setup_simpsons
+  [ "$(echo $(cat "${Q}/query/age,numsortdesc[age],limit[2]/list" | xargs -n1 basename))" = "flanders homer" ]
+  [ "$(echo $(cat "${Q}/query/lastname=Simpson,numsort[age],offset[3]/list" | xargs -n1 basename))" = "marge homer" ]
+  [ "$(ls "${Q}/query/age,limit[3]/all" | wc -l | tr -d '[:space:]')" = "3" ]
+}
+
+@test "all directory preserves query order" {
+  setup_simpsons
+  [ "$(echo $(ls -U "${Q}/query/age,numsortdesc[age],limit[3]/all"))" = "flanders homer marge" ]
+}
+
+@test "can look up entities within a limited query" {
+  setup_simpsons
+  [ "$(cat "${Q}/query/age,numsortdesc[age],limit[2]/all/homer/firstname")" = "Homer" ]
+  [ ! -e "${Q}/query/age,numsortdesc[age],limit[2]/all/bart" ]
+}
+
+@test "cannot combine random selection with sorting" {
+  setup_simpsons
+  run ls "${Q}/query/random[2],sort[age]/all"
+  [ $status -ne 0 ]
+}
+
+@test "can count values of a file" {
+  setup_simpsons
+  [ "$(cat ${Q}/entities/values/lastname)" = "$(printf '5\tSimpson\n1\tFlanders')" ]
+  [ "$(cat ${Q}/query/lastname=Simpson/values/sex)" = "$(printf '3\tfemale\n2\tmale')" ]
+}
+
+@test "can browse query results by value" {
+  setup_simpsons
+  [ "$(echo $(ls ${Q}/query/lastname=Simpson/by/sex))" = "female male" ]
+  [ "$(echo $(ls ${Q}/query/lastname=Simpson/by/sex/female/all))" = "lisa maggie marge" ]
+  [ "$(echo $(ls ${Q}/entities/by/sex/male/by/lastname))" = "Flanders Simpson" ]
+}
+
+@test "can export query results as csv" {
+  setup_simpsons
+  expected="$(printf 'entity_id,firstname,age\nbart,Bart,10\nhomer,Homer,39\nlisa,Lisa,8\nmaggie,Maggie,1\nmarge,Marge,36')"
+  [ "$(cat "${Q}/query/lastname=Simpson,sort[firstname]/csv/firstname,age")" = "$expected" ]
+}
+
+@test "can export query results as tsv" {
+  setup_simpsons
+  expected="$(printf 'entity_id\tfirstname\nitchy\tItchy\nscratchy\tScratchy')"
+  [ "$(cat "${Q}/query/fictional,sort[firstname]/tsv/firstname")" = "$expected" ]
+}
+
+@test "can export query results as ndjson" {
+  setup_simpsons
+  expected="$(printf '{"entity_id":"homer","firstname":"Homer","religion":null}\n{"entity_id":"flanders","firstname":"Ned","religion":"Christian"}')"
+  [ "$(cat "${Q}/query/age>35,sex=male,numsort[age]/ndjson/firstname,religion")" = "$expected" ]
+}
+
+@test "can export query results as json" {
+  setup_simpsons
+  expected="$(printf '[\n{"entity_id":"itchy","sex":"male"},\n{"entity_id":"scratchy","sex":"male"}\n]')"
+  [ "$(cat "${Q}/query/fictional,sort[firstname]/json/sex")" = "$expected" ]
+  [ "$(cat "${Q}/query/nonexistent/json/sex")" = "[]" ]
+}
+
+@test "can export all entities" {
+  setup_simpsons
+  [ "$(cat "${Q}/entities/csv/firstname" | wc -l | tr -d '[:space:]')" = "9" ]
+}
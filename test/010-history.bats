@@ -0,0 +1,48 @@
+load helpers
+
+@test "history lists one revision per write" {
+  echo one > "${Q}/entities/all/e/attr"
+  echo two > "${Q}/entities/all/e/attr"
+  echo three > "${Q}/entities/all/e/attr"
+  [ "$(ls ${Q}/entities/all/e/.history/attr | wc -l | tr -d '[:space:]')" = "3" ]
+}
+
+@test "history directory is not listed in the entity directory" {
+  echo hello > "${Q}/entities/all/e/attr"
+  [ "$(ls -a ${Q}/entities/all/e | grep -c history)" = "0" ]
+}
+
+@test "can read old revisions when keeping revision data" {
+  restart_qmfs_keeping_revisions
+  echo one > "${Q}/entities/all/e/attr"
+  echo two > "${Q}/entities/all/e/attr"
+  oldest=$(ls ${Q}/entities/all/e/.history/attr | sort -n | head -1)
+  newest=$(ls ${Q}/entities/all/e/.history/attr | sort -n | tail -1)
+  [ "$(cat ${Q}/entities/all/e/.history/attr/${oldest})" = "one" ]
+  [ "$(cat ${Q}/entities/all/e/.history/attr/${newest})" = "two" ]
+}
+
+@test "history of a deleted file remains accessible" {
+  restart_qmfs_keeping_revisions
+  echo gone > "${Q}/entities/all/e/attr"
+  rm "${Q}/entities/all/e/attr"
+  rev=$(ls ${Q}/entities/all/e/.history/attr | head -1)
+  [ "$(cat ${Q}/entities/all/e/.history/attr/${rev})" = "gone" ]
+}
+
+@test "history of files in subdirectories" {
+  restart_qmfs_keeping_revisions
+  mkdir "${Q}/entities/all/e/dir"
+  echo one > "${Q}/entities/all/e/dir/attr"
+  echo two > "${Q}/entities/all/e/dir/attr"
+  [ "$(ls ${Q}/entities/all/e/dir/.history/attr | wc -l | tr -d '[:space:]')" = "2" ]
+}
+
+@test "history is read-only" {
+  echo hello > "${Q}/entities/all/e/attr"
+  rev=$(ls ${Q}/entities/all/e/.history/attr | head -1)
+  run sh -c "echo bye > ${Q}/entities/all/e/.history/attr/${rev}"
+  [ $status -ne 0 ]
+  run rm -r "${Q}/entities/all/e/.history"
+  [ $status -ne 0 ]
+}
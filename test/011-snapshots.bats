@@ -0,0 +1,101 @@
+load helpers
+
+now_nanos() {
+  cat "${Q}/service/last_changed"
+}
+
+@test "snapshot shows files as they were" {
+  restart_qmfs_keeping_revisions
+  echo before > "${Q}/entities/all/e/attr"
+  t=$(now_nanos)
+  echo after > "${Q}/entities/all/e/attr"
+  [ "$(cat ${Q}/entities/all/e/attr)" = "after" ]
+  [ "$(cat ${Q}/snapshot/${t}/entities/all/e/attr)" = "before" ]
+}
+
+@test "snapshot does not show entities created later" {
+  echo hello > "${Q}/entities/all/old/attr"
+  t=$(now_nanos)
+  echo hello > "${Q}/entities/all/new/attr"
+  [ "$(ls ${Q}/entities/all | wc -l | tr -d '[:space:]')" = "2" ]
+  [ "$(ls ${Q}/snapshot/${t}/entities/all | wc -l | tr -d '[:space:]')" = "1" ]
+}
+
+@test "snapshot still shows files deleted later" {
+  restart_qmfs_keeping_revisions
+  echo hello > "${Q}/entities/all/e/attr"
+  t=$(now_nanos)
+  rm "${Q}/entities/all/e/attr"
+  [ ! -f "${Q}/entities/all/e/attr" ]
+  [ -f "${Q}/snapshot/${t}/entities/all/e/attr" ]
+}
+
+@test "can query a snapshot" {
+  restart_qmfs_keeping_revisions
+  echo new > "${Q}/entities/all/a/status"
+  echo new > "${Q}/entities/all/b/status"
+  t=$(now_nanos)
+  echo done > "${Q}/entities/all/a/status"
+  [ "$(ls ${Q}/query/status=new/all | wc -l | tr -d '[:space:]')" = "1" ]
+  [ "$(ls ${Q}/snapshot/${t}/query/status=new/all | wc -l | tr -d '[:space:]')" = "2" ]
+}
+
+@test "can query a snapshot without keeping revision data" {
+  echo new > "${Q}/entities/all/a/status"
+  echo new > "${Q}/entities/all/b/status"
+  t=$(now_nanos)
+  echo done > "${Q}/entities/all/a/status"
+  [ "$(ls ${Q}/query/status=new/all | wc -l | tr -d '[:space:]')" = "1" ]
+  [ "$(ls ${Q}/snapshot/${t}/query/status=new/all | wc -l | tr -d '[:space:]')" = "2" ]
+}
+
+@test "snapshots are read-only" {
+  echo hello > "${Q}/entities/all/e/attr"
+  t=$(now_nanos)
+  run sh -c "echo bye > ${Q}/snapshot/${t}/entities/all/e/attr"
+  [ $status -ne 0 ]
+  [ "$(cat ${Q}/entities/all/e/attr)" = "hello" ]
+}
+
+@test "can compare in a snapshot when keeping revision data" {
+  restart_qmfs_keeping_revisions
+  echo 12 > "${Q}/entities/all/a/age"
+  echo 12 > "${Q}/entities/all/b/age"
+  t=$(now_nanos)
+  echo 5 > "${Q}/entities/all/a/age"
+  [ "$(ls "${Q}/query/age>10/all" | wc -l | tr -d '[:space:]')" = "1" ]
+  [ "$(ls "${Q}/snapshot/${t}/query/age>10/all" | wc -l | tr -d '[:space:]')" = "2" ]
+}
+
+@test "cannot compare in a snapshot without keeping revision data" {
+  echo 12 > "${Q}/entities/all/a/age"
+  t=$(now_nanos)
+  echo 5 > "${Q}/entities/all/a/age"
+  run ls "${Q}/snapshot/${t}/query/age>10/all"
+  [ "$status" -ne 0 ]
+}
+
+@test "can match patterns in a snapshot when keeping revision data" {
+  restart_qmfs_keeping_revisions
+  echo Maggie > "${Q}/entities/all/a/firstname"
+  echo Marge > "${Q}/entities/all/b/firstname"
+  t=$(now_nanos)
+  echo Lisa > "${Q}/entities/all/a/firstname"
+  [ "$(ls "${Q}/query/prefix[firstname,Ma]/all" | wc -l | tr -d '[:space:]')" = "1" ]
+  [ "$(ls "${Q}/snapshot/${t}/query/prefix[firstname,Ma]/all" | wc -l | tr -d '[:space:]')" = "2" ]
+  [ "$(ls "${Q}/snapshot/${t}/query/contains[firstname,gg]/all" | wc -l | tr -d '[:space:]')" = "1" ]
+}
+
+@test "cannot match patterns in a snapshot without keeping revision data" {
+  echo Maggie > "${Q}/entities/all/a/firstname"
+  t=$(now_nanos)
+  echo Lisa > "${Q}/entities/all/a/firstname"
+  run ls "${Q}/snapshot/${t}/query/prefix[firstname,Ma]/all"
+  [ "$status" -ne 0 ]
+  run ls "${Q}/snapshot/${t}/query/contains[firstname,gg]/all"
+  [ "$status" -ne 0 ]
+  run ls "${Q}/snapshot/${t}/query/glob[firstname,M*]/all"
+  [ "$status" -ne 0 ]
+  run ls "${Q}/snapshot/${t}/query/regex[firstname,^M]/all"
+  [ "$status" -ne 0 ]
+}
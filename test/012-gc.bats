@@ -0,0 +1,44 @@
+load helpers
+
+run_gc() {
+  ./qmfs gc --localdb "${QMFS_TEST_TEMP}/database.sqlite3" "$@" 2> /dev/null
+}
+
+@test "gc drops superseded revisions but keeps current contents" {
+  echo one > "${Q}/entities/all/e/attr"
+  echo two > "${Q}/entities/all/e/attr"
+  echo three > "${Q}/entities/all/e/attr"
+  stop_qmfs
+  run_gc --retention 0s
+  start_qmfs
+  [ "$(cat ${Q}/entities/all/e/attr)" = "three" ]
+  [ "$(ls ${Q}/entities/all/e/.history/attr | wc -l | tr -d '[:space:]')" = "1" ]
+}
+
+@test "gc respects the retention window" {
+  echo one > "${Q}/entities/all/e/attr"
+  echo two > "${Q}/entities/all/e/attr"
+  stop_qmfs
+  run_gc --retention 1h
+  start_qmfs
+  [ "$(ls ${Q}/entities/all/e/.history/attr | wc -l | tr -d '[:space:]')" = "2" ]
+}
+
+@test "gc drops deleted files entirely" {
+  echo hello > "${Q}/entities/all/e/attr"
+  echo hello > "${Q}/entities/all/e/other"
+  rm "${Q}/entities/all/e/attr"
+  stop_qmfs
+  run_gc --retention 0s > /dev/null
+  start_qmfs
+  [ ! -d "${Q}/entities/all/e/.history/attr" ]
+  [ "$(cat ${Q}/entities/all/e/other)" = "hello" ]
+}
+
+@test "gc reports what it deleted" {
+  echo one > "${Q}/entities/all/e/attr"
+  echo two > "${Q}/entities/all/e/attr"
+  stop_qmfs
+  run_gc --retention 0s | grep -q "deleted_revisions: 1"
+  start_qmfs
+}
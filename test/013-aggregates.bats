@@ -0,0 +1,42 @@
+load helpers
+
+setup_scores() {
+  echo 3 > "${Q}/entities/all/a/score"
+  echo team-red > "${Q}/entities/all/a/team"
+  echo 5 > "${Q}/entities/all/b/score"
+  echo team-red > "${Q}/entities/all/b/team"
+  echo 10 > "${Q}/entities/all/c/score"
+  echo team-blue > "${Q}/entities/all/c/team"
+  echo unknown > "${Q}/entities/all/d/score"
+  echo team-blue > "${Q}/entities/all/d/team"
+}
+
+@test "can count all entities" {
+  setup_scores
+  [ "$(cat ${Q}/entities/count)" = "4" ]
+}
+
+@test "can count query results" {
+  setup_scores
+  [ "$(cat ${Q}/query/team=team-red/count)" = "2" ]
+  [ "$(cat ${Q}/query/nonexistent/count)" = "0" ]
+}
+
+@test "can aggregate numeric contents" {
+  setup_scores
+  [ "$(cat ${Q}/entities/sum/score)" = "18" ]
+  [ "$(cat ${Q}/entities/min/score)" = "3" ]
+  [ "$(cat ${Q}/entities/max/score)" = "10" ]
+  [ "$(cat ${Q}/entities/avg/score)" = "6" ]
+}
+
+@test "can aggregate over query results" {
+  setup_scores
+  [ "$(cat ${Q}/query/team=team-red/sum/score)" = "8" ]
+  [ "$(cat ${Q}/query/team=team-blue/max/score)" = "10" ]
+}
+
+@test "aggregate over no values is empty" {
+  setup_scores
+  [ "$(cat ${Q}/entities/sum/nonexistent)" = "" ]
+}
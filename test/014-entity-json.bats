@@ -0,0 +1,63 @@
+load helpers
+
+@test "entity json shows all files" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  echo Simpson > "${Q}/entities/all/homer/lastname"
+  mkdir "${Q}/entities/all/homer/kids"
+  echo Bart > "${Q}/entities/all/homer/kids/son"
+  grep -qF '"firstname": "Homer\n"' "${Q}/entities/all/homer/.entity.json"
+  grep -qF '"kids": {' "${Q}/entities/all/homer/.entity.json"
+  grep -qF '"son": "Bart\n"' "${Q}/entities/all/homer/.entity.json"
+  [ "$(cat ${Q}/entities/all/homer/kids/.entity.json)" = "$(printf '{\n  "son": "Bart\\n"\n}')" ]
+}
+
+@test "entity json is not listed" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  [ "$(ls -a ${Q}/entities/all/homer | grep -c entity.json)" = "0" ]
+}
+
+@test "can write entity json" {
+  echo '{"firstname": "Homer", "age": 39, "kids": {"son": "Bart"}}' > "${Q}/entities/all/homer/.entity.json"
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Homer" ]
+  [ "$(cat ${Q}/entities/all/homer/age)" = "39" ]
+  [ -d "${Q}/entities/all/homer/kids" ]
+  [ "$(cat ${Q}/entities/all/homer/kids/son)" = "Bart" ]
+}
+
+@test "writing entity json deletes removed keys" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  echo Simpson > "${Q}/entities/all/homer/lastname"
+  mkdir "${Q}/entities/all/homer/kids"
+  echo Bart > "${Q}/entities/all/homer/kids/son"
+  echo '{"firstname": "Homer"}' > "${Q}/entities/all/homer/.entity.json"
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Homer" ]
+  [ ! -e "${Q}/entities/all/homer/lastname" ]
+  [ ! -e "${Q}/entities/all/homer/kids" ]
+}
+
+@test "invalid entity json is rejected without changes" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  run bash -c "echo '{\"firstname\": ' > '${Q}/entities/all/homer/.entity.json'"
+  [ $status -ne 0 ]
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Homer" ]
+}
+
+@test "cannot delete entity json" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  run rm "${Q}/entities/all/homer/.entity.json"
+  [ $status -ne 0 ]
+}
+
+@test "entity json writes racing other writes fail only with ESTALE" {
+  echo 0 > "${Q}/entities/all/homer/count"
+  ( for i in $(seq 300); do echo $i > "${Q}/entities/all/homer/count"; done ) &
+  writer=$!
+  for i in $(seq 100); do
+    run bash -c "echo '{\"count\": \"x\"}' > '${Q}/entities/all/homer/.entity.json'"
+    [[ "$output" != *"Input/output error"* ]]
+    if [ $status -ne 0 ]; then
+      [[ "$output" == *"Stale file handle"* ]]
+    fi
+  done
+  wait $writer
+}
@@ -0,0 +1,47 @@
+load helpers
+
+@test "files expose revision metadata as xattrs" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  getfattr -d "${Q}/entities/all/homer/firstname" | grep -q '^user.qmfs.row_guid='
+  getfattr -d "${Q}/entities/all/homer/firstname" | grep -q '^user.qmfs.last_changed='
+  [ "$(getfattr -n user.qmfs.sha256 --only-values ${Q}/entities/all/homer/firstname)" = "$(echo Homer | sha256sum | cut -d' ' -f1)" ]
+}
+
+@test "row guid xattr changes on write" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  OLDGUID="$(getfattr -n user.qmfs.row_guid --only-values ${Q}/entities/all/homer/firstname)"
+  echo Marge > "${Q}/entities/all/homer/firstname"
+  NEWGUID="$(getfattr -n user.qmfs.row_guid --only-values ${Q}/entities/all/homer/firstname)"
+  [ -n "$OLDGUID" ]
+  [ "$OLDGUID" != "$NEWGUID" ]
+}
+
+@test "write succeeds with matching expected revision" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  GUID="$(getfattr -n user.qmfs.row_guid --only-values ${Q}/entities/all/homer/firstname)"
+  setfattr -n user.qmfs.expect_revision -v "$GUID" "${Q}/entities/all/homer/firstname"
+  echo Marge > "${Q}/entities/all/homer/firstname"
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Marge" ]
+}
+
+@test "write fails with stale expected revision" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  GUID="$(getfattr -n user.qmfs.row_guid --only-values ${Q}/entities/all/homer/firstname)"
+  echo Bart > "${Q}/entities/all/homer/firstname"
+  setfattr -n user.qmfs.expect_revision -v "$GUID" "${Q}/entities/all/homer/firstname"
+  ! (echo Marge > "${Q}/entities/all/homer/firstname")
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Bart" ]
+}
+
+@test "expected revision only applies to the next write" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  setfattr -n user.qmfs.expect_revision -v "bogus" "${Q}/entities/all/homer/firstname"
+  ! (echo Marge > "${Q}/entities/all/homer/firstname")
+  echo Bart > "${Q}/entities/all/homer/firstname"
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Bart" ]
+}
+
+@test "other xattrs cannot be set" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  ! setfattr -n user.qmfs.row_guid -v "bogus" "${Q}/entities/all/homer/firstname"
+}
@@ -0,0 +1,37 @@
+load helpers
+
+@test "file mtime reflects last change" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  sleep 1.1
+  echo Marge > "${Q}/entities/all/marge/firstname"
+  [ "${Q}/entities/all/marge/firstname" -nt "${Q}/entities/all/homer/firstname" ]
+}
+
+@test "file mtime is recent" {
+  BEFORE="$(date +%s)"
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  AFTER="$(date +%s)"
+  MTIME="$(stat -c %Y ${Q}/entities/all/homer/firstname)"
+  [ "$MTIME" -ge "$BEFORE" ]
+  [ "$MTIME" -le "$AFTER" ]
+}
+
+@test "file mtime is stable across reads" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  FIRST="$(stat -c %Y ${Q}/entities/all/homer/firstname)"
+  sleep 1.1
+  cat "${Q}/entities/all/homer/firstname" > /dev/null
+  [ "$(stat -c %Y ${Q}/entities/all/homer/firstname)" = "$FIRST" ]
+}
+
+@test "entity directory mtime is latest file change" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  sleep 1.1
+  echo Simpson > "${Q}/entities/all/homer/lastname"
+  [ "$(stat -c %Y ${Q}/entities/all/homer)" = "$(stat -c %Y ${Q}/entities/all/homer/lastname)" ]
+}
+
+@test "entities directory mtime is latest change" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  [ "$(stat -c %Y ${Q}/entities)" = "$(stat -c %Y ${Q}/entities/all/homer/firstname)" ]
+}
@@ -0,0 +1,62 @@
+load helpers
+
+start_second_qmfs() {
+  export Q2="${QMFS_TEST_TEMP}/mountpoint2"
+  mkdir -p "${Q2}"
+  ./qmfs serve --mountpoint "${Q2}" --localdb "${QMFS_TEST_TEMP}/database.sqlite3" > /dev/null 2> /dev/null &
+  for n in $(seq 1000); do
+    if [[ ! -d "${Q2}/service" ]]; then
+      sleep 0.1
+    fi
+  done
+}
+
+stop_second_qmfs() {
+  if [[ -d "${Q2}/service" ]]; then
+    kill $(cat "${Q2}/service/pid")
+    fusermount -u "${Q2}" || true
+  fi
+}
+
+wait_for_contents() {
+  for n in $(seq 150); do
+    if [[ "$(cat $1 2> /dev/null)" = "$2" ]]; then
+      return 0
+    fi
+    sleep 0.1
+  done
+  return 1
+}
+
+@test "changes from another writer become visible" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Homer" ]
+  start_second_qmfs
+  echo Marge > "${Q2}/entities/all/homer/firstname"
+  stop_second_qmfs
+  wait_for_contents "${Q}/entities/all/homer/firstname" "Marge"
+}
+
+@test "new files from another writer become visible" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  ls "${Q}/entities/all/homer" > /dev/null
+  start_second_qmfs
+  echo Simpson > "${Q2}/entities/all/homer/lastname"
+  stop_second_qmfs
+  wait_for_contents "${Q}/entities/all/homer/lastname" "Simpson"
+}
+
+@test "deletions from another writer become visible" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Homer" ]
+  start_second_qmfs
+  rm "${Q2}/entities/all/homer/firstname"
+  stop_second_qmfs
+  for n in $(seq 150); do
+    if [[ ! -f "${Q}/entities/all/homer/firstname" ]]; then
+      return 0
+    fi
+    sleep 0.1
+  done
+  return 1
+}
@@ -0,0 +1,61 @@
+load helpers
+
+@test "can mount a remote server" {
+  start_remote_qmfs
+  echo Homer > "${R}/entities/all/homer/firstname"
+  [ "$(cat ${R}/entities/all/homer/firstname)" = "Homer" ]
+  stop_remote_qmfs
+}
+
+@test "remote server writes credentials" {
+  start_remote_qmfs
+  [ -f "${CREDS}/server_cert.pem" ]
+  [ -f "${CREDS}/client_cert.pem" ]
+  [ -f "${CREDS}/client_key.pem" ]
+  [ "$(stat -c %a ${CREDS}/client_key.pem)" = "600" ]
+  stop_remote_qmfs
+}
+
+@test "remote server rewrites credentials when rotating certificates" {
+  start_remote_qmfs --tls_validity 8s --tls_rotation_overlap 4s
+  server_cert="$(cat ${CREDS}/server_cert.pem)"
+  client_cert="$(cat ${CREDS}/client_cert.pem)"
+  sleep 8
+  [ "$(cat ${CREDS}/server_cert.pem)" != "${server_cert}" ]
+  [ "$(cat ${CREDS}/client_cert.pem)" != "${client_cert}" ]
+  R2="${QMFS_TEST_TEMP}/rotated-mountpoint"
+  mkdir -p "${R2}"
+  ./qmfs mount --server "localhost:${PORT}" --credentials_dir "${CREDS}" --mountpoint "${R2}" > /dev/null 2> /dev/null &
+  for n in $(seq 100); do
+    if [[ ! -d "${R2}/service" ]]; then
+      sleep 0.1
+    fi
+  done
+  echo Homer > "${R2}/entities/all/homer/firstname"
+  [ "$(cat ${R2}/entities/all/homer/firstname)" = "Homer" ]
+  kill $(cat "${R2}/service/pid")
+  fusermount -u "${R2}" || true
+  stop_remote_qmfs
+}
+
+@test "remote data persists across remounts" {
+  start_remote_qmfs
+  echo Homer > "${R}/entities/all/homer/firstname"
+  stop_remote_qmfs
+  sleep 0.5
+  start_remote_qmfs
+  [ "$(cat ${R}/entities/all/homer/firstname)" = "Homer" ]
+  stop_remote_qmfs
+}
+
+@test "mount requires a server" {
+  mkdir -p "${QMFS_TEST_TEMP}/nowhere"
+  ! ./qmfs mount --mountpoint "${QMFS_TEST_TEMP}/nowhere" --credentials_dir "${QMFS_TEST_TEMP}" 2> /dev/null
+}
+
+@test "remote server rejects a rotation overlap that is not positive" {
+  run ./qmfs serve-grpc --localdb "${QMFS_TEST_TEMP}/remote.sqlite3" --listen localhost:0 --credentials_dir "${QMFS_TEST_TEMP}/credentials" --tls_rotation_overlap 0
+  [ "$status" -ne 0 ]
+  [[ "$output" == *"--tls_rotation_overlap"* ]]
+  [[ "$output" != *"panic"* ]]
+}
@@ -0,0 +1,26 @@
+load helpers
+
+@test "service exposes grpc socket" {
+  [ -S "$(cat ${Q}/service/grpc_socket)" ]
+}
+
+@test "grpc socket is private" {
+  [ "$(stat -c %a $(cat ${Q}/service/grpc_socket))" = "600" ]
+}
+
+@test "can serve on a chosen socket" {
+  stop_qmfs
+  start_qmfs --grpc_socket "${QMFS_TEST_TEMP}/qmfs.sock"
+  [ "$(cat ${Q}/service/grpc_socket)" = "${QMFS_TEST_TEMP}/qmfs.sock" ]
+  [ -S "${QMFS_TEST_TEMP}/qmfs.sock" ]
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Homer" ]
+}
+
+@test "does not replace a file at the chosen socket path" {
+  stop_qmfs
+  echo precious > "${QMFS_TEST_TEMP}/qmfs.sock"
+  run ./qmfs serve --mountpoint "${Q}" --localdb "${QMFS_TEST_TEMP}/database.sqlite3" --grpc_socket "${QMFS_TEST_TEMP}/qmfs.sock"
+  [ $status -ne 0 ]
+  [ "$(cat ${QMFS_TEST_TEMP}/qmfs.sock)" = "precious" ]
+}
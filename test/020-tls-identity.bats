@@ -0,0 +1,26 @@
+load helpers
+
+@test "certificate changes across restarts by default" {
+  cp "${Q}/service/server_cert.pem" "${QMFS_TEST_TEMP}/cert-before.pem"
+  restart_qmfs
+  ! cmp -s "${Q}/service/server_cert.pem" "${QMFS_TEST_TEMP}/cert-before.pem"
+}
+
+@test "persistent certificate survives restarts" {
+  stop_qmfs
+  start_qmfs --persistent_tls
+  cp "${Q}/service/server_cert.pem" "${QMFS_TEST_TEMP}/cert-before.pem"
+  sleep 0.1
+  stop_qmfs
+  start_qmfs --persistent_tls
+  cmp -s "${Q}/service/server_cert.pem" "${QMFS_TEST_TEMP}/cert-before.pem"
+  [ -f "${QMFS_TEST_TEMP}/database.sqlite3.tls/cert.pem" ]
+}
+
+@test "certificates can be kept in a chosen directory" {
+  stop_qmfs
+  start_qmfs --tls_dir "${QMFS_TEST_TEMP}/tls"
+  [ -f "${QMFS_TEST_TEMP}/tls/cert.pem" ]
+  [ "$(stat -c %a ${QMFS_TEST_TEMP}/tls/key.pem)" = "600" ]
+  grep -q "BEGIN CERTIFICATE" "${QMFS_TEST_TEMP}/tls/cert.pem"
+}
@@ -0,0 +1,108 @@
+load helpers
+
+export_qmfs() {
+  ./qmfs export --localdb "${QMFS_TEST_TEMP}/database.sqlite3" "$@" 2> /dev/null
+}
+
+import_qmfs() {
+  ./qmfs import --localdb "${QMFS_TEST_TEMP}/database.sqlite3" "$@" 2> /dev/null
+}
+
+replace_database_with() {
+  stop_qmfs
+  rm -f "${QMFS_TEST_TEMP}/database.sqlite3"*
+  import_qmfs "$@"
+  start_qmfs
+}
+
+@test "export and import roundtrip" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  mkdir "${Q}/entities/all/homer/kids"
+  echo Bart > "${Q}/entities/all/homer/kids/son"
+  echo Marge > "${Q}/entities/all/marge/firstname"
+  export_qmfs --output "${QMFS_TEST_TEMP}/archive"
+  replace_database_with --input "${QMFS_TEST_TEMP}/archive"
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Homer" ]
+  [ "$(cat ${Q}/entities/all/homer/kids/son)" = "Bart" ]
+  [ "$(cat ${Q}/entities/all/marge/firstname)" = "Marge" ]
+}
+
+@test "export only includes the chosen namespace" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  mkdir "${Q}/namespace/other"
+  echo Ned > "${Q}/namespace/other/entities/all/ned/firstname"
+  export_qmfs --namespace other --output "${QMFS_TEST_TEMP}/archive"
+  replace_database_with --input "${QMFS_TEST_TEMP}/archive"
+  [ "$(cat ${Q}/namespace/other/entities/all/ned/firstname)" = "Ned" ]
+  [ ! -e "${Q}/entities/all/homer/firstname" ]
+}
+
+@test "export of all namespaces" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  mkdir "${Q}/namespace/other"
+  echo Ned > "${Q}/namespace/other/entities/all/ned/firstname"
+  export_qmfs --all_namespaces --output "${QMFS_TEST_TEMP}/archive"
+  replace_database_with --input "${QMFS_TEST_TEMP}/archive"
+  [ "$(cat ${Q}/namespace/other/entities/all/ned/firstname)" = "Ned" ]
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Homer" ]
+}
+
+@test "deleted files are not exported without history" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  echo Simpson > "${Q}/entities/all/homer/lastname"
+  rm "${Q}/entities/all/homer/lastname"
+  export_qmfs --output "${QMFS_TEST_TEMP}/archive"
+  replace_database_with --input "${QMFS_TEST_TEMP}/archive"
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Homer" ]
+  [ ! -e "${Q}/entities/all/homer/lastname" ]
+}
+
+@test "preserved import keeps row guids" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  GUID="$(getfattr -n user.qmfs.row_guid --only-values ${Q}/entities/all/homer/firstname)"
+  export_qmfs --history --authorship --output "${QMFS_TEST_TEMP}/archive"
+  replace_database_with --input "${QMFS_TEST_TEMP}/archive" --preserve_revisions
+  [ "$(getfattr -n user.qmfs.row_guid --only-values ${Q}/entities/all/homer/firstname)" = "$GUID" ]
+}
+
+@test "history is replayed in order" {
+  stop_qmfs
+  start_qmfs --keep_revision_data
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  echo Marge > "${Q}/entities/all/homer/firstname"
+  echo Simpson > "${Q}/entities/all/homer/lastname"
+  rm "${Q}/entities/all/homer/lastname"
+  export_qmfs --history --output "${QMFS_TEST_TEMP}/archive"
+  replace_database_with --input "${QMFS_TEST_TEMP}/archive" --preserve_revisions
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Marge" ]
+  [ ! -e "${Q}/entities/all/homer/lastname" ]
+  [ "$(ls ${Q}/entities/all/homer/.history/firstname | wc -l | tr -d '[:space:]')" = "2" ]
+}
+
+@test "preserved import is idempotent" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  export_qmfs --history --output "${QMFS_TEST_TEMP}/archive"
+  stop_qmfs
+  import_qmfs --input "${QMFS_TEST_TEMP}/archive" --preserve_revisions | grep -q "^imported: 0$"
+  start_qmfs
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Homer" ]
+}
+
+@test "preserved import does not replace newer revisions" {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  export_qmfs --history --output "${QMFS_TEST_TEMP}/archive"
+  stop_qmfs
+  rm -f "${QMFS_TEST_TEMP}/database.sqlite3"*
+  start_qmfs
+  echo Marge > "${Q}/entities/all/homer/firstname"
+  stop_qmfs
+  import_qmfs --input "${QMFS_TEST_TEMP}/archive" --preserve_revisions
+  start_qmfs
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Marge" ]
+}
+
+@test "import rejects other files" {
+  echo "not an archive" > "${QMFS_TEST_TEMP}/archive"
+  stop_qmfs
+  ! import_qmfs --input "${QMFS_TEST_TEMP}/archive"
+}
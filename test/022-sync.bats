@@ -0,0 +1,82 @@
+load helpers
+
+sync_qmfs() {
+  stop_qmfs
+  ./qmfs sync --localdb "${QMFS_TEST_TEMP}/database.sqlite3" --credentials_dir "${CREDS}" "localhost:${PORT}" 2> /dev/null
+  start_qmfs
+}
+
+@test "sync pulls remote changes" {
+  start_remote_qmfs
+  echo Homer > "${R}/entities/all/homer/firstname"
+  sync_qmfs
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Homer" ]
+  stop_remote_qmfs
+}
+
+@test "sync pushes local changes" {
+  start_remote_qmfs
+  echo Marge > "${Q}/entities/all/marge/firstname"
+  sync_qmfs
+  [ "$(cat ${R}/entities/all/marge/firstname)" = "Marge" ]
+  stop_remote_qmfs
+}
+
+@test "sync propagates deletions" {
+  start_remote_qmfs
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  sync_qmfs
+  rm "${Q}/entities/all/homer/firstname"
+  sync_qmfs
+  [ ! -e "${R}/entities/all/homer/firstname" ]
+  stop_remote_qmfs
+}
+
+@test "sync only sends new changes" {
+  start_remote_qmfs
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  sync_qmfs
+  stop_qmfs
+  ./qmfs sync --localdb "${QMFS_TEST_TEMP}/database.sqlite3" --credentials_dir "${CREDS}" "localhost:${PORT}" 2> /dev/null | grep -q "^pushed: 0$"
+  start_qmfs
+  stop_remote_qmfs
+}
+
+@test "sync propagates deletions compacted before syncing" {
+  start_remote_qmfs
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  sync_qmfs
+  rm "${Q}/entities/all/homer/firstname"
+  stop_qmfs
+  ./qmfs gc --localdb "${QMFS_TEST_TEMP}/database.sqlite3" --retention 0s 2> /dev/null
+  start_qmfs
+  sync_qmfs
+  [ ! -e "${R}/entities/all/homer/firstname" ]
+  [ ! -e "${Q}/entities/all/homer/firstname" ]
+  stop_remote_qmfs
+}
+
+@test "sync does not send pulled changes back" {
+  start_remote_qmfs
+  echo Homer > "${R}/entities/all/homer/firstname"
+  stop_qmfs
+  ./qmfs sync --localdb "${QMFS_TEST_TEMP}/database.sqlite3" --credentials_dir "${CREDS}" "localhost:${PORT}" 2> /dev/null | grep -q "^skipped: 0$"
+  start_qmfs
+  stop_remote_qmfs
+}
+
+@test "latest write wins and the other is kept as history" {
+  start_remote_qmfs
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  sleep 0.1
+  echo Max > "${R}/entities/all/homer/firstname"
+  sync_qmfs
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Max" ]
+  [ "$(cat ${R}/entities/all/homer/firstname)" = "Max" ]
+  [ "$(ls ${Q}/entities/all/homer/.history/firstname | wc -l | tr -d '[:space:]')" = "2" ]
+  stop_remote_qmfs
+}
+
+@test "sync requires a remote" {
+  ! ./qmfs sync --localdb "${QMFS_TEST_TEMP}/database.sqlite3" --credentials_dir "${QMFS_TEST_TEMP}" 2> /dev/null
+}
@@ -0,0 +1,52 @@
+load helpers
+
+make_conflict() {
+  echo Homer > "${Q}/entities/all/homer/firstname"
+  GUID="$(getfattr -n user.qmfs.row_guid --only-values ${Q}/entities/all/homer/firstname)"
+  echo Bart > "${Q}/entities/all/homer/firstname"
+  setfattr -n user.qmfs.expect_revision -v "$GUID" "${Q}/entities/all/homer/firstname"
+}
+
+@test "conflicting write fails with ESTALE by default" {
+  make_conflict
+  run bash -c "echo Marge > ${Q}/entities/all/homer/firstname"
+  [ "$status" -ne 0 ]
+  [[ "$output" == *"Stale file handle"* ]]
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Bart" ]
+}
+
+@test "conflicting write is saved as a sibling" {
+  stop_qmfs
+  start_qmfs --on_conflict save
+  make_conflict
+  echo Marge > "${Q}/entities/all/homer/firstname"
+  [ "$(cat ${Q}/entities/all/homer/firstname)" = "Bart" ]
+  [ "$(cat ${Q}/entities/all/homer/firstname.conflict-${GUID})" = "Marge" ]
+}
+
+@test "repeated conflicting writes are all saved" {
+  stop_qmfs
+  start_qmfs --on_conflict save
+  make_conflict
+  echo Marge > "${Q}/entities/all/homer/firstname"
+  setfattr -n user.qmfs.expect_revision -v "$GUID" "${Q}/entities/all/homer/firstname"
+  echo Lisa > "${Q}/entities/all/homer/firstname"
+  [ "$(cat ${Q}/entities/all/homer/firstname.conflict-${GUID})" = "Marge" ]
+  [ "$(cat ${Q}/entities/all/homer/firstname.conflict-${GUID}-2)" = "Lisa" ]
+}
+
+@test "conflicts are logged" {
+  stop_qmfs
+  start_qmfs --on_conflict save
+  make_conflict
+  echo Marge > "${Q}/entities/all/homer/firstname"
+  LINE="$(timeout 5 head -n1 ${Q}/service/conflicts)"
+  echo "$LINE" | grep -q '"entity_id":"homer"'
+  echo "$LINE" | grep -q "\"expected_revision\":\"${GUID}\""
+  echo "$LINE" | grep -q "\"saved_as\":\"firstname.conflict-${GUID}\""
+}
+
+@test "unknown conflict policy is rejected" {
+  stop_qmfs
+  ! ./qmfs serve --mountpoint "${Q}" --localdb "${QMFS_TEST_TEMP}/database.sqlite3" --on_conflict bogus 2> /dev/null
+}
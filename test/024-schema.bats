@@ -0,0 +1,75 @@
+load helpers
+
+SCHEMA_ENTITIES="namespace/schema/entities/all"
+
+define_schema() {
+  mkdir -p "${Q}/namespace/schema"
+  echo "enum todo doing done" > "${Q}/${SCHEMA_ENTITIES}/_default/status"
+  echo "integer" > "${Q}/${SCHEMA_ENTITIES}/_default/priority"
+  echo "regex [a-z]+@[a-z.]+" > "${Q}/${SCHEMA_ENTITIES}/_default/email"
+  echo "json" > "${Q}/${SCHEMA_ENTITIES}/_default/config"
+  echo "ref" > "${Q}/${SCHEMA_ENTITIES}/_default/parent"
+  touch "${Q}/${SCHEMA_ENTITIES}/_default/notes"
+}
+
+write_file() {
+  echo "$2" | tee "$1" > /dev/null
+}
+
+@test "namespaces without a schema allow anything" {
+  write_file "${Q}/entities/all/task1/stauts" todo
+  [ "$(cat ${Q}/entities/all/task1/stauts)" = "todo" ]
+}
+
+@test "schema allows valid files" {
+  define_schema
+  write_file "${Q}/entities/all/task1/status" todo
+  write_file "${Q}/entities/all/task1/priority" 3
+  write_file "${Q}/entities/all/task1/email" "homer@example.com"
+  write_file "${Q}/entities/all/task1/config" '{"a": 1}'
+  write_file "${Q}/entities/all/task1/notes" "anything at all"
+  write_file "${Q}/entities/all/task2/parent" task1
+  [ "$(cat ${Q}/entities/all/task1/status)" = "todo" ]
+  [ "$(cat ${Q}/entities/all/task2/parent)" = "task1" ]
+}
+
+@test "schema rejects unknown filenames" {
+  define_schema
+  run write_file "${Q}/entities/all/task1/stauts" todo
+  [ "$status" -ne 0 ]
+  [[ "$output" == *"Invalid argument"* ]]
+  [ ! -e "${Q}/entities/all/task1/stauts" ]
+}
+
+@test "schema rejects invalid contents" {
+  define_schema
+  ! write_file "${Q}/entities/all/task1/status" bogus
+  ! write_file "${Q}/entities/all/task1/priority" high
+  ! write_file "${Q}/entities/all/task1/email" "not an address"
+  ! write_file "${Q}/entities/all/task1/config" '{"a":'
+  ! write_file "${Q}/entities/all/task1/parent" nonexistent
+}
+
+@test "invalid rules are rejected" {
+  mkdir -p "${Q}/namespace/schema"
+  ! write_file "${Q}/${SCHEMA_ENTITIES}/_default/status" "oneof todo done"
+  ! write_file "${Q}/${SCHEMA_ENTITIES}/_default/email" "regex [a-z"
+}
+
+@test "schema applies per namespace" {
+  define_schema
+  mkdir "${Q}/namespace/other"
+  write_file "${Q}/namespace/other/entities/all/ned/stauts" todo
+  [ "$(cat ${Q}/namespace/other/entities/all/ned/stauts)" = "todo" ]
+}
+
+@test "import rejects files not allowed by the schema" {
+  write_file "${Q}/entities/all/task1/priority" high
+  ./qmfs export --localdb "${QMFS_TEST_TEMP}/database.sqlite3" --output "${QMFS_TEST_TEMP}/archive" 2> /dev/null
+  rm "${Q}/entities/all/task1/priority"
+  define_schema
+  stop_qmfs
+  ! ./qmfs import --localdb "${QMFS_TEST_TEMP}/database.sqlite3" --input "${QMFS_TEST_TEMP}/archive" 2> /dev/null
+  start_qmfs
+  [ ! -e "${Q}/entities/all/task1/priority" ]
+}
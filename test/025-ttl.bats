@@ -0,0 +1,56 @@
+load helpers
+
+@test "file with a ttl is deleted once it expires" {
+  echo worker1 > "${Q}/entities/all/job/lock"
+  setfattr -n user.qmfs.ttl -v 1 "${Q}/entities/all/job/lock"
+  [ "$(cat ${Q}/entities/all/job/lock)" = "worker1" ]
+  sleep 3
+  [ ! -e "${Q}/entities/all/job/lock" ]
+}
+
+@test "ttl sets the expires_at xattr" {
+  echo worker1 > "${Q}/entities/all/job/lock"
+  ! getfattr -n user.qmfs.expires_at "${Q}/entities/all/job/lock" 2> /dev/null
+  setfattr -n user.qmfs.ttl -v 5m "${Q}/entities/all/job/lock"
+  getfattr -d "${Q}/entities/all/job/lock" | grep -q '^user.qmfs.expires_at='
+}
+
+@test "overwriting a file cancels its expiry" {
+  echo worker1 > "${Q}/entities/all/job/lock"
+  setfattr -n user.qmfs.ttl -v 1 "${Q}/entities/all/job/lock"
+  echo worker2 > "${Q}/entities/all/job/lock"
+  sleep 3
+  [ "$(cat ${Q}/entities/all/job/lock)" = "worker2" ]
+}
+
+@test "removing the ttl cancels the expiry" {
+  echo worker1 > "${Q}/entities/all/job/lock"
+  setfattr -n user.qmfs.ttl -v 1 "${Q}/entities/all/job/lock"
+  setfattr -x user.qmfs.ttl "${Q}/entities/all/job/lock"
+  sleep 3
+  [ "$(cat ${Q}/entities/all/job/lock)" = "worker1" ]
+}
+
+@test "renewing the ttl extends the expiry" {
+  echo worker1 > "${Q}/entities/all/job/lock"
+  setfattr -n user.qmfs.ttl -v 2 "${Q}/entities/all/job/lock"
+  sleep 1
+  setfattr -n user.qmfs.ttl -v 1h "${Q}/entities/all/job/lock"
+  sleep 3
+  [ "$(cat ${Q}/entities/all/job/lock)" = "worker1" ]
+}
+
+@test "invalid ttl is rejected" {
+  echo worker1 > "${Q}/entities/all/job/lock"
+  ! setfattr -n user.qmfs.ttl -v soon "${Q}/entities/all/job/lock"
+  ! setfattr -n user.qmfs.ttl -v 0 "${Q}/entities/all/job/lock"
+}
+
+@test "expiry deletions are recorded as ordinary deletions" {
+  echo worker1 > "${Q}/entities/all/job/lock"
+  setfattr -n user.qmfs.ttl -v 1 "${Q}/entities/all/job/lock"
+  sleep 3
+  [ ! -e "${Q}/entities/all/job/lock" ]
+  echo worker2 > "${Q}/entities/all/job/lock"
+  [ "$(cat ${Q}/entities/all/job/lock)" = "worker2" ]
+}
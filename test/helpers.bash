@@ -7,7 +7,7 @@ stop_qmfs() {
 
 start_qmfs() {
   fusermount -u "${Q}" || true
-  ./qmfs serve --mountpoint "${Q}" --localdb "${QMFS_TEST_TEMP}/database.sqlite3" > /dev/null 2> /dev/null &
+  ./qmfs serve --mountpoint "${Q}" --localdb "${QMFS_TEST_TEMP}/database.sqlite3" "$@" > /dev/null 2> /dev/null &
   for n in $(seq 1000); do
     if [[ ! -d "${Q}/service" ]]; then
       sleep 0.1
@@ -20,6 +20,10 @@ instantly_and_ungracefully_restart_qmfs() {
   start_qmfs
 }
 
+restart_qmfs_keeping_revisions() {
+  stop_qmfs
+  start_qmfs --keep_revision_data
+}
 
 restart_qmfs() {
   sleep 0.1
@@ -27,6 +31,38 @@ restart_qmfs() {
   start_qmfs
 }
 
+start_remote_qmfs() {
+  export R="${QMFS_TEST_TEMP}/remote-mountpoint"
+  export CREDS="${QMFS_TEST_TEMP}/credentials"
+  export PORT="$(( 20000 + RANDOM % 10000 ))"
+  mkdir -p "${R}"
+  rm -rf "${CREDS}"
+  ./qmfs serve-grpc --localdb "${QMFS_TEST_TEMP}/remote.sqlite3" --listen "localhost:${PORT}" --hostname localhost --credentials_dir "${CREDS}" "$@" > /dev/null 2> /dev/null &
+  echo $! > "${QMFS_TEST_TEMP}/remote-server.pid"
+  for n in $(seq 100); do
+    if [[ ! -f "${CREDS}/client_key.pem" ]]; then
+      sleep 0.1
+    fi
+  done
+  ./qmfs mount --server "localhost:${PORT}" --credentials_dir "${CREDS}" --mountpoint "${R}" > /dev/null 2> /dev/null &
+  for n in $(seq 1000); do
+    if [[ ! -d "${R}/service" ]]; then
+      sleep 0.1
+    fi
+  done
+}
+
+stop_remote_qmfs() {
+  if [[ -n "${R}" && -d "${R}/service" ]]; then
+    kill $(cat "${R}/service/pid")
+    fusermount -u "${R}" || true
+  fi
+  if [[ -f "${QMFS_TEST_TEMP}/remote-server.pid" ]]; then
+    kill $(cat "${QMFS_TEST_TEMP}/remote-server.pid") 2> /dev/null || true
+    rm -f "${QMFS_TEST_TEMP}/remote-server.pid"
+  fi
+}
+
 setup() {
   export QMFS_TEST_TEMP="${BATS_TMPDIR}/qmfs-test-temp"
   mkdir -p "${QMFS_TEST_TEMP}"
@@ -38,6 +74,7 @@ setup() {
 }
 
 teardown() {
+  stop_remote_qmfs
   stop_qmfs
 
   if [[ -n "${QMFS_TEST_TEMP}" ]]; then